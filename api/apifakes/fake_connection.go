@@ -44,6 +44,22 @@ type FakeConnection struct {
 		result1 string
 		result2 error
 	}
+	UserGuidStub        func() (string, error)
+	userGuidMutex       sync.RWMutex
+	userGuidArgsForCall []struct{}
+	userGuidReturns     struct {
+		result1 string
+		result2 error
+	}
+	CliCommandStub        func(args ...string) ([]string, error)
+	cliCommandMutex       sync.RWMutex
+	cliCommandArgsForCall []struct {
+		args []string
+	}
+	cliCommandReturns struct {
+		result1 []string
+		result2 error
+	}
 	CliCommandWithoutTerminalOutputStub        func(args ...string) ([]string, error)
 	cliCommandWithoutTerminalOutputMutex       sync.RWMutex
 	cliCommandWithoutTerminalOutputArgsForCall []struct {
@@ -207,6 +223,64 @@ func (fake *FakeConnection) UsernameReturns(result1 string, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeConnection) UserGuid() (string, error) {
+	fake.userGuidMutex.Lock()
+	fake.userGuidArgsForCall = append(fake.userGuidArgsForCall, struct{}{})
+	fake.userGuidMutex.Unlock()
+	if fake.UserGuidStub != nil {
+		return fake.UserGuidStub()
+	} else {
+		return fake.userGuidReturns.result1, fake.userGuidReturns.result2
+	}
+}
+
+func (fake *FakeConnection) UserGuidCallCount() int {
+	fake.userGuidMutex.RLock()
+	defer fake.userGuidMutex.RUnlock()
+	return len(fake.userGuidArgsForCall)
+}
+
+func (fake *FakeConnection) UserGuidReturns(result1 string, result2 error) {
+	fake.UserGuidStub = nil
+	fake.userGuidReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConnection) CliCommand(args ...string) ([]string, error) {
+	fake.cliCommandMutex.Lock()
+	fake.cliCommandArgsForCall = append(fake.cliCommandArgsForCall, struct {
+		args []string
+	}{args})
+	fake.cliCommandMutex.Unlock()
+	if fake.CliCommandStub != nil {
+		return fake.CliCommandStub(args...)
+	} else {
+		return fake.cliCommandReturns.result1, fake.cliCommandReturns.result2
+	}
+}
+
+func (fake *FakeConnection) CliCommandCallCount() int {
+	fake.cliCommandMutex.RLock()
+	defer fake.cliCommandMutex.RUnlock()
+	return len(fake.cliCommandArgsForCall)
+}
+
+func (fake *FakeConnection) CliCommandArgsForCall(i int) []string {
+	fake.cliCommandMutex.RLock()
+	defer fake.cliCommandMutex.RUnlock()
+	return fake.cliCommandArgsForCall[i].args
+}
+
+func (fake *FakeConnection) CliCommandReturns(result1 []string, result2 error) {
+	fake.CliCommandStub = nil
+	fake.cliCommandReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeConnection) CliCommandWithoutTerminalOutput(args ...string) ([]string, error) {
 	fake.cliCommandWithoutTerminalOutputMutex.Lock()
 	fake.cliCommandWithoutTerminalOutputArgsForCall = append(fake.cliCommandWithoutTerminalOutputArgsForCall, struct {
@@ -2,19 +2,45 @@ package api
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/cloudfoundry/cli/cf/trace"
 	"github.com/cloudfoundry/cli/plugin/models"
 )
 
 type Client struct {
 	BaseUrl   *url.URL
 	AuthToken string
+
+	// ResultsPerPage overrides the Cloud Controller's default page size
+	// when it is greater than zero.
+	ResultsPerPage int
+
+	// OrderBy, when set, asks the Cloud Controller to sort results by that
+	// field, in descending order if OrderDescending is true.
+	OrderBy         string
+	OrderDescending bool
 }
 
+// ApiV2 and ApiV3 are the versions of the Cloud Controller API that apps
+// can be listed with. The v2 API is the default.
+const (
+	ApiV2 = "v2"
+	ApiV3 = "v3"
+)
+
+// OrderByName is the only field the Cloud Controller sorts apps by besides
+// its default, the app's id.
+const OrderByName = "name"
+
 //go:generate counterfeiter . Connection
 
 type Connection interface {
@@ -24,7 +50,9 @@ type Connection interface {
 	AccessToken() (string, error)
 
 	Username() (string, error)
+	UserGuid() (string, error)
 
+	CliCommand(args ...string) ([]string, error)
 	CliCommandWithoutTerminalOutput(args ...string) ([]string, error)
 	GetApp(string) (plugin_models.GetAppModel, error)
 	GetOrg(string) (plugin_models.GetOrg_Model, error)
@@ -33,6 +61,16 @@ type Connection interface {
 
 var NotLoggedInError = errors.New("You must be logged in")
 
+// MalformedTokenError is returned for an access token that is not of the
+// form "bearer TOKEN", which the Cloud Controller would only reject once
+// the first request is made.
+var MalformedTokenError = errors.New("The access token is not a valid bearer token, please run cf login")
+
+// UserAgent is sent with every request so that Cloud Controller operators
+// can tell the plugin's traffic from the CLI's. main sets it to include
+// the plugin version.
+var UserAgent = "diego-enabler"
+
 func NewClient(connection Connection) (*Client, error) {
 	if connected, err := connection.IsLoggedIn(); !connected {
 		if err != nil {
@@ -54,6 +92,9 @@ func NewClient(connection Connection) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !isBearerToken(authToken) {
+		return nil, MalformedTokenError
+	}
 
 	client := &Client{
 		BaseUrl:   u,
@@ -63,26 +104,59 @@ func NewClient(connection Connection) (*Client, error) {
 	return client, nil
 }
 
+func isBearerToken(token string) bool {
+	fields := strings.Fields(token)
+	return len(fields) == 2 && strings.EqualFold(fields[0], "bearer")
+}
+
 func (c *Client) NewGetAppsRequest() (*http.Request, error) {
-	req := &http.Request{
-		Method: "GET",
-		URL:    c.BaseUrl,
-	}
-	req.URL.Path = "/v2/apps"
+	return c.newGetRequest("/v2/apps"), nil
+}
 
-	return req, nil
+func (c *Client) NewGetV3AppsRequest() (*http.Request, error) {
+	return c.newGetRequest("/v3/apps"), nil
 }
 
 func (c *Client) NewGetSpacesRequest() (*http.Request, error) {
-	req := &http.Request{
-		Method: "GET",
-		URL:    c.BaseUrl,
-	}
-	req.URL.Path = "/v2/spaces"
+	return c.newGetRequest("/v2/spaces"), nil
+}
 
+// NewGetSpaceRequest requests a single space with its organization, as
+// NewGetSpacesRequest gets them with inline-relations-depth.
+func (c *Client) NewGetSpaceRequest(guid string) (*http.Request, error) {
+	req := c.newGetRequest("/v2/spaces/" + url.PathEscape(guid))
+	req.URL.RawQuery = "inline-relations-depth=1"
 	return req, nil
 }
 
+func (c *Client) NewGetStacksRequest() (*http.Request, error) {
+	return c.newGetRequest("/v2/stacks"), nil
+}
+
+func (c *Client) NewGetOrganizationsRequest() (*http.Request, error) {
+	return c.newGetRequest("/v2/organizations"), nil
+}
+
+func (c *Client) NewGetInfoRequest() (*http.Request, error) {
+	return c.newGetRequest("/v2/info"), nil
+}
+
+// newGetRequest copies BaseUrl so that requests built concurrently don't
+// share, and overwrite, the same URL.
+func (c *Client) newGetRequest(path string) *http.Request {
+	u := *c.BaseUrl
+	u.Path = path
+
+	header := http.Header{}
+	header.Set("User-Agent", UserAgent)
+
+	return &http.Request{
+		Method: "GET",
+		URL:    &u,
+		Header: header,
+	}
+}
+
 func (c *Client) HandleFiltersAndParameters(next func() (*http.Request, error)) func(filter Filter, params map[string]interface{}) (*http.Request, error) {
 	return func(filter Filter, params map[string]interface{}) (*http.Request, error) {
 		req, err := next()
@@ -90,11 +164,85 @@ func (c *Client) HandleFiltersAndParameters(next func() (*http.Request, error))
 			return new(http.Request), err
 		}
 
-		req.URL.RawQuery = generateParams(filter, params).Encode()
+		values := generateParams(filter, params)
+		if c.ResultsPerPage > 0 {
+			values.Set("results-per-page", fmt.Sprint(c.ResultsPerPage))
+		}
+		if c.OrderBy != "" {
+			values.Set("order-by", c.OrderBy)
+			if c.OrderDescending {
+				values.Set("order-direction", "desc")
+			} else {
+				values.Set("order-direction", "asc")
+			}
+		}
+
+		req.URL.RawQuery = values.Encode()
+		return req, nil
+	}
+}
+
+// HandleV3FiltersAndParameters is HandleFiltersAndParameters for the v3
+// API, which takes each filter as a parameter of its own rather than in
+// `q`, and names the page size and order differently.
+func (c *Client) HandleV3FiltersAndParameters(next func() (*http.Request, error)) func(filter Filter, params map[string]interface{}) (*http.Request, error) {
+	return func(filter Filter, params map[string]interface{}) (*http.Request, error) {
+		req, err := next()
+		if err != nil {
+			return new(http.Request), err
+		}
+
+		values := url.Values{}
+		addV3FilterParams(values, filter)
+		for k, v := range params {
+			values.Set(k, fmt.Sprint(v))
+		}
+		if c.ResultsPerPage > 0 {
+			values.Set("per_page", fmt.Sprint(c.ResultsPerPage))
+		}
+		if c.OrderBy != "" {
+			if c.OrderDescending {
+				values.Set("order_by", "-"+c.OrderBy)
+			} else {
+				values.Set("order_by", c.OrderBy)
+			}
+		}
+
+		req.URL.RawQuery = values.Encode()
 		return req, nil
 	}
 }
 
+// v3FilterParams names the v3 parameter for each v2 filter it has one for.
+var v3FilterParams = map[string]string{
+	"organization_guid": "organization_guids",
+	"space_guid":        "space_guids",
+	"name":              "names",
+}
+
+// addV3FilterParams leaves out filters the v3 API has no parameter for,
+// such as diego, so the caller has to apply those to the results itself.
+func addV3FilterParams(values url.Values, filter Filter) {
+	switch f := filter.(type) {
+	case Filters:
+		for _, each := range f {
+			addV3FilterParams(values, each)
+		}
+	case EqualFilter:
+		if name, ok := v3FilterParams[f.Name]; ok {
+			values.Set(name, fmt.Sprint(f.Value))
+		}
+	case InclusionFilter:
+		if name, ok := v3FilterParams[f.Name]; ok {
+			vals := make([]string, 0, len(f.Values))
+			for _, v := range f.Values {
+				vals = append(vals, fmt.Sprint(v))
+			}
+			values.Set(name, strings.Join(vals, ","))
+		}
+	}
+}
+
 func (c *Client) Authorize(next func() (*http.Request, error)) func() (*http.Request, error) {
 	return func() (*http.Request, error) {
 		req, err := next()
@@ -102,10 +250,10 @@ func (c *Client) Authorize(next func() (*http.Request, error)) func() (*http.Req
 			return new(http.Request), err
 		}
 
-		header := http.Header{}
-		header.Set("Authorization", c.AuthToken)
-
-		req.Header = header
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		req.Header.Set("Authorization", c.AuthToken)
 		return req, nil
 	}
 }
@@ -123,16 +271,193 @@ func generateParams(filter Filter, params map[string]interface{}) url.Values {
 	return values
 }
 
-func NewHttpClient(cliConnection Connection) (*http.Client, error) {
-	skipVerify, err := cliConnection.IsSSLDisabled()
+// Insecure is set by --insecure to skip verifying the certificate of the
+// Cloud Controller. It is not implied by targeting the Cloud Controller
+// with cf api --skip-ssl-validation, so that skipping verification is
+// always asked for on the command line.
+var Insecure bool
+
+// ErrorIfVerificationSkipped refuses to go on when the CLI targets the
+// Cloud Controller with cf api --skip-ssl-validation, which cf curl
+// follows, unless --insecure is given. Setting the Diego flag goes through
+// cf curl, so without this it would skip verification that the plugin's
+// own requests do not.
+func ErrorIfVerificationSkipped(cliConnection Connection) error {
+	if Insecure {
+		return nil
+	}
+
+	disabled, err := cliConnection.IsSSLDisabled()
+	if err != nil {
+		return err
+	}
+	if disabled {
+		return VerificationSkippedError{}
+	}
+	return nil
+}
+
+// Proxy picks the proxy for each request to the Cloud Controller. It reads
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY unless it is replaced.
+var Proxy = http.ProxyFromEnvironment
+
+// NewHttpClient returns a client for the Cloud Controller that verifies its
+// certificate unless --insecure is given, whether or not the CLI was
+// targeted with --skip-ssl-validation.
+func NewHttpClient() (*http.Client, error) {
+	return newHttpClient(Insecure)
+}
+
+// newHttpClient is NewHttpClient with skipVerify in place of --insecure.
+func newHttpClient(skipVerify bool) (*http.Client, error) {
+	minVersion, err := TLSMinVersion()
 	if err != nil {
 		return nil, err
 	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+		MinVersion:         minVersion,
+	}
+	if caCertPath := os.Getenv("CF_CA_CERT"); caCertPath != "" {
+		tlsConfig.RootCAs, err = loadCertPool(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	timeout, err := HttpTimeout()
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           Proxy,
+	}
+	if traceEnv := os.Getenv("CF_TRACE"); traceEnv != "" && traceEnv != "false" {
+		transport = &TracingTransport{
+			Transport: transport,
+			Logger:    trace.NewLogger(false, traceEnv, ""),
+		}
+	}
+
 	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
-			Proxy:           http.ProxyFromEnvironment,
-		},
+		Timeout:   timeout,
+		Transport: transport,
 	}
 	return httpClient, nil
 }
+
+const DefaultHttpTimeout = 30 * time.Second
+
+type InvalidHttpTimeoutError struct {
+	PassedValue string
+}
+
+func (e InvalidHttpTimeoutError) Error() string {
+	return fmt.Sprintf("Invalid CF_DIEGO_HTTP_TIMEOUT %q: expected a positive duration such as 30s or 2m", e.PassedValue)
+}
+
+// HttpTimeout returns the timeout for requests to the Cloud Controller,
+// taken from CF_DIEGO_HTTP_TIMEOUT when it is set.
+func HttpTimeout() (time.Duration, error) {
+	value := os.Getenv("CF_DIEGO_HTTP_TIMEOUT")
+	if value == "" {
+		return DefaultHttpTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, InvalidHttpTimeoutError{PassedValue: value}
+	}
+
+	return timeout, nil
+}
+
+// DefaultTLSMinVersion is the oldest TLS version the plugin negotiates
+// with the Cloud Controller unless CF_DIEGO_TLS_MIN_VERSION allows older.
+const DefaultTLSMinVersion = tls.VersionTLS12
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type InvalidTLSMinVersionError struct {
+	PassedValue string
+}
+
+func (e InvalidTLSMinVersionError) Error() string {
+	return fmt.Sprintf("Invalid CF_DIEGO_TLS_MIN_VERSION %q: expected 1.0, 1.1, 1.2 or 1.3", e.PassedValue)
+}
+
+// TLSMinVersion returns the oldest TLS version to accept from the Cloud
+// Controller, taken from CF_DIEGO_TLS_MIN_VERSION when it is set.
+func TLSMinVersion() (uint16, error) {
+	value := os.Getenv("CF_DIEGO_TLS_MIN_VERSION")
+	if value == "" {
+		return DefaultTLSMinVersion, nil
+	}
+
+	version, ok := tlsVersions[value]
+	if !ok {
+		return 0, InvalidTLSMinVersionError{PassedValue: value}
+	}
+
+	return version, nil
+}
+
+type TimeoutError struct {
+	URL string
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("Timed out waiting for a response from %s. Set CF_DIEGO_HTTP_TIMEOUT to wait longer.", e.URL)
+}
+
+// CertificateError is returned when the certificate of the Cloud
+// Controller cannot be verified.
+type CertificateError struct {
+	URL string
+	Err error
+}
+
+func (e CertificateError) Error() string {
+	return fmt.Sprintf("Could not verify the certificate of %s: %s. Set CF_CA_CERT to a PEM bundle with its CA, or pass --insecure to skip verification.", e.URL, e.Err)
+}
+
+// VerificationSkippedError is returned by ErrorIfVerificationSkipped.
+type VerificationSkippedError struct{}
+
+func (VerificationSkippedError) Error() string {
+	return "The CLI skips verifying the certificate of the Cloud Controller, as set by cf api --skip-ssl-validation. Pass --insecure to set the Diego flag anyway, or run cf api again without --skip-ssl-validation."
+}
+
+type InvalidCACertError struct {
+	Path string
+}
+
+func (e InvalidCACertError) Error() string {
+	return fmt.Sprintf("No PEM certificates found in CF_CA_CERT file %s", e.Path)
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pemCerts, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, InvalidCACertError{Path: path}
+	}
+
+	return pool, nil
+}
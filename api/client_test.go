@@ -1,7 +1,18 @@
 package api_test
 
 import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -24,7 +35,7 @@ var _ = Describe("Api", func() {
 
 	BeforeEach(func() {
 		baseUrl = "https://api.my-crazy-domain.com"
-		authToken = "some-auth-token"
+		authToken = "bearer some-auth-token"
 
 		cliConnection = new(apifakes.FakeConnection)
 		cliConnection.AccessTokenReturns(authToken, nil)
@@ -57,16 +68,25 @@ var _ = Describe("Api", func() {
 
 	Describe("HandleFiltersAndParameters", func() {
 		var (
-			fakeFilter *apifakes.FakeFilter
-			params     map[string]interface{}
+			fakeFilter      *apifakes.FakeFilter
+			params          map[string]interface{}
+			resultsPerPage  int
+			orderBy         string
+			orderDescending bool
 		)
 
 		BeforeEach(func() {
 			fakeFilter = new(apifakes.FakeFilter)
 			params = map[string]interface{}{}
+			resultsPerPage = 0
+			orderBy = ""
+			orderDescending = false
 		})
 
 		JustBeforeEach(func() {
+			apiClient.ResultsPerPage = resultsPerPage
+			apiClient.OrderBy = orderBy
+			apiClient.OrderDescending = orderDescending
 			requestFactory := apiClient.HandleFiltersAndParameters(func() (*http.Request, error) {
 				req := &http.Request{
 					Method: "GET",
@@ -93,6 +113,41 @@ var _ = Describe("Api", func() {
 			})
 		})
 
+		Context("when a page size is set", func() {
+			BeforeEach(func() {
+				resultsPerPage = 100
+			})
+
+			It("asks for that many results per page", func() {
+				Expect(request.URL.Query().Get("results-per-page")).To(Equal("100"))
+			})
+		})
+
+		Context("when no page size is set", func() {
+			It("leaves the page size to the Cloud Controller", func() {
+				Expect(request.URL.Query()).NotTo(HaveKey("results-per-page"))
+			})
+		})
+
+		Context("when an order is set", func() {
+			BeforeEach(func() {
+				orderBy = OrderByName
+				orderDescending = true
+			})
+
+			It("asks the Cloud Controller to sort the results", func() {
+				Expect(request.URL.Query().Get("order-by")).To(Equal("name"))
+				Expect(request.URL.Query().Get("order-direction")).To(Equal("desc"))
+			})
+		})
+
+		Context("when no order is set", func() {
+			It("leaves the order to the Cloud Controller", func() {
+				Expect(request.URL.Query()).NotTo(HaveKey("order-by"))
+				Expect(request.URL.Query()).NotTo(HaveKey("order-direction"))
+			})
+		})
+
 		Context("when given params", func() {
 			BeforeEach(func() {
 				params = map[string]interface{}{"param1": "paramValue", "param2": "some value with spaces"}
@@ -117,6 +172,45 @@ var _ = Describe("Api", func() {
 		})
 	})
 
+	Describe("NewGetV3AppsRequest", func() {
+		JustBeforeEach(func() {
+			request, err = apiClient.NewGetV3AppsRequest()
+		})
+
+		It("hits the v3 apps URL", func() {
+			Expect(request.Method).To(Equal("GET"))
+			Expect(request.URL.String()).To(Equal("https://api.my-crazy-domain.com/v3/apps"))
+		})
+	})
+
+	Describe("HandleV3FiltersAndParameters", func() {
+		newRequest := func() (*http.Request, error) {
+			return apiClient.NewGetV3AppsRequest()
+		}
+
+		It("passes the filters v3 knows as parameters of their own and drops the rest", func() {
+			filter := Filters{
+				EqualFilter{Name: "diego", Value: true},
+				EqualFilter{Name: "space_guid", Value: "some-space-guid"},
+			}
+
+			request, err = apiClient.HandleV3FiltersAndParameters(newRequest)(filter, map[string]interface{}{"page": 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(request.URL.RawQuery).To(Equal("page=2&space_guids=some-space-guid"))
+		})
+
+		It("asks for the page size and order the v3 way", func() {
+			apiClient.ResultsPerPage = 50
+			apiClient.OrderBy = OrderByName
+			apiClient.OrderDescending = true
+
+			request, err = apiClient.HandleV3FiltersAndParameters(newRequest)(Filters{}, map[string]interface{}{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(request.URL.Query().Get("per_page")).To(Equal("50"))
+			Expect(request.URL.Query().Get("order_by")).To(Equal("-name"))
+		})
+	})
+
 	Describe("NewGetSpacesRequest", func() {
 		JustBeforeEach(func() {
 			request, err = apiClient.NewGetSpacesRequest()
@@ -128,6 +222,302 @@ var _ = Describe("Api", func() {
 		})
 	})
 
+	It("does not modify the base URL when building requests", func() {
+		_, err = apiClient.NewGetAppsRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiClient.BaseUrl.String()).To(Equal(baseUrl))
+	})
+
+	Describe("NewGetStacksRequest", func() {
+		JustBeforeEach(func() {
+			request, err = apiClient.NewGetStacksRequest()
+		})
+
+		It("hits the appropriate API URL", func() {
+			Expect(request.Method).To(Equal("GET"))
+			Expect(request.URL.String()).To(Equal("https://api.my-crazy-domain.com/v2/stacks"))
+		})
+	})
+
+	Describe("NewGetOrganizationsRequest", func() {
+		JustBeforeEach(func() {
+			request, err = apiClient.NewGetOrganizationsRequest()
+		})
+
+		It("hits the appropriate API URL", func() {
+			Expect(request.Method).To(Equal("GET"))
+			Expect(request.URL.String()).To(Equal("https://api.my-crazy-domain.com/v2/organizations"))
+		})
+
+		It("identifies the plugin in the User-Agent header", func() {
+			Expect(request.Header.Get("User-Agent")).To(Equal(UserAgent))
+		})
+	})
+
+	Describe("NewGetInfoRequest", func() {
+		JustBeforeEach(func() {
+			request, err = apiClient.NewGetInfoRequest()
+		})
+
+		It("hits the appropriate API URL", func() {
+			Expect(request.Method).To(Equal("GET"))
+			Expect(request.URL.String()).To(Equal("https://api.my-crazy-domain.com/v2/info"))
+		})
+	})
+
+	Describe("NewHttpClient", func() {
+		var httpClient *http.Client
+
+		JustBeforeEach(func() {
+			httpClient, err = NewHttpClient()
+		})
+
+		It("verifies certificates", func() {
+			Expect(err).NotTo(HaveOccurred())
+			transport := httpClient.Transport.(*http.Transport)
+			Expect(transport.TLSClientConfig.InsecureSkipVerify).To(BeFalse())
+		})
+
+		Context("when --insecure is given", func() {
+			BeforeEach(func() {
+				Insecure = true
+			})
+
+			AfterEach(func() {
+				Insecure = false
+			})
+
+			It("skips certificate verification", func() {
+				Expect(err).NotTo(HaveOccurred())
+				transport := httpClient.Transport.(*http.Transport)
+				Expect(transport.TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+			})
+		})
+
+		It("refuses TLS versions older than 1.2", func() {
+			Expect(err).NotTo(HaveOccurred())
+			transport := httpClient.Transport.(*http.Transport)
+			Expect(transport.TLSClientConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		})
+
+		Context("when CF_DIEGO_TLS_MIN_VERSION is set", func() {
+			BeforeEach(func() {
+				os.Setenv("CF_DIEGO_TLS_MIN_VERSION", "1.0")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("CF_DIEGO_TLS_MIN_VERSION")
+			})
+
+			It("uses it as the minimum version", func() {
+				Expect(err).NotTo(HaveOccurred())
+				transport := httpClient.Transport.(*http.Transport)
+				Expect(transport.TLSClientConfig.MinVersion).To(Equal(uint16(tls.VersionTLS10)))
+			})
+
+			Context("when it is not a known version", func() {
+				BeforeEach(func() {
+					os.Setenv("CF_DIEGO_TLS_MIN_VERSION", "TLS1.2")
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(Equal(InvalidTLSMinVersionError{PassedValue: "TLS1.2"}))
+				})
+			})
+		})
+
+		Context("when a proxy is set", func() {
+			var (
+				proxy        *httptest.Server
+				proxied      []string
+				proxyAuth    []string
+				proxyMutex   sync.Mutex
+				proxyUserURL string
+			)
+
+			BeforeEach(func() {
+				proxied = nil
+				proxyAuth = nil
+				proxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					proxyMutex.Lock()
+					defer proxyMutex.Unlock()
+					proxied = append(proxied, r.URL.String())
+					proxyAuth = append(proxyAuth, r.Header.Get("Proxy-Authorization"))
+					w.Write([]byte(`{}`))
+				}))
+				proxyUserURL = proxy.URL
+				Proxy = func(*http.Request) (*url.URL, error) {
+					return url.Parse(proxyUserURL)
+				}
+			})
+
+			AfterEach(func() {
+				Proxy = http.ProxyFromEnvironment
+				proxy.Close()
+			})
+
+			It("sends requests through it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				res, err := httpClient.Get("http://api.example.com/v2/info")
+				Expect(err).NotTo(HaveOccurred())
+				res.Body.Close()
+
+				proxyMutex.Lock()
+				defer proxyMutex.Unlock()
+				Expect(proxied).To(Equal([]string{"http://api.example.com/v2/info"}))
+				Expect(proxyAuth).To(Equal([]string{""}))
+			})
+
+			Context("when the proxy URL has credentials", func() {
+				BeforeEach(func() {
+					proxyUserURL = strings.Replace(proxy.URL, "http://", "http://some-user:some-password@", 1)
+				})
+
+				It("authenticates with the proxy", func() {
+					Expect(err).NotTo(HaveOccurred())
+					res, err := httpClient.Get("http://api.example.com/v2/info")
+					Expect(err).NotTo(HaveOccurred())
+					res.Body.Close()
+
+					proxyMutex.Lock()
+					defer proxyMutex.Unlock()
+					Expect(proxied).To(Equal([]string{"http://api.example.com/v2/info"}))
+					credentials := base64.StdEncoding.EncodeToString([]byte("some-user:some-password"))
+					Expect(proxyAuth).To(Equal([]string{"Basic " + credentials}))
+				})
+			})
+		})
+
+		It("times out requests after the default timeout", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(httpClient.Timeout).To(Equal(DefaultHttpTimeout))
+		})
+
+		Context("when CF_DIEGO_HTTP_TIMEOUT is set", func() {
+			BeforeEach(func() {
+				os.Setenv("CF_DIEGO_HTTP_TIMEOUT", "2m")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("CF_DIEGO_HTTP_TIMEOUT")
+			})
+
+			It("uses it as the timeout", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(httpClient.Timeout).To(Equal(2 * time.Minute))
+			})
+
+			Context("when it is not a valid duration", func() {
+				BeforeEach(func() {
+					os.Setenv("CF_DIEGO_HTTP_TIMEOUT", "soon")
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(Equal(InvalidHttpTimeoutError{PassedValue: "soon"}))
+				})
+			})
+		})
+
+		It("sends requests through the proxy configured in the environment", func() {
+			Expect(err).NotTo(HaveOccurred())
+			transport := httpClient.Transport.(*http.Transport)
+			Expect(transport.Proxy).NotTo(BeNil())
+		})
+
+		Context("when CF_TRACE is set", func() {
+			BeforeEach(func() {
+				os.Setenv("CF_TRACE", "true")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("CF_TRACE")
+			})
+
+			It("traces requests", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(httpClient.Transport).To(BeAssignableToTypeOf(&TracingTransport{}))
+			})
+		})
+
+		Context("when CF_CA_CERT points at a PEM bundle", func() {
+			var (
+				server     *httptest.Server
+				caCertPath string
+			)
+
+			BeforeEach(func() {
+				server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+				caCertFile, err := ioutil.TempFile("", "ca-cert")
+				Expect(err).NotTo(HaveOccurred())
+				err = pem.Encode(caCertFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(caCertFile.Close()).To(Succeed())
+				caCertPath = caCertFile.Name()
+
+				os.Setenv("CF_CA_CERT", caCertPath)
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("CF_CA_CERT")
+				os.Remove(caCertPath)
+				server.Close()
+			})
+
+			It("trusts certificates signed by that CA", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				res, err := httpClient.Get(server.URL)
+				Expect(err).NotTo(HaveOccurred())
+				res.Body.Close()
+			})
+
+			Context("when the file has no certificates in it", func() {
+				BeforeEach(func() {
+					Expect(ioutil.WriteFile(caCertPath, []byte("not a cert"), 0600)).To(Succeed())
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(Equal(InvalidCACertError{Path: caCertPath}))
+				})
+			})
+		})
+
+	})
+
+	Describe("ErrorIfVerificationSkipped", func() {
+		It("allows a CLI that verifies certificates", func() {
+			Expect(ErrorIfVerificationSkipped(cliConnection)).To(Succeed())
+		})
+
+		Context("when the user targeted with --skip-ssl-validation", func() {
+			BeforeEach(func() {
+				cliConnection.IsSSLDisabledReturns(true, nil)
+			})
+
+			It("returns an error", func() {
+				Expect(ErrorIfVerificationSkipped(cliConnection)).To(Equal(VerificationSkippedError{}))
+			})
+
+			It("allows it when --insecure is given", func() {
+				Insecure = true
+				defer func() { Insecure = false }()
+
+				Expect(ErrorIfVerificationSkipped(cliConnection)).To(Succeed())
+			})
+		})
+
+		Context("when the CLI setting cannot be read", func() {
+			BeforeEach(func() {
+				cliConnection.IsSSLDisabledReturns(false, errors.New("no config"))
+			})
+
+			It("returns the error", func() {
+				Expect(ErrorIfVerificationSkipped(cliConnection)).To(MatchError("no config"))
+			})
+		})
+	})
+
 	Describe("EqualFilter", func() {
 		It("serializes to name:val", func() {
 			filter := EqualFilter{
@@ -181,6 +571,39 @@ var _ = Describe("Api", func() {
 		})
 	})
 
+	Describe("V3PageParser", func() {
+		It("reads the page count from the pagination envelope", func() {
+			jsonBody := `{
+   "pagination": {
+      "total_results": 3,
+      "total_pages": 2,
+      "first": {"href": "https://api.example.com/v3/apps?page=1&per_page=2"},
+      "last": {"href": "https://api.example.com/v3/apps?page=2&per_page=2"},
+      "next": {"href": "https://api.example.com/v3/apps?page=2&per_page=2"},
+      "previous": null
+   },
+   "resources": [
+      {"guid": "app-1-guid", "name": "app-1"},
+      {"guid": "app-2-guid", "name": "app-2"}
+   ]
+}`
+			pages, err := V3PageParser{}.Parse([]byte(jsonBody))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages.TotalPages).To(Equal(2))
+			Expect(pages.TotalResults).To(Equal(3))
+			Expect(pages.Resources).To(HaveLen(2))
+			Expect(pages.FollowNext).To(BeTrue())
+			Expect(pages.NextUrl).To(Equal("https://api.example.com/v3/apps?page=2&per_page=2"))
+		})
+
+		It("has no next page once next is null", func() {
+			pages, err := V3PageParser{}.Parse([]byte(`{"pagination":{"total_results":3,"total_pages":2,"next":null},"resources":[{"guid":"app-3-guid"}]}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages.FollowNext).To(BeTrue())
+			Expect(pages.NextUrl).To(BeEmpty())
+		})
+	})
+
 	Describe("PageParser", func() {
 		It("parses", func() {
 			jsonBody := `{
@@ -289,3 +712,35 @@ var _ = Describe("Api", func() {
 		})
 	})
 })
+
+var _ = Describe("NewClient", func() {
+	var cliConnection *apifakes.FakeConnection
+
+	BeforeEach(func() {
+		cliConnection = new(apifakes.FakeConnection)
+		cliConnection.ApiEndpointReturns("https://api.my-crazy-domain.com", nil)
+		cliConnection.IsLoggedInReturns(true, nil)
+	})
+
+	It("accepts a bearer token", func() {
+		cliConnection.AccessTokenReturns("Bearer eyJhbGciOiJSUzI1NiJ9.e30.c2ln", nil)
+
+		client, err := NewClient(cliConnection)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.AuthToken).To(Equal("Bearer eyJhbGciOiJSUzI1NiJ9.e30.c2ln"))
+	})
+
+	It("rejects a token without the bearer scheme", func() {
+		cliConnection.AccessTokenReturns("eyJhbGciOiJSUzI1NiJ9.e30.c2ln", nil)
+
+		_, err := NewClient(cliConnection)
+		Expect(err).To(Equal(MalformedTokenError))
+	})
+
+	It("rejects an empty token", func() {
+		cliConnection.AccessTokenReturns("", nil)
+
+		_, err := NewClient(cliConnection)
+		Expect(err).To(Equal(MalformedTokenError))
+	})
+})
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+type UnexpectedStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("Unexpected status %d from %s", e.StatusCode, e.URL)
+}
+
+// ForbiddenError is returned for a listing that the Cloud Controller
+// refuses with a 403, such as the orgs or spaces for a token that is
+// scoped to fewer of them.
+type ForbiddenError struct {
+	URL string
+}
+
+func (e ForbiddenError) Error() string {
+	return fmt.Sprintf("Not authorized to list %s", e.URL)
+}
+
+// GetInfo fetches /v2/info from the Cloud Controller that cliConnection
+// targets.
+func GetInfo(ctx context.Context, cliConnection Connection) (models.Info, error) {
+	apiClient, err := NewClient(cliConnection)
+	if err != nil {
+		return models.Info{}, err
+	}
+
+	client, err := newCloudControllerClient(cliConnection)
+	if err != nil {
+		return models.Info{}, err
+	}
+
+	req, err := apiClient.Authorize(apiClient.NewGetInfoRequest)()
+	if err != nil {
+		return models.Info{}, err
+	}
+	req = req.WithContext(ctx)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return models.Info{}, timeoutErrorFor(req, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return models.Info{}, timeoutErrorFor(req, err)
+	}
+	if err := checkJSONResponse(req, res, body); err != nil {
+		return models.Info{}, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return models.Info{}, UnexpectedStatusError{URL: req.URL.String(), StatusCode: res.StatusCode}
+	}
+	return models.InfoParser{}.Parse(body)
+}
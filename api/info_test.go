@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/api/apifakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetInfo", func() {
+	var (
+		cc            *httptest.Server
+		status        int
+		cliConnection *apifakes.FakeConnection
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2/info" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"name":"vcap","build":"2222","api_version":"2.54.0"}`))
+		}))
+
+		cliConnection = new(apifakes.FakeConnection)
+		cliConnection.IsLoggedInReturns(true, nil)
+		cliConnection.ApiEndpointReturns(cc.URL, nil)
+		cliConnection.AccessTokenReturns("bearer some-token", nil)
+	})
+
+	AfterEach(func() {
+		cc.Close()
+	})
+
+	It("returns the API version of the targeted Cloud Controller", func() {
+		info, err := api.GetInfo(context.Background(), cliConnection)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ApiVersion).To(Equal("2.54.0"))
+		Expect(info.DefaultToDiego).To(BeNil())
+	})
+
+	It("fails on a status other than 200", func() {
+		status = http.StatusInternalServerError
+
+		_, err := api.GetInfo(context.Background(), cliConnection)
+		Expect(err).To(Equal(api.UnexpectedStatusError{URL: cc.URL + "/v2/info", StatusCode: http.StatusInternalServerError}))
+	})
+
+	Context("when the certificate of the Cloud Controller cannot be verified", func() {
+		var tlsCC *httptest.Server
+
+		BeforeEach(func() {
+			tlsCC = httptest.NewTLSServer(cc.Config.Handler)
+			cliConnection.ApiEndpointReturns(tlsCC.URL, nil)
+			cliConnection.IsSSLDisabledReturns(true, nil)
+		})
+
+		AfterEach(func() {
+			tlsCC.Close()
+		})
+
+		It("fails, suggesting --insecure", func() {
+			_, err := api.GetInfo(context.Background(), cliConnection)
+			Expect(err).To(BeAssignableToTypeOf(api.CertificateError{}))
+			Expect(err.Error()).To(ContainSubstring("pass --insecure"))
+		})
+
+		It("skips verification with --insecure", func() {
+			api.Insecure = true
+			defer func() { api.Insecure = false }()
+
+			info, err := api.GetInfo(context.Background(), cliConnection)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ApiVersion).To(Equal("2.54.0"))
+		})
+	})
+})
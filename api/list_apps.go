@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+// ListAppsOptions configure ListApps.
+type ListAppsOptions struct {
+	// Filter narrows the apps down, such as to those on Diego with
+	// EqualFilter{Name: "diego", Value: true}. Every app is listed when it
+	// is nil.
+	Filter Filter
+
+	// SkipSSLValidation is the equivalent of cf api --skip-ssl-validation.
+	SkipSSLValidation bool
+}
+
+// ListApps fetches every app visible to token, a bearer token such as the
+// one printed by cf oauth-token, from the Cloud Controller at endpoint. It
+// does the fetching of the listing commands without a CF CLI plugin
+// connection, so that other Go tools can call it, and does not refresh
+// the token when it expires.
+func ListApps(ctx context.Context, endpoint string, token string, options ListAppsOptions) (models.Applications, error) {
+	baseUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !isBearerToken(token) {
+		return nil, MalformedTokenError
+	}
+
+	httpClient, err := newHttpClient(options.SkipSSLValidation)
+	if err != nil {
+		return nil, err
+	}
+	retryingClient, err := NewRetryingClient(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{BaseUrl: baseUrl, AuthToken: token}
+	requester := &PaginatedRequester{
+		RequestFactory: client.HandleFiltersAndParameters(client.Authorize(client.NewGetAppsRequest)),
+		Client:         retryingClient,
+		PageParser:     PageParser{},
+	}
+
+	filter := options.Filter
+	if filter == nil {
+		filter = Filters{}
+	}
+
+	var apps models.Applications
+	err = requester.DoEach(ctx, filter, map[string]interface{}{}, func(body []byte) error {
+		page, err := models.ApplicationsParser{}.Parse(body)
+		apps = append(apps, page...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListApps", func() {
+	var (
+		cc       *httptest.Server
+		requests []*http.Request
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			page := r.URL.Query().Get("page")
+			if page == "" {
+				page = "1"
+			}
+			fmt.Fprintf(w, `{"total_pages":2,"resources":[{"metadata":{"guid":"app-%s-guid"},"entity":{"name":"app-%s","diego":true}}]}`, page, page)
+		}))
+	})
+
+	AfterEach(func() {
+		cc.Close()
+	})
+
+	It("fetches every page of apps with the token and filter", func() {
+		apps, err := api.ListApps(context.Background(), cc.URL, "bearer some-token", api.ListAppsOptions{
+			Filter: api.EqualFilter{Name: "diego", Value: true},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(apps).To(HaveLen(2))
+		Expect(apps[0].Name).To(Equal("app-1"))
+		Expect(apps[1].Guid).To(Equal("app-2-guid"))
+
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].URL.Path).To(Equal("/v2/apps"))
+		Expect(requests[0].URL.Query().Get("q")).To(Equal("diego:true"))
+		Expect(requests[0].Header.Get("Authorization")).To(Equal("bearer some-token"))
+	})
+
+	It("lists every app without a filter", func() {
+		_, err := api.ListApps(context.Background(), cc.URL, "bearer some-token", api.ListAppsOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests[0].URL.Query().Get("q")).To(BeEmpty())
+	})
+
+	It("rejects a token that is not a bearer token", func() {
+		_, err := api.ListApps(context.Background(), cc.URL, "some-token", api.ListAppsOptions{})
+		Expect(err).To(Equal(api.MalformedTokenError))
+		Expect(requests).To(BeEmpty())
+	})
+})
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// RequestLog, when set, receives the method and URL of every request made
+// to the Cloud Controller.
+var RequestLog io.Writer
+
+// LoggingClient writes the method and URL of each request to Writer, with
+// the query unescaped so that filters are easy to read.
+type LoggingClient struct {
+	Client CloudControllerClient
+	Writer io.Writer
+}
+
+func (c *LoggingClient) Do(req *http.Request) (*http.Response, error) {
+	u := *req.URL
+	if query, err := url.QueryUnescape(u.RawQuery); err == nil {
+		u.RawQuery = query
+	}
+	fmt.Fprintf(c.Writer, "%s %s\n", req.Method, u.String())
+
+	return c.Client.Do(req)
+}
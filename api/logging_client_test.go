@@ -0,0 +1,43 @@
+package api_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/api/apifakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoggingClient", func() {
+	var (
+		fakeClient *apifakes.FakeCloudControllerClient
+		output     *bytes.Buffer
+		client     *api.LoggingClient
+	)
+
+	BeforeEach(func() {
+		fakeClient = new(apifakes.FakeCloudControllerClient)
+		fakeClient.DoReturns(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil)
+		output = new(bytes.Buffer)
+		client = &api.LoggingClient{Client: fakeClient, Writer: output}
+	})
+
+	It("prints the method and the unescaped URL before sending the request", func() {
+		request, err := http.NewRequest("GET", "https://api.example.com/v2/apps?q=space_guid%3Aspace-1&results-per-page=100", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Do(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(output.String()).To(Equal("GET https://api.example.com/v2/apps?q=space_guid:space-1&results-per-page=100\n"))
+		Expect(fakeClient.DoCallCount()).To(Equal(1))
+		Expect(fakeClient.DoArgsForCall(0)).To(Equal(request))
+	})
+})
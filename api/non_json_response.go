@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxSnippetLength bounds how much of a non-JSON body is quoted in a
+// NonJSONResponseError, as proxies tend to return whole HTML pages.
+const maxSnippetLength = 200
+
+// NonJSONResponseError is returned for a response that is declared to be
+// something other than JSON, such as the HTML error page of a proxy in
+// front of the Cloud Controller, so that it is not reported as a
+// confusing JSON parse error.
+type NonJSONResponseError struct {
+	URL         string
+	StatusCode  int
+	ContentType string
+	Snippet     string
+}
+
+func (e NonJSONResponseError) Error() string {
+	return fmt.Sprintf(
+		"Unexpected non-JSON response (HTTP %d, %s) from %s: %s",
+		e.StatusCode,
+		e.ContentType,
+		e.URL,
+		e.Snippet,
+	)
+}
+
+// checkJSONResponse fails for a response whose Content-Type is set to
+// anything but JSON, unless the body looks like JSON anyway, as it does
+// when a server leaves net/http to guess text/plain. Responses without a
+// Content-Type are left to the parser.
+func checkJSONResponse(req *http.Request, res *http.Response, body []byte) error {
+	contentType := res.Header.Get("Content-Type")
+	if contentType == "" || strings.Contains(strings.ToLower(contentType), "json") || looksLikeJSON(body) {
+		return nil
+	}
+
+	return NonJSONResponseError{
+		URL:         req.URL.String(),
+		StatusCode:  res.StatusCode,
+		ContentType: contentType,
+		Snippet:     snippet(body),
+	}
+}
+
+func looksLikeJSON(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// snippet collapses the whitespace in body and shortens it to at most
+// maxSnippetLength characters.
+func snippet(body []byte) string {
+	text := strings.Join(strings.Fields(string(body)), " ")
+
+	runes := []rune(text)
+	if len(runes) > maxSnippetLength {
+		return string(runes[:maxSnippetLength]) + "..."
+	}
+	return text
+}
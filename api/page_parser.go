@@ -3,7 +3,15 @@ package api
 import "encoding/json"
 
 type PaginatedResponse struct {
-	TotalPages int `json:"total_pages"`
+	TotalResults int               `json:"total_results"`
+	TotalPages   int               `json:"total_pages"`
+	Resources    []json.RawMessage `json:"resources"`
+
+	// FollowNext is set for APIs whose pages are followed by link, from
+	// NextUrl, rather than requested by number. NextUrl is empty on the
+	// last page.
+	FollowNext bool   `json:"-"`
+	NextUrl    string `json:"-"`
 }
 
 type PageParser struct{}
@@ -19,3 +27,41 @@ func (p PageParser) Parse(body []byte) (PaginatedResponse, error) {
 
 	return pages, nil
 }
+
+type v3PaginatedResponse struct {
+	Pagination struct {
+		TotalResults int `json:"total_results"`
+		TotalPages   int `json:"total_pages"`
+		Next         *struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"pagination"`
+	Resources []json.RawMessage `json:"resources"`
+}
+
+// V3PageParser reads the v3 envelope, which keeps the counts and the link
+// to the next page in `pagination`. Later pages are fetched by following
+// `pagination.next.href` until it is null.
+type V3PageParser struct{}
+
+func (p V3PageParser) Parse(body []byte) (PaginatedResponse, error) {
+	var pages v3PaginatedResponse
+
+	err := json.Unmarshal(body, &pages)
+	if err != nil {
+		return PaginatedResponse{}, err
+	}
+
+	var nextUrl string
+	if pages.Pagination.Next != nil {
+		nextUrl = pages.Pagination.Next.Href
+	}
+
+	return PaginatedResponse{
+		TotalResults: pages.Pagination.TotalResults,
+		TotalPages:   pages.Pagination.TotalPages,
+		Resources:    pages.Resources,
+		FollowNext:   true,
+		NextUrl:      nextUrl,
+	}, nil
+}
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 )
 
 //go:generate counterfeiter . RequestFactory
@@ -19,76 +24,242 @@ type PaginatedParser interface {
 	Parse([]byte) (PaginatedResponse, error)
 }
 
+// ProgressFunc is told about each page as it arrives, along with how many
+// results have been fetched so far.
+type ProgressFunc func(page, totalPages, results int)
+
+// TotalsFunc is told, once the first page has arrived, how many results
+// and pages there are in all, before any other page is fetched.
+type TotalsFunc func(totalResults, totalPages int)
+
 type PaginatedRequester struct {
 	RequestFactory RequestFactory
 	Client         CloudControllerClient
 	PageParser     PaginatedParser
+	Progress       ProgressFunc
+	Totals         TotalsFunc
+
+	// Page, when set, fetches only that page instead of every page.
+	Page int
 }
 
 func NewPaginatedRequester(cliConnection Connection, requestFactory RequestFactory) (*PaginatedRequester, error) {
 	pageParser := PageParser{}
 
-	httpClient, err := NewHttpClient(cliConnection)
+	client, err := newCloudControllerClient(cliConnection)
 	if err != nil {
 		return nil, err
 	}
 
 	return &PaginatedRequester{
 		RequestFactory: requestFactory,
-		Client:         httpClient,
+		Client:         client,
 		PageParser:     pageParser,
 	}, nil
 }
 
-func (p *PaginatedRequester) Do(filter Filter, params map[string]interface{}) ([][]byte, error) {
-	var noBodies [][]byte
+// newCloudControllerClient logs, retries and refreshes the token of the
+// requests made for cliConnection.
+func newCloudControllerClient(cliConnection Connection) (CloudControllerClient, error) {
+	httpClient, err := NewHttpClient()
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := p.RequestFactory(filter, params)
+	var client CloudControllerClient = httpClient
+	if RequestLog != nil {
+		client = &LoggingClient{Client: httpClient, Writer: RequestLog}
+	}
+
+	retryingClient, err := NewRetryingClient(client)
 	if err != nil {
-		return noBodies, err
+		return nil, err
 	}
 
+	return NewRefreshingClient(retryingClient, cliConnection), nil
+}
+
+// PageFunc is called with the body of each page as it arrives. Returning
+// an error stops any further pages being fetched.
+type PageFunc func(body []byte) error
+
+// Do fetches every page, or just Page when it is set. Cancelling ctx aborts
+// the request in flight and stops any further pages being fetched.
+func (p *PaginatedRequester) Do(ctx context.Context, filter Filter, params map[string]interface{}) ([][]byte, error) {
+	var noBodies [][]byte
 	var responseBodies [][]byte
 
-	res, err := p.Client.Do(req)
+	err := p.DoEach(ctx, filter, params, func(body []byte) error {
+		responseBodies = append(responseBodies, body)
+		return nil
+	})
 	if err != nil {
 		return noBodies, err
 	}
 
-	defer res.Body.Close()
-	body, err := ioutil.ReadAll(res.Body)
+	return responseBodies, nil
+}
+
+// DoEach is Do, handing each page to handle as soon as it is fetched
+// instead of holding on to every page until the last one arrives.
+func (p *PaginatedRequester) DoEach(ctx context.Context, filter Filter, params map[string]interface{}, handle PageFunc) error {
+	if p.Page > 0 {
+		params["page"] = p.Page
+	}
+
+	body, err := p.fetch(ctx, filter, params)
 	if err != nil {
-		return noBodies, err
+		return err
 	}
 
-	responseBodies = append(responseBodies, body)
+	if p.Page > 0 {
+		return handle(body)
+	}
 
 	paginatedRes, err := p.PageParser.Parse(body)
 	if err != nil {
-		return noBodies, err
+		return err
+	}
+
+	if p.Totals != nil {
+		p.Totals(paginatedRes.TotalResults, paginatedRes.TotalPages)
+	}
+
+	perPage := len(paginatedRes.Resources)
+	p.reportProgress(1, paginatedRes, perPage)
+	if err := handle(body); err != nil {
+		return err
+	}
+
+	if paginatedRes.FollowNext {
+		return p.followNext(ctx, filter, params, paginatedRes, handle)
 	}
+
 	for page := 2; page <= paginatedRes.TotalPages; page++ {
-		// construct a new request with the current page
+		if err := ctx.Err(); err != nil {
+			return contextError(err)
+		}
+
+		// fetch the current page
 		params["page"] = page
-		req, err := p.RequestFactory(filter, params)
+		body, err := p.fetch(ctx, filter, params)
 		if err != nil {
-			return noBodies, err
+			return err
+		}
+
+		p.reportProgress(page, paginatedRes, page*perPage)
+		if err := handle(body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// followNext fetches the pages after first by following the link to the
+// next page until a page has none.
+func (p *PaginatedRequester) followNext(ctx context.Context, filter Filter, params map[string]interface{}, first PaginatedResponse, handle PageFunc) error {
+	perPage := len(first.Resources)
+	next := first.NextUrl
+	for page := 2; next != ""; page++ {
+		if err := ctx.Err(); err != nil {
+			return contextError(err)
 		}
 
-		// perform the request
-		res, err := p.Client.Do(req)
+		req, err := p.RequestFactory(filter, params)
+		if err != nil {
+			return err
+		}
+		nextUrl, err := url.Parse(next)
 		if err != nil {
-			return noBodies, err
+			return err
 		}
+		req.URL = req.URL.ResolveReference(nextUrl)
 
-		defer res.Body.Close()
-		body, err = ioutil.ReadAll(res.Body)
+		body, err := p.do(ctx, req)
 		if err != nil {
-			return noBodies, err
+			return err
+		}
+		paginatedRes, err := p.PageParser.Parse(body)
+		if err != nil {
+			return err
 		}
 
-		responseBodies = append(responseBodies, body)
+		p.reportProgress(page, first, page*perPage)
+		if err := handle(body); err != nil {
+			return err
+		}
+		next = paginatedRes.NextUrl
 	}
 
-	return responseBodies, nil
+	return nil
+}
+
+func (p *PaginatedRequester) fetch(ctx context.Context, filter Filter, params map[string]interface{}) ([]byte, error) {
+	req, err := p.RequestFactory(filter, params)
+	if err != nil {
+		return nil, err
+	}
+	return p.do(ctx, req)
+}
+
+func (p *PaginatedRequester) do(ctx context.Context, req *http.Request) ([]byte, error) {
+	req = req.WithContext(ctx)
+
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return nil, timeoutErrorFor(req, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, timeoutErrorFor(req, err)
+	}
+	if err := checkJSONResponse(req, res, body); err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusForbidden {
+		return nil, ForbiddenError{URL: req.URL.String()}
+	}
+	return body, nil
+}
+
+func (p *PaginatedRequester) reportProgress(page int, paginatedRes PaginatedResponse, results int) {
+	if p.Progress == nil || paginatedRes.TotalPages < 2 {
+		return
+	}
+
+	if results > paginatedRes.TotalResults {
+		results = paginatedRes.TotalResults
+	}
+	p.Progress(page, paginatedRes.TotalPages, results)
+}
+
+// ErrInterrupted is returned once the context of a request is cancelled,
+// usually because the user pressed Ctrl-C.
+var ErrInterrupted = errors.New("Interrupted")
+
+func timeoutErrorFor(req *http.Request, err error) error {
+	if ctxErr := req.Context().Err(); ctxErr != nil {
+		return contextError(ctxErr)
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return TimeoutError{URL: req.URL.String()}
+	}
+
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) {
+		return CertificateError{URL: req.URL.String(), Err: verifyErr.Err}
+	}
+
+	return err
+}
+
+func contextError(err error) error {
+	if err == context.Canceled {
+		return ErrInterrupted
+	}
+	return err
 }
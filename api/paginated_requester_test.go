@@ -1,10 +1,13 @@
 package api_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
@@ -21,11 +24,13 @@ var _ = Describe("PaginatedRequester", func() {
 	var params map[string]interface{}
 	var testRequest *http.Request
 	var testResponse *http.Response
+	var ctx context.Context
 
 	var paginatedRequester *api.PaginatedRequester
 
 	var err error
 	var responseBodies [][]byte
+	var handle api.PageFunc
 
 	generateApiResponse := func(body string) *http.Response {
 		return &http.Response{
@@ -41,6 +46,8 @@ var _ = Describe("PaginatedRequester", func() {
 		fakeRequestFactory = new(apifakes.FakeRequestFactory)
 		fakeFilter = new(apifakes.FakeFilter)
 		params = make(map[string]interface{})
+		ctx = context.Background()
+		handle = nil
 
 		testRequest, err = http.NewRequest("GET", "something", strings.NewReader(""))
 		Expect(err).NotTo(HaveOccurred())
@@ -57,7 +64,11 @@ var _ = Describe("PaginatedRequester", func() {
 	})
 
 	JustBeforeEach(func() {
-		responseBodies, err = paginatedRequester.Do(fakeFilter, params)
+		if handle == nil {
+			responseBodies, err = paginatedRequester.Do(ctx, fakeFilter, params)
+		} else {
+			err = paginatedRequester.DoEach(ctx, fakeFilter, params, handle)
+		}
 	})
 
 	It("should create a request", func() {
@@ -86,10 +97,8 @@ var _ = Describe("PaginatedRequester", func() {
 		It("should make a request", func() {
 			Expect(fakeCloudControllerClient.DoCallCount()).To(Equal(1))
 
-			expectedRequest, err := http.NewRequest("GET", "something", strings.NewReader(""))
-			Expect(err).NotTo(HaveOccurred())
-
-			Expect(fakeCloudControllerClient.DoArgsForCall(0)).To(Equal(expectedRequest))
+			Expect(fakeCloudControllerClient.DoArgsForCall(0).URL).To(Equal(testRequest.URL))
+			Expect(fakeCloudControllerClient.DoArgsForCall(0).Context()).To(Equal(ctx))
 		})
 
 		Context("when making the request fails", func() {
@@ -106,6 +115,85 @@ var _ = Describe("PaginatedRequester", func() {
 			})
 		})
 
+		Context("when the request times out", func() {
+			BeforeEach(func() {
+				fakeCloudControllerClient.DoReturns(nil, &url.Error{Op: "Get", URL: "something", Err: timeoutError{}})
+			})
+
+			It("returns a timeout error naming the URL", func() {
+				Expect(responseBodies).To(BeEmpty())
+				Expect(err).To(Equal(api.TimeoutError{URL: "something"}))
+			})
+		})
+
+		Context("when the context is cancelled during the request", func() {
+			BeforeEach(func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(context.Background())
+				fakeCloudControllerClient.DoStub = func(*http.Request) (*http.Response, error) {
+					cancel()
+					return nil, &url.Error{Op: "Get", URL: "something", Err: context.Canceled}
+				}
+			})
+
+			It("returns an interrupted error", func() {
+				Expect(responseBodies).To(BeEmpty())
+				Expect(err).To(Equal(api.ErrInterrupted))
+			})
+		})
+
+		Context("when the context deadline passes during the request", func() {
+			BeforeEach(func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(context.Background(), 0)
+				defer cancel()
+				fakeCloudControllerClient.DoReturns(nil, &url.Error{Op: "Get", URL: "something", Err: context.DeadlineExceeded})
+			})
+
+			It("returns the deadline error", func() {
+				Expect(err).To(Equal(context.DeadlineExceeded))
+			})
+		})
+
+		Context("when a proxy answers with an HTML error page", func() {
+			BeforeEach(func() {
+				response := generateApiResponse("<html>\n  <body>504 Gateway Time-out</body>\n</html>")
+				response.StatusCode = http.StatusGatewayTimeout
+				response.Header = http.Header{"Content-Type": []string{"text/html"}}
+				fakeCloudControllerClient.DoReturns(response, nil)
+			})
+
+			It("returns the status and a snippet of the body instead of parsing it", func() {
+				Expect(fakePaginatedParser.ParseCallCount()).To(Equal(0))
+				Expect(err).To(MatchError("Unexpected non-JSON response (HTTP 504, text/html) from something: <html> <body>504 Gateway Time-out</body> </html>"))
+			})
+
+			Context("when the page is long", func() {
+				BeforeEach(func() {
+					response := generateApiResponse(strings.Repeat("x", 300))
+					response.Header = http.Header{"Content-Type": []string{"text/html"}}
+					fakeCloudControllerClient.DoReturns(response, nil)
+				})
+
+				It("shortens the snippet", func() {
+					Expect(err.(api.NonJSONResponseError).Snippet).To(Equal(strings.Repeat("x", 200) + "..."))
+				})
+			})
+		})
+
+		Context("when the Cloud Controller refuses the listing", func() {
+			BeforeEach(func() {
+				response := generateApiResponse(`{"code": 10003, "error_code": "CF-NotAuthorized"}`)
+				response.StatusCode = http.StatusForbidden
+				fakeCloudControllerClient.DoReturns(response, nil)
+			})
+
+			It("returns a forbidden error instead of parsing it", func() {
+				Expect(fakePaginatedParser.ParseCallCount()).To(Equal(0))
+				Expect(err).To(Equal(api.ForbiddenError{URL: "something"}))
+			})
+		})
+
 		Context("when making the request succeeds", func() {
 			response := generateApiResponse("")
 
@@ -168,6 +256,67 @@ var _ = Describe("PaginatedRequester", func() {
 						}
 					})
 
+					Context("when the pages are followed by link", func() {
+						BeforeEach(func() {
+							var parsed int
+							fakePaginatedParser.ParseStub = func([]byte) (api.PaginatedResponse, error) {
+								parsed++
+								switch parsed {
+								case 1:
+									return api.PaginatedResponse{TotalPages: 1, FollowNext: true, NextUrl: "https://api.example.com/v3/apps?page=2&per_page=2"}, nil
+								case 2:
+									return api.PaginatedResponse{TotalPages: 1, FollowNext: true, NextUrl: "/v3/apps?page=3&per_page=2"}, nil
+								}
+								return api.PaginatedResponse{TotalPages: 1, FollowNext: true}, nil
+							}
+							fakeRequestFactory.Stub = func(api.Filter, map[string]interface{}) (*http.Request, error) {
+								return http.NewRequest("GET", "https://api.example.com/v3/apps", nil)
+							}
+							fakeCloudControllerClient.DoStub = func(*http.Request) (*http.Response, error) {
+								return generateApiResponse("some-body"), nil
+							}
+						})
+
+						It("follows the next link until there is none, whatever the page count says", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeCloudControllerClient.DoCallCount()).To(Equal(3))
+							Expect(fakeCloudControllerClient.DoArgsForCall(1).URL.String()).To(Equal("https://api.example.com/v3/apps?page=2&per_page=2"))
+							Expect(fakeCloudControllerClient.DoArgsForCall(2).URL.String()).To(Equal("https://api.example.com/v3/apps?page=3&per_page=2"))
+							Expect(responseBodies).To(HaveLen(3))
+						})
+					})
+
+					Context("when a single page is requested", func() {
+						BeforeEach(func() {
+							paginatedRequester.Page = 3
+						})
+
+						It("fetches only that page", func() {
+							Expect(fakeRequestFactory.CallCount()).To(Equal(1))
+
+							_, params := fakeRequestFactory.ArgsForCall(0)
+							Expect(params["page"]).To(Equal(3))
+							Expect(responseBodies).To(Equal([][]byte{[]byte("some-body")}))
+						})
+					})
+
+					Context("when the context is cancelled after the first page", func() {
+						BeforeEach(func() {
+							var cancel context.CancelFunc
+							ctx, cancel = context.WithCancel(context.Background())
+							fakeCloudControllerClient.DoStub = func(*http.Request) (*http.Response, error) {
+								cancel()
+								return testResponse, nil
+							}
+						})
+
+						It("stops before fetching the next page", func() {
+							Expect(fakeCloudControllerClient.DoCallCount()).To(Equal(1))
+							Expect(responseBodies).To(BeEmpty())
+							Expect(err).To(Equal(api.ErrInterrupted))
+						})
+					})
+
 					It("calls for more results", func() {
 						Expect(fakeRequestFactory.CallCount()).To(Equal(2))
 
@@ -175,14 +324,103 @@ var _ = Describe("PaginatedRequester", func() {
 						Expect(params["page"]).To(Equal(2))
 					})
 
+					Context("when progress is reported", func() {
+						type progress struct{ page, totalPages, results int }
+						var reported []progress
+
+						BeforeEach(func() {
+							reported = nil
+							fakePaginatedParser.ParseReturns(api.PaginatedResponse{
+								TotalResults: 150,
+								TotalPages:   2,
+								Resources:    make([]json.RawMessage, 100),
+							}, nil)
+							paginatedRequester.Progress = func(page, totalPages, results int) {
+								reported = append(reported, progress{page, totalPages, results})
+							}
+						})
+
+						It("reports each page with the results fetched so far", func() {
+							Expect(reported).To(Equal([]progress{
+								{page: 1, totalPages: 2, results: 100},
+								{page: 2, totalPages: 2, results: 150},
+							}))
+						})
+					})
+
+					Context("when the totals are asked for", func() {
+						var totals []int
+						var callsWhenTold int
+
+						BeforeEach(func() {
+							fakePaginatedParser.ParseReturns(api.PaginatedResponse{
+								TotalResults: 150,
+								TotalPages:   2,
+							}, nil)
+							paginatedRequester.Totals = func(totalResults, totalPages int) {
+								totals = append(totals, totalResults, totalPages)
+								callsWhenTold = fakeCloudControllerClient.DoCallCount()
+							}
+						})
+
+						It("reports them once, before fetching the other pages", func() {
+							Expect(totals).To(Equal([]int{150, 2}))
+							Expect(callsWhenTold).To(Equal(1))
+						})
+					})
+
 					It("contains the list of all byte slices of response bodies", func() {
 						Expect(responseBodies).To(Equal([][]byte{
 							[]byte("some-body"),
 							[]byte("some-second-body"),
 						}))
 					})
+
+					Context("when the pages are handed over as they arrive", func() {
+						var handled [][]byte
+						var callsWhenHandled []int
+						var handleErr error
+
+						BeforeEach(func() {
+							handled = nil
+							callsWhenHandled = nil
+							handleErr = nil
+							handle = func(body []byte) error {
+								handled = append(handled, body)
+								callsWhenHandled = append(callsWhenHandled, fakeCloudControllerClient.DoCallCount())
+								return handleErr
+							}
+						})
+
+						It("hands over each page before fetching the next", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(handled).To(Equal([][]byte{
+								[]byte("some-body"),
+								[]byte("some-second-body"),
+							}))
+							Expect(callsWhenHandled).To(Equal([]int{1, 2}))
+						})
+
+						Context("when handling a page fails", func() {
+							BeforeEach(func() {
+								handleErr = errors.New("handle err")
+							})
+
+							It("stops before fetching the next page", func() {
+								Expect(err).To(Equal(handleErr))
+								Expect(handled).To(HaveLen(1))
+								Expect(fakeCloudControllerClient.DoCallCount()).To(Equal(1))
+							})
+						})
+					})
 				})
 			})
 		})
 	})
 })
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+var SessionExpiredError = errors.New("Your session has expired, please run cf login")
+
+// RefreshingClient retries a request once with a fresh access token when
+// the Cloud Controller rejects the current one, and keeps using the fresh
+// token for the requests that follow.
+type RefreshingClient struct {
+	Client      CloudControllerClient
+	AccessToken func() (string, error)
+
+	token string
+}
+
+func NewRefreshingClient(client CloudControllerClient, cliConnection Connection) *RefreshingClient {
+	return &RefreshingClient{
+		Client:      client,
+		AccessToken: cliConnection.AccessToken,
+	}
+}
+
+func (c *RefreshingClient) Do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req = withAuthorization(req, c.token)
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	res.Body.Close()
+
+	token, err := c.AccessToken()
+	if err != nil {
+		return nil, err
+	}
+	c.token = token
+
+	res, err = c.Client.Do(withAuthorization(req, token))
+	if err != nil {
+		return res, err
+	}
+	if res.StatusCode == http.StatusUnauthorized {
+		res.Body.Close()
+		return nil, SessionExpiredError
+	}
+
+	return res, nil
+}
+
+func withAuthorization(req *http.Request, token string) *http.Request {
+	authorized := req.Clone(req.Context())
+	if authorized.Header == nil {
+		authorized.Header = http.Header{}
+	}
+	authorized.Header.Set("Authorization", token)
+	return authorized
+}
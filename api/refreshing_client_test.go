@@ -0,0 +1,114 @@
+package api_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/api/apifakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RefreshingClient", func() {
+	var (
+		fakeClient       *apifakes.FakeCloudControllerClient
+		refreshingClient *api.RefreshingClient
+		refreshes        int
+		refreshErr       error
+
+		request  *http.Request
+		response *http.Response
+		err      error
+	)
+
+	responseWithStatus := func(statusCode int) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+	}
+
+	BeforeEach(func() {
+		fakeClient = new(apifakes.FakeCloudControllerClient)
+		refreshes = 0
+		refreshErr = nil
+		refreshingClient = &api.RefreshingClient{
+			Client: fakeClient,
+			AccessToken: func() (string, error) {
+				refreshes++
+				return "bearer fresh-token", refreshErr
+			},
+		}
+
+		request, err = http.NewRequest("GET", "https://api.example.com/v2/apps", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.Header.Set("Authorization", "bearer stale-token")
+	})
+
+	JustBeforeEach(func() {
+		response, err = refreshingClient.Do(request)
+	})
+
+	Context("when the token is accepted", func() {
+		BeforeEach(func() {
+			fakeClient.DoReturns(responseWithStatus(http.StatusOK), nil)
+		})
+
+		It("does not refresh it", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeClient.DoCallCount()).To(Equal(1))
+			Expect(refreshes).To(Equal(0))
+		})
+	})
+
+	Context("when the token has expired", func() {
+		BeforeEach(func() {
+			fakeClient.DoStub = func(req *http.Request) (*http.Response, error) {
+				if req.Header.Get("Authorization") == "bearer fresh-token" {
+					return responseWithStatus(http.StatusOK), nil
+				}
+				return responseWithStatus(http.StatusUnauthorized), nil
+			}
+		})
+
+		It("retries once with a fresh token", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeClient.DoCallCount()).To(Equal(2))
+			Expect(refreshes).To(Equal(1))
+		})
+
+		It("keeps using the fresh token", func() {
+			_, err = refreshingClient.Do(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeClient.DoCallCount()).To(Equal(3))
+			Expect(fakeClient.DoArgsForCall(2).Header.Get("Authorization")).To(Equal("bearer fresh-token"))
+			Expect(refreshes).To(Equal(1))
+		})
+
+		Context("when refreshing the token fails", func() {
+			BeforeEach(func() {
+				refreshErr = errors.New("refresh failed")
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(MatchError("refresh failed"))
+			})
+		})
+	})
+
+	Context("when the fresh token is rejected too", func() {
+		BeforeEach(func() {
+			fakeClient.DoReturns(responseWithStatus(http.StatusUnauthorized), nil)
+		})
+
+		It("reports that the session has expired", func() {
+			Expect(err).To(Equal(api.SessionExpiredError))
+			Expect(fakeClient.DoCallCount()).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+)
+
+type InvalidRetrySettingError struct {
+	Name        string
+	PassedValue string
+}
+
+func (e InvalidRetrySettingError) Error() string {
+	return fmt.Sprintf("Invalid %s %q", e.Name, e.PassedValue)
+}
+
+// RetryingClient retries GET requests that fail with a transient gateway
+// status, doubling the delay between attempts, and any request that is
+// rate limited with a 429, after the delay given by its Retry-After header.
+// Any other response, including 401 and 404, is returned straight away.
+type RetryingClient struct {
+	Client     CloudControllerClient
+	MaxRetries int
+	BaseDelay  time.Duration
+	Sleep      func(time.Duration)
+}
+
+// NewRetryingClient wraps client using CF_DIEGO_HTTP_RETRIES and
+// CF_DIEGO_HTTP_RETRY_DELAY when they are set.
+func NewRetryingClient(client CloudControllerClient) (*RetryingClient, error) {
+	maxRetries := DefaultMaxRetries
+	if value := os.Getenv("CF_DIEGO_HTTP_RETRIES"); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return nil, InvalidRetrySettingError{Name: "CF_DIEGO_HTTP_RETRIES", PassedValue: value}
+		}
+		maxRetries = retries
+	}
+
+	baseDelay := DefaultRetryDelay
+	if value := os.Getenv("CF_DIEGO_HTTP_RETRY_DELAY"); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return nil, InvalidRetrySettingError{Name: "CF_DIEGO_HTTP_RETRY_DELAY", PassedValue: value}
+		}
+		baseDelay = delay
+	}
+
+	return &RetryingClient{
+		Client:     client,
+		MaxRetries: maxRetries,
+		BaseDelay:  baseDelay,
+		Sleep:      time.Sleep,
+	}, nil
+}
+
+func (c *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	delay := c.BaseDelay
+	for attempt := 0; ; attempt++ {
+		res, err := c.Client.Do(req)
+		if err != nil || attempt >= c.MaxRetries {
+			return res, err
+		}
+
+		wait := delay
+		switch {
+		case res.StatusCode == http.StatusTooManyRequests && (req.Body == nil || req.GetBody != nil):
+			if retryAfter, ok := RetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				wait = retryAfter
+			}
+		case req.Method == "GET" && isRetryableStatus(res.StatusCode):
+		default:
+			return res, err
+		}
+
+		res.Body.Close()
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+		c.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// RetryAfter reads a Retry-After header, given in seconds or as an HTTP
+// date, as the time to wait from now. ok is false if there is no valid
+// header.
+func RetryAfter(value string, now time.Time) (wait time.Duration, ok bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait = date.Sub(now); wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
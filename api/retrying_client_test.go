@@ -0,0 +1,239 @@
+package api_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/api/apifakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryingClient", func() {
+	var (
+		fakeClient     *apifakes.FakeCloudControllerClient
+		retryingClient *api.RetryingClient
+		sleeps         []time.Duration
+
+		request  *http.Request
+		response *http.Response
+		err      error
+	)
+
+	responseWithStatus := func(statusCode int) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+	}
+
+	BeforeEach(func() {
+		fakeClient = new(apifakes.FakeCloudControllerClient)
+		sleeps = nil
+		retryingClient = &api.RetryingClient{
+			Client:     fakeClient,
+			MaxRetries: 3,
+			BaseDelay:  time.Second,
+			Sleep: func(d time.Duration) {
+				sleeps = append(sleeps, d)
+			},
+		}
+
+		request, err = http.NewRequest("GET", "https://api.example.com/v2/apps", nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		response, err = retryingClient.Do(request)
+	})
+
+	Context("when the request succeeds", func() {
+		BeforeEach(func() {
+			fakeClient.DoReturns(responseWithStatus(http.StatusOK), nil)
+		})
+
+		It("makes the request once", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeClient.DoCallCount()).To(Equal(1))
+			Expect(sleeps).To(BeEmpty())
+		})
+	})
+
+	Context("when the Cloud Controller is temporarily unavailable", func() {
+		BeforeEach(func() {
+			var i int
+			fakeClient.DoStub = func(*http.Request) (*http.Response, error) {
+				i++
+				if i < 3 {
+					return responseWithStatus(http.StatusServiceUnavailable), nil
+				}
+				return responseWithStatus(http.StatusOK), nil
+			}
+		})
+
+		It("retries with exponential backoff", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeClient.DoCallCount()).To(Equal(3))
+			Expect(sleeps).To(Equal([]time.Duration{time.Second, 2 * time.Second}))
+		})
+	})
+
+	Context("when the Cloud Controller keeps failing", func() {
+		BeforeEach(func() {
+			fakeClient.DoStub = func(*http.Request) (*http.Response, error) {
+				return responseWithStatus(http.StatusBadGateway), nil
+			}
+		})
+
+		It("gives up after the maximum number of retries", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(fakeClient.DoCallCount()).To(Equal(4))
+		})
+	})
+
+	Context("when the status is not retryable", func() {
+		BeforeEach(func() {
+			fakeClient.DoReturns(responseWithStatus(http.StatusUnauthorized), nil)
+		})
+
+		It("fails fast", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(fakeClient.DoCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the request is not a GET", func() {
+		BeforeEach(func() {
+			request.Method = "PUT"
+			fakeClient.DoReturns(responseWithStatus(http.StatusServiceUnavailable), nil)
+		})
+
+		It("does not retry it", func() {
+			Expect(fakeClient.DoCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the request is rate limited", func() {
+		BeforeEach(func() {
+			request.Method = "PUT"
+			var i int
+			fakeClient.DoStub = func(*http.Request) (*http.Response, error) {
+				i++
+				if i == 1 {
+					response := responseWithStatus(http.StatusTooManyRequests)
+					response.Header = http.Header{"Retry-After": []string{"7"}}
+					return response, nil
+				}
+				return responseWithStatus(http.StatusOK), nil
+			}
+		})
+
+		It("waits for as long as Retry-After says and retries, whatever the method", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeClient.DoCallCount()).To(Equal(2))
+			Expect(sleeps).To(Equal([]time.Duration{7 * time.Second}))
+		})
+	})
+
+	Context("when the request is rate limited without a Retry-After header", func() {
+		BeforeEach(func() {
+			fakeClient.DoStub = func(*http.Request) (*http.Response, error) {
+				return responseWithStatus(http.StatusTooManyRequests), nil
+			}
+		})
+
+		It("backs off exponentially and gives up after the maximum number of retries", func() {
+			Expect(response.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(fakeClient.DoCallCount()).To(Equal(4))
+			Expect(sleeps).To(Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second}))
+		})
+	})
+
+	Context("when the request errors", func() {
+		var requestErr = errors.New("connection refused")
+
+		BeforeEach(func() {
+			fakeClient.DoReturns(nil, requestErr)
+		})
+
+		It("returns the error", func() {
+			Expect(err).To(Equal(requestErr))
+			Expect(fakeClient.DoCallCount()).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("RetryAfter", func() {
+	now := time.Date(2016, 3, 16, 16, 40, 43, 0, time.UTC)
+
+	It("reads a number of seconds", func() {
+		wait, ok := api.RetryAfter("30", now)
+		Expect(ok).To(BeTrue())
+		Expect(wait).To(Equal(30 * time.Second))
+	})
+
+	It("reads an HTTP date", func() {
+		wait, ok := api.RetryAfter("Wed, 16 Mar 2016 16:41:13 GMT", now)
+		Expect(ok).To(BeTrue())
+		Expect(wait).To(Equal(30 * time.Second))
+	})
+
+	It("does not wait for a date in the past", func() {
+		wait, ok := api.RetryAfter("Wed, 16 Mar 2016 16:00:00 GMT", now)
+		Expect(ok).To(BeTrue())
+		Expect(wait).To(BeZero())
+	})
+
+	It("rejects missing and malformed values", func() {
+		for _, value := range []string{"", "-1", "soon"} {
+			_, ok := api.RetryAfter(value, now)
+			Expect(ok).To(BeFalse(), value)
+		}
+	})
+})
+
+var _ = Describe("NewRetryingClient", func() {
+	var fakeClient *apifakes.FakeCloudControllerClient
+
+	BeforeEach(func() {
+		fakeClient = new(apifakes.FakeCloudControllerClient)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CF_DIEGO_HTTP_RETRIES")
+		os.Unsetenv("CF_DIEGO_HTTP_RETRY_DELAY")
+	})
+
+	It("uses the defaults", func() {
+		client, err := api.NewRetryingClient(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.MaxRetries).To(Equal(api.DefaultMaxRetries))
+		Expect(client.BaseDelay).To(Equal(api.DefaultRetryDelay))
+	})
+
+	It("reads the settings from the environment", func() {
+		os.Setenv("CF_DIEGO_HTTP_RETRIES", "5")
+		os.Setenv("CF_DIEGO_HTTP_RETRY_DELAY", "2s")
+
+		client, err := api.NewRetryingClient(fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.MaxRetries).To(Equal(5))
+		Expect(client.BaseDelay).To(Equal(2 * time.Second))
+	})
+
+	It("rejects invalid settings", func() {
+		os.Setenv("CF_DIEGO_HTTP_RETRIES", "lots")
+
+		_, err := api.NewRetryingClient(fakeClient)
+		Expect(err).To(Equal(api.InvalidRetrySettingError{Name: "CF_DIEGO_HTTP_RETRIES", PassedValue: "lots"}))
+	})
+})
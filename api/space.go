@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+// SpaceFetcher fetches single spaces, as linked from the space_url of an
+// app, instead of listing every space.
+type SpaceFetcher struct {
+	RequestFactory func(guid string) (*http.Request, error)
+	Client         CloudControllerClient
+}
+
+func NewSpaceFetcher(cliConnection Connection, apiClient *Client) (*SpaceFetcher, error) {
+	client, err := newCloudControllerClient(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpaceFetcher{
+		RequestFactory: func(guid string) (*http.Request, error) {
+			return apiClient.Authorize(func() (*http.Request, error) {
+				return apiClient.NewGetSpaceRequest(guid)
+			})()
+		},
+		Client: client,
+	}, nil
+}
+
+// GetSpace fetches the space with guid, with its organization. ok is false
+// for a space that does not exist or that the user cannot see, which the
+// Cloud Controller answers with a 404 or a 403.
+func (f *SpaceFetcher) GetSpace(ctx context.Context, guid string) (space models.Space, ok bool, err error) {
+	req, err := f.RequestFactory(guid)
+	if err != nil {
+		return models.Space{}, false, err
+	}
+	req = req.WithContext(ctx)
+
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return models.Space{}, false, timeoutErrorFor(req, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return models.Space{}, false, timeoutErrorFor(req, err)
+	}
+	if err := checkJSONResponse(req, res, body); err != nil {
+		return models.Space{}, false, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden, http.StatusNotFound:
+		return models.Space{}, false, nil
+	default:
+		return models.Space{}, false, UnexpectedStatusError{URL: req.URL.String(), StatusCode: res.StatusCode}
+	}
+
+	space, err = models.SpaceParser{}.Parse(body)
+	if err != nil {
+		return models.Space{}, false, err
+	}
+	return space, true, nil
+}
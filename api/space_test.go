@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/api/apifakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SpaceFetcher", func() {
+	var (
+		cc      *httptest.Server
+		status  int
+		query   string
+		fetcher *api.SpaceFetcher
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2/spaces/space-guid" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":40004,"error_code":"CF-SpaceNotFound"}`))
+				return
+			}
+			query = r.URL.RawQuery
+			w.WriteHeader(status)
+			w.Write([]byte(`{"metadata":{"guid":"space-guid"},"entity":{"name":"some-space","organization":{"metadata":{"guid":"org-guid"},"entity":{"name":"some-org"}}}}`))
+		}))
+
+		cliConnection := new(apifakes.FakeConnection)
+		cliConnection.IsLoggedInReturns(true, nil)
+		cliConnection.ApiEndpointReturns(cc.URL, nil)
+		cliConnection.AccessTokenReturns("bearer some-token", nil)
+
+		apiClient, err := api.NewClient(cliConnection)
+		Expect(err).NotTo(HaveOccurred())
+		fetcher, err = api.NewSpaceFetcher(cliConnection, apiClient)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cc.Close()
+	})
+
+	It("fetches the space with its organization", func() {
+		space, ok, err := fetcher.GetSpace(context.Background(), "space-guid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(space.Name).To(Equal("some-space"))
+		Expect(space.Organization.Name).To(Equal("some-org"))
+		Expect(query).To(Equal("inline-relations-depth=1"))
+	})
+
+	It("reports a space that is not found as missing", func() {
+		_, ok, err := fetcher.GetSpace(context.Background(), "other-space-guid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("fails on an unexpected status", func() {
+		status = http.StatusInternalServerError
+
+		_, _, err := fetcher.GetSpace(context.Background(), "space-guid")
+		Expect(err).To(Equal(api.UnexpectedStatusError{URL: cc.URL + "/v2/spaces/space-guid?inline-relations-depth=1", StatusCode: http.StatusInternalServerError}))
+	})
+})
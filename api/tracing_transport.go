@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"time"
+
+	"github.com/cloudfoundry/cli/cf/trace"
+)
+
+var authorizationHeader = regexp.MustCompile(`(?m)^Authorization: .*`)
+
+// TracingTransport dumps every request and response to Logger, the way the
+// core CLI does when CF_TRACE is set. Access tokens are left out.
+type TracingTransport struct {
+	Transport http.RoundTripper
+	Logger    trace.Printer
+}
+
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return nil, err
+	}
+	t.Logger.Printf("\nREQUEST: [%s]\n%s\n", time.Now().Format(time.RFC3339), sanitize(dump))
+
+	res, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	dump, err = httputil.DumpResponse(res, true)
+	if err != nil {
+		return nil, err
+	}
+	t.Logger.Printf("\nRESPONSE: [%s]\n%s\n", time.Now().Format(time.RFC3339), sanitize(dump))
+
+	return res, nil
+}
+
+func sanitize(dump []byte) string {
+	return authorizationHeader.ReplaceAllString(string(dump), "Authorization: [PRIVATE DATA HIDDEN]")
+}
@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry/cli/cf/trace"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var _ = Describe("TracingTransport", func() {
+	var (
+		buf       *gbytes.Buffer
+		transport *api.TracingTransport
+		roundTrip roundTripperFunc
+
+		request  *http.Request
+		response *http.Response
+		err      error
+	)
+
+	BeforeEach(func() {
+		buf = gbytes.NewBuffer()
+		roundTrip = func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"resources":[]}`)),
+			}, nil
+		}
+
+		request, err = http.NewRequest("GET", "https://api.example.com/v2/apps", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.Header.Set("Authorization", "bearer some-secret-token")
+	})
+
+	JustBeforeEach(func() {
+		transport = &api.TracingTransport{
+			Transport: roundTrip,
+			Logger:    trace.NewWriterPrinter(buf, false),
+		}
+		response, err = transport.RoundTrip(request)
+	})
+
+	It("dumps the request without the access token", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf).To(gbytes.Say("REQUEST:"))
+		Expect(buf).To(gbytes.Say("GET /v2/apps HTTP/1.1"))
+		Expect(buf).To(gbytes.Say(`Authorization: \[PRIVATE DATA HIDDEN\]`))
+		Expect(string(buf.Contents())).NotTo(ContainSubstring("some-secret-token"))
+	})
+
+	It("dumps the response and leaves its body readable", func() {
+		Expect(buf).To(gbytes.Say("RESPONSE:"))
+		Expect(buf).To(gbytes.Say("200 OK"))
+		Expect(buf).To(gbytes.Say(`\{"resources":\[\]\}`))
+
+		body, err := ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"resources":[]}`))
+	})
+
+	Context("when the request fails", func() {
+		var requestErr = errors.New("connection refused")
+
+		BeforeEach(func() {
+			roundTrip = func(*http.Request) (*http.Response, error) {
+				return nil, requestErr
+			}
+		})
+
+		It("returns the error", func() {
+			Expect(err).To(Equal(requestErr))
+			Expect(string(buf.Contents())).NotTo(ContainSubstring("RESPONSE:"))
+		})
+	})
+})
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+// DiegoEnabler refers to showApiInfo, which refers back to DiegoEnabler, so
+// the option is set once both are initialized.
+func init() {
+	DiegoEnabler.ShowApiInfo = showApiInfo
+}
+
+func showApiInfo() error {
+	cliConnection := DiegoEnabler.CLIConnection
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	info, err := api.GetInfo(ctx, cliConnection)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := cliConnection.ApiEndpoint()
+	if err != nil {
+		return err
+	}
+
+	ui.SayApiInfo(endpoint, info)
+	return nil
+}
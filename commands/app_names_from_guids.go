@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type AppNamesFromGuidsCommand struct {
+	GuidsFile string `long:"guids-file" value-name:"PATH" description:"File listing one app guid per line to look up"`
+	Stdin     bool   `long:"stdin" description:"Read one app guid per line from stdin to look up"`
+	Output    string `long:"output" value-name:"FORMAT" choice:"table" choice:"json" default:"table" description:"Output format for the mapping: table or json"`
+}
+
+func (command AppNamesFromGuidsCommand) Execute([]string) error {
+	cliConnection := DiegoEnabler.CLIConnection
+	ui.UseOutputFormat(command.Output)
+
+	if command.Stdin == (command.GuidsFile != "") {
+		return GuidsSourceError{}
+	}
+
+	appGuids, err := readAppGuids(command.GuidsFile)
+	if err != nil {
+		return err
+	}
+
+	appNamesCommand, err := diegohelpers.NewAppNamesCommand(cliConnection, command.Output)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	return diegohelpers.ResolveAppGuids(ctx, cliConnection, appGuids, &appNamesCommand)
+}
+
+// GuidsSourceError is returned unless exactly one of --guids-file and
+// --stdin is given.
+type GuidsSourceError struct{}
+
+func (GuidsSourceError) Error() string {
+	return "Specify either --guids-file or --stdin."
+}
+
+// readAppGuids reads one app guid per line from guidsFile or, when it is
+// not set, from stdin.
+func readAppGuids(guidsFile string) ([]string, error) {
+	if guidsFile == "" {
+		return diegohelpers.ReadLines(os.Stdin)
+	}
+	return diegohelpers.ReadAppNames(guidsFile)
+}
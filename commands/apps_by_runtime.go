@@ -0,0 +1,23 @@
+package commands
+
+import "github.com/cloudfoundry-incubator/diego-enabler/ui"
+
+type AppsByRuntimeCommand struct {
+	ListAppsOptions
+	Diego string `long:"diego" value-name:"BOOL" choice:"true" choice:"false" description:"Only list apps whose Diego flag is true or false, as diego-apps and dea-apps do"`
+}
+
+func (command AppsByRuntimeCommand) Execute([]string) error {
+	return command.listApps(command.runtime())
+}
+
+// runtime is the runtime picked with --diego, or every runtime without it.
+func (command AppsByRuntimeCommand) runtime() ui.Runtime {
+	switch command.Diego {
+	case "true":
+		return ui.Diego
+	case "false":
+		return ui.DEA
+	}
+	return ui.AllRuntimes
+}
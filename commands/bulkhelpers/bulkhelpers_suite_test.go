@@ -0,0 +1,13 @@
+package bulkhelpers_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBulkhelpers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bulkhelpers Suite")
+}
@@ -0,0 +1,591 @@
+package bulkhelpers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	Changed = iota
+	Skipped
+	Failed
+	NotStarted
+	TimedOut
+)
+
+// DefaultMaxInFlight is how many apps are switched at once unless
+// MaxInFlight says otherwise.
+const DefaultMaxInFlight = 5
+
+// ConfirmationThreshold is how many apps a bulk switch may change before
+// it asks the user to confirm.
+const ConfirmationThreshold = 10
+
+type ConfirmationRequiredError struct {
+	Count int
+}
+
+func (e ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("This will change %d apps. Pass --force to continue without confirmation.", e.Count)
+}
+
+type ToggleApps struct {
+	MaxInFlight       int
+	Runtime           ui.Runtime
+	AppsGetterFunc    thingdoer.AppsGetterFunc
+	ToggleAppsCommand *ui.ToggleAppsCommand
+	DryRun            bool
+	Force             bool
+
+	// TimeoutPerApp, when set, gives up on an app whose flag has not been
+	// set in that time and moves on to the next one.
+	TimeoutPerApp time.Duration
+
+	// FailuresFile, when set, is written with the guid of every app that
+	// was not switched, so that only those can be retried with
+	// enable-diego --guids-file or disable-diego --guids-file.
+	FailuresFile string
+
+	// Live, when set, shows the apps in a table whose status column is
+	// redrawn as each app is switched, instead of a line for each app.
+	// It needs stdout to be a terminal; see LiveOutput.
+	Live bool
+
+	// requests holds a slot for each request to set the Diego flag that
+	// has not returned, including the ones given up on after
+	// TimeoutPerApp, so that no more than MaxInFlight are ever running.
+	requests chan struct{}
+}
+
+// LiveOutput reports whether a live table was asked for and can be drawn,
+// falling back to a line for each app when stdout is not a terminal or
+// output is quiet.
+func LiveOutput(requested bool) bool {
+	return requested && !ui.Quiet && isatty.IsTerminal(os.Stdout.Fd())
+}
+
+func (cmd *ToggleApps) Execute(ctx context.Context, cliConnection api.Connection) error {
+	cmd.ToggleAppsCommand.BeforeAll()
+
+	apiClient, err := api.NewClient(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	appRequestFactory := apiClient.HandleFiltersAndParameters(
+		apiClient.Authorize(apiClient.NewGetAppsRequest),
+	)
+
+	appPaginatedRequester, err := api.NewPaginatedRequester(cliConnection, appRequestFactory)
+	if err != nil {
+		return err
+	}
+
+	apps, err := cmd.AppsGetterFunc(
+		ctx,
+		models.ApplicationsParser{},
+		appPaginatedRequester,
+	)
+	if err != nil {
+		return err
+	}
+
+	confirmed, err := cmd.ConfirmChanges(apps)
+	if err != nil || !confirmed {
+		return err
+	}
+
+	diegoSupport := diegosupport.NewDiegoSupport(cliConnection)
+
+	spaces, err := cmd.orgSpaces(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	appPrinters := make([]*displayhelpers.AppPrinter, 0, len(apps))
+	for _, app := range apps {
+		appPrinters = append(appPrinters, &displayhelpers.AppPrinter{App: app, Spaces: spaces})
+	}
+
+	var changed, skipped, failed, timedOut, notStarted int
+	var toggled, notSwitched []*displayhelpers.AppPrinter
+	for i, result := range cmd.ToggleAll(ctx, appPrinters, diegoSupport) {
+		switch result {
+		case Changed:
+			changed++
+			toggled = append(toggled, appPrinters[i])
+		case Skipped:
+			skipped++
+		case Failed:
+			failed++
+			notSwitched = append(notSwitched, appPrinters[i])
+		case TimedOut:
+			timedOut++
+			notSwitched = append(notSwitched, appPrinters[i])
+		case NotStarted:
+			notStarted++
+			notSwitched = append(notSwitched, appPrinters[i])
+		}
+	}
+
+	if ctx.Err() != nil {
+		cmd.ToggleAppsCommand.AfterInterrupt(changed, skipped, failed, timedOut, notStarted)
+		if err := cmd.writeFailures(notSwitched); err != nil {
+			return err
+		}
+		return api.ErrInterrupted
+	}
+
+	if cmd.DryRun {
+		cmd.ToggleAppsCommand.AfterDryRun(changed, skipped)
+		return nil
+	}
+
+	if len(toggled) > 0 {
+		unverified, err := cmd.VerifyApps(ctx, toggled, appPaginatedRequester)
+		if err != nil {
+			return err
+		}
+		changed -= len(unverified)
+		failed += len(unverified)
+		notSwitched = append(notSwitched, unverified...)
+	}
+
+	cmd.ToggleAppsCommand.AfterAll(changed, skipped, failed, timedOut)
+	if err := cmd.writeFailures(notSwitched); err != nil {
+		return err
+	}
+
+	if failed+timedOut > 0 {
+		return errorhelpers.BatchFailure{
+			Message:   fmt.Sprintf("Failed to switch %d of %d apps to %s", failed+timedOut, len(apps), cmd.Runtime),
+			Failed:    failed + timedOut,
+			Attempted: changed + failed + timedOut,
+		}
+	}
+
+	return nil
+}
+
+// orgSpaces returns the spaces of the org when switching every app in it,
+// so that a failure can name the space of the app. It returns nil when a
+// single space is switched, whose name is already known.
+func (cmd *ToggleApps) orgSpaces(cliConnection api.Connection) (map[string]models.Space, error) {
+	if cmd.ToggleAppsCommand.Space != "" || cmd.ToggleAppsCommand.Organization == "" {
+		return nil, nil
+	}
+
+	org, err := cliConnection.GetOrg(cmd.ToggleAppsCommand.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	spaces := make(map[string]models.Space, len(org.Spaces))
+	for _, space := range org.Spaces {
+		spaces[space.Guid] = models.Space{
+			SpaceEntity: models.SpaceEntity{
+				Name:             space.Name,
+				OrganizationGuid: org.Guid,
+				Organization: models.Organization{
+					OrganizationEntity:   models.OrganizationEntity{Name: org.Name},
+					OrganizationMetadata: models.OrganizationMetadata{Guid: org.Guid},
+				},
+			},
+			SpaceMetadata: models.SpaceMetadata{Guid: space.Guid},
+		}
+	}
+	return spaces, nil
+}
+
+// ConfirmChanges asks before changing more than ConfirmationThreshold apps,
+// unless Force or DryRun is set. Without a terminal to ask on it refuses.
+func (cmd *ToggleApps) ConfirmChanges(apps models.Applications) (bool, error) {
+	if cmd.Force || cmd.DryRun {
+		return true, nil
+	}
+
+	on := cmd.Runtime == ui.Diego
+	count := 0
+	for _, app := range apps {
+		if app.Diego != on {
+			count++
+		}
+	}
+
+	if count <= ConfirmationThreshold {
+		return true, nil
+	}
+
+	if !cmd.ToggleAppsCommand.Interactive {
+		return false, ConfirmationRequiredError{Count: count}
+	}
+
+	return cmd.ToggleAppsCommand.ConfirmChanges(count), nil
+}
+
+//go:generate counterfeiter . DiegoFlagSetter
+type DiegoFlagSetter interface {
+	SetDiegoFlag(string, bool) ([]string, error)
+}
+
+type toggleResult struct {
+	index  int
+	result int
+	err    error
+}
+
+// ToggleAll switches up to MaxInFlight apps at a time and returns the
+// result for each app. Results are reported in the order of appPrinters,
+// whatever order the switches finish in. Once ctx is cancelled no more
+// switches are started; the ones in flight are waited for and the rest
+// are NotStarted.
+func (cmd *ToggleApps) ToggleAll(
+	ctx context.Context,
+	appPrinters []*displayhelpers.AppPrinter,
+	diegoSupport DiegoFlagSetter,
+) []int {
+	maxInFlight := cmd.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	if len(appPrinters) < maxInFlight {
+		maxInFlight = len(appPrinters)
+	}
+	cmd.requests = make(chan struct{}, maxInFlight)
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range appPrinters {
+			if ctx.Err() != nil {
+				cmd.ToggleAppsCommand.Interrupted()
+				return
+			}
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				cmd.ToggleAppsCommand.Interrupted()
+				return
+			}
+		}
+	}()
+
+	done := make(chan toggleResult, len(appPrinters))
+	var workers sync.WaitGroup
+	for i := 0; i < maxInFlight; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				if ctx.Err() != nil {
+					done <- toggleResult{index: index, result: NotStarted}
+					continue
+				}
+				result, err := cmd.toggle(appPrinters[index], diegoSupport)
+				done <- toggleResult{index: index, result: result, err: err}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	if cmd.Live {
+		return cmd.collectLive(appPrinters, done)
+	}
+
+	results := make([]int, len(appPrinters))
+	pending := make(map[int]toggleResult)
+	next := 0
+	for r := range done {
+		pending[r.index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			results[next] = cmd.report(appPrinters[next], r.result, r.err)
+			next++
+		}
+	}
+	for ; next < len(appPrinters); next++ {
+		results[next] = NotStarted
+	}
+
+	return results
+}
+
+// collectLive updates the live table as each app finishes, in whatever
+// order that is, and reports the apps that failed or timed out below the
+// table once every app has finished.
+func (cmd *ToggleApps) collectLive(
+	appPrinters []*displayhelpers.AppPrinter,
+	done <-chan toggleResult,
+) []int {
+	apps := make([]ui.ApplicationPrinter, 0, len(appPrinters))
+	for _, appPrinter := range appPrinters {
+		apps = append(apps, appPrinter)
+	}
+	cmd.ToggleAppsCommand.BeforeLive(apps)
+
+	results := make([]int, len(appPrinters))
+	errs := make([]error, len(appPrinters))
+	for i := range results {
+		results[i] = NotStarted
+	}
+	for r := range done {
+		results[r.index] = r.result
+		errs[r.index] = r.err
+		cmd.ToggleAppsCommand.LiveEach(r.index, cmd.liveStatus(r.result))
+	}
+	for i, result := range results {
+		if result == NotStarted {
+			cmd.ToggleAppsCommand.LiveEach(i, ui.LiveNotStarted)
+		}
+	}
+	cmd.ToggleAppsCommand.AfterLive()
+
+	for i, result := range results {
+		if result == Failed || result == TimedOut {
+			cmd.report(appPrinters[i], result, errs[i])
+		}
+	}
+
+	return results
+}
+
+func (cmd *ToggleApps) liveStatus(result int) string {
+	switch result {
+	case Skipped:
+		return ui.LiveSkipped
+	case Failed:
+		return ui.LiveFailed
+	case TimedOut:
+		return ui.LiveTimedOut
+	case NotStarted:
+		return ui.LiveNotStarted
+	}
+	if cmd.DryRun {
+		return ui.LiveWouldSwitch
+	}
+	return ui.LiveDone
+}
+
+func (cmd *ToggleApps) ToggleApp(
+	appPrinter *displayhelpers.AppPrinter,
+	diegoSupport DiegoFlagSetter,
+) int {
+	result, err := cmd.toggle(appPrinter, diegoSupport)
+	return cmd.report(appPrinter, result, err)
+}
+
+func (cmd *ToggleApps) toggle(
+	appPrinter *displayhelpers.AppPrinter,
+	diegoSupport DiegoFlagSetter,
+) (int, error) {
+	on := cmd.Runtime == ui.Diego
+
+	if appPrinter.App.Diego == on {
+		return Skipped, nil
+	}
+
+	if cmd.DryRun {
+		return Changed, nil
+	}
+
+	err := cmd.setDiegoFlag(appPrinter.App.Guid, on, diegoSupport)
+	if err == context.DeadlineExceeded {
+		return TimedOut, err
+	}
+	if ccErr, ok := err.(diegosupport.CCErrorResponse); ok && ccErr.IsNotAuthorized() {
+		return Failed, errorhelpers.SpaceDeveloperRequiredError{SpaceName: cmd.spaceName(appPrinter)}
+	}
+	if err != nil {
+		return Failed, err
+	}
+
+	return Changed, nil
+}
+
+// spaceName names the space of the app, or returns "" when it is not
+// known.
+func (cmd *ToggleApps) spaceName(appPrinter *displayhelpers.AppPrinter) string {
+	if cmd.ToggleAppsCommand.Space != "" {
+		return cmd.ToggleAppsCommand.Space
+	}
+	space, ok := appPrinter.Spaces[appPrinter.App.SpaceGuid]
+	if !ok {
+		return ""
+	}
+	return space.Name
+}
+
+// setDiegoFlag gives up once TimeoutPerApp has passed. The deadline is not
+// tied to the context of the whole switch, so that apps in flight are
+// still waited for after an interrupt. The request itself cannot be
+// cancelled and may still complete after giving up on it; until then it
+// keeps its slot in requests, and the time spent waiting for a free slot
+// counts towards the deadline of the next app.
+func (cmd *ToggleApps) setDiegoFlag(appGuid string, on bool, diegoSupport DiegoFlagSetter) error {
+	if cmd.TimeoutPerApp <= 0 {
+		_, err := diegoSupport.SetDiegoFlag(appGuid, on)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.TimeoutPerApp)
+	defer cancel()
+
+	requests := cmd.requests
+	if requests != nil {
+		select {
+		case requests <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := diegoSupport.SetDiegoFlag(appGuid, on)
+		if requests != nil {
+			<-requests
+		}
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (cmd *ToggleApps) report(appPrinter *displayhelpers.AppPrinter, result int, err error) int {
+	switch {
+	case result == NotStarted:
+	case result == Skipped:
+		cmd.ToggleAppsCommand.SkippedEach(appPrinter)
+	case result == Failed:
+		cmd.ToggleAppsCommand.FailedEach(appPrinter, err)
+	case result == TimedOut:
+		cmd.ToggleAppsCommand.TimedOutEach(appPrinter, cmd.TimeoutPerApp)
+	case cmd.DryRun:
+		cmd.ToggleAppsCommand.DryRunEach(appPrinter)
+	default:
+		cmd.ToggleAppsCommand.CompletedEach(appPrinter)
+	}
+	return result
+}
+
+// VerifyApps re-lists the apps in a single paginated request rather than
+// fetching each toggled app again, and returns the ones that did not end
+// up on the target runtime.
+func (cmd *ToggleApps) VerifyApps(
+	ctx context.Context,
+	toggled []*displayhelpers.AppPrinter,
+	paginatedRequester thingdoer.PaginatedRequester,
+) ([]*displayhelpers.AppPrinter, error) {
+	cmd.ToggleAppsCommand.BeforeVerify()
+
+	apps, err := cmd.AppsGetterFunc(
+		ctx,
+		models.ApplicationsParser{},
+		paginatedRequester,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	diegoFlags := make(map[string]bool)
+	for _, app := range apps {
+		diegoFlags[app.Guid] = app.Diego
+	}
+
+	on := cmd.Runtime == ui.Diego
+	var unverified []*displayhelpers.AppPrinter
+	for _, appPrinter := range toggled {
+		diego, ok := diegoFlags[appPrinter.App.Guid]
+		if !ok || diego != on {
+			cmd.ToggleAppsCommand.UnverifiedEach(appPrinter)
+			unverified = append(unverified, appPrinter)
+		}
+	}
+
+	return unverified, nil
+}
+
+// writeFailures writes FailuresFile, when it is set, even if no app
+// failed, so that a file left over from an earlier run is not retried.
+func (cmd *ToggleApps) writeFailures(notSwitched []*displayhelpers.AppPrinter) error {
+	if cmd.FailuresFile == "" {
+		return nil
+	}
+
+	guids := make([]string, 0, len(notSwitched))
+	for _, appPrinter := range notSwitched {
+		guids = append(guids, appPrinter.App.Guid)
+	}
+
+	if err := WriteFailuresFile(cmd.FailuresFile, guids); err != nil {
+		return err
+	}
+	cmd.ToggleAppsCommand.AfterWriteFailures(cmd.FailuresFile, len(guids))
+	return nil
+}
+
+// WriteFailuresFile writes one app guid per line to path, replacing the
+// file if it exists.
+func WriteFailuresFile(path string, guids []string) error {
+	var contents strings.Builder
+	for _, guid := range guids {
+		contents.WriteString(guid + "\n")
+	}
+	return ioutil.WriteFile(path, []byte(contents.String()), 0644)
+}
+
+func NewToggleAppsCommand(cliConnection api.Connection, organizationName string, spaceName string, runtime ui.Runtime) (ui.ToggleAppsCommand, error) {
+	username, err := cliConnection.Username()
+	if err != nil {
+		return ui.ToggleAppsCommand{}, err
+	}
+
+	if spaceName != "" && organizationName == "" {
+		space, err := cliConnection.GetSpace(spaceName)
+		if err != nil || space.Guid == "" {
+			return ui.ToggleAppsCommand{}, err
+		}
+		organizationName = space.Organization.Name
+	}
+
+	traceEnv := os.Getenv("CF_TRACE")
+	traceLogger := trace.NewLogger(false, traceEnv, "")
+	tUI := terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), traceLogger)
+
+	return ui.ToggleAppsCommand{
+		Username:     username,
+		Runtime:      runtime,
+		Organization: organizationName,
+		Space:        spaceName,
+		UI:           tUI,
+		Interactive:  isatty.IsTerminal(os.Stdin.Fd()),
+	}, nil
+}
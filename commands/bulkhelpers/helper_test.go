@@ -0,0 +1,515 @@
+package bulkhelpers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/bulkhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/bulkhelpers/bulkhelpersfakes"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer/thingdoerfakes"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ToggleApps", func() {
+	var (
+		command ToggleApps
+		buf     *gbytes.Buffer
+		stdout  *os.File
+	)
+
+	BeforeEach(func() {
+		buf = gbytes.NewBuffer()
+		stdout = captureStdout(buf)
+
+		command = ToggleApps{
+			Runtime: ui.Diego,
+			ToggleAppsCommand: &ui.ToggleAppsCommand{
+				Username:     "some-username",
+				Runtime:      ui.Diego,
+				Organization: "some-organization",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	})
+
+	Describe("ToggleApp", func() {
+		var (
+			result       int
+			diegoSupport *bulkhelpersfakes.FakeDiegoFlagSetter
+			appPrinter   *displayhelpers.AppPrinter
+		)
+
+		BeforeEach(func() {
+			diegoSupport = new(bulkhelpersfakes.FakeDiegoFlagSetter)
+			appPrinter = &displayhelpers.AppPrinter{
+				App: models.Application{
+					ApplicationEntity: models.ApplicationEntity{
+						Name:  "some-app",
+						Diego: false,
+					},
+					ApplicationMetadata: models.ApplicationMetadata{
+						Guid: "some-app-guid",
+					},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			result = command.ToggleApp(appPrinter, diegoSupport)
+		})
+
+		Context("when the app is already on the target runtime", func() {
+			BeforeEach(func() {
+				appPrinter.App.Diego = true
+			})
+
+			It("skips the app without setting the flag", func() {
+				Expect(result).To(Equal(Skipped))
+				Expect(diegoSupport.SetDiegoFlagCallCount()).To(Equal(0))
+				Eventually(buf).Should(gbytes.Say("Skipping app some-app"))
+			})
+		})
+
+		Context("when setting the flag succeeds", func() {
+			It("sets the diego flag for the app", func() {
+				Expect(result).To(Equal(Changed))
+				Expect(diegoSupport.SetDiegoFlagCallCount()).To(Equal(1))
+
+				guid, on := diegoSupport.SetDiegoFlagArgsForCall(0)
+				Expect(guid).To(Equal("some-app-guid"))
+				Expect(on).To(BeTrue())
+				Eventually(buf).Should(gbytes.Say("Switched app some-app"))
+			})
+		})
+
+		Context("when it is a dry run", func() {
+			BeforeEach(func() {
+				command.DryRun = true
+			})
+
+			It("reports the change without setting the flag", func() {
+				Expect(result).To(Equal(Changed))
+				Expect(diegoSupport.SetDiegoFlagCallCount()).To(Equal(0))
+				Eventually(buf).Should(gbytes.Say("Would switch app some-app"))
+			})
+		})
+
+		Context("when setting the flag fails", func() {
+			BeforeEach(func() {
+				diegoSupport.SetDiegoFlagReturns(nil, errors.New("disaster"))
+			})
+
+			It("reports the failure", func() {
+				Expect(result).To(Equal(Failed))
+				Eventually(buf).Should(gbytes.Say("Error: Failed to switch app some-app"))
+			})
+		})
+
+		Context("when the user lacks the SpaceDeveloper role", func() {
+			BeforeEach(func() {
+				command.ToggleAppsCommand.Space = "some-space"
+				diegoSupport.SetDiegoFlagReturns(nil, diegosupport.CCErrorResponse{StatusCode: 403})
+			})
+
+			It("says which role is needed in which space", func() {
+				Expect(result).To(Equal(Failed))
+				Eventually(buf).Should(gbytes.Say("Error: Failed to switch app some-app to Diego: You need the SpaceDeveloper role in space some-space to change Diego for this app"))
+			})
+
+			Context("when switching the apps of a whole org", func() {
+				BeforeEach(func() {
+					command.ToggleAppsCommand.Space = ""
+					appPrinter.App.SpaceGuid = "some-space-guid"
+					appPrinter.Spaces = map[string]models.Space{
+						"some-space-guid": {SpaceEntity: models.SpaceEntity{Name: "org-space"}},
+					}
+				})
+
+				It("names the space of the app", func() {
+					Expect(result).To(Equal(Failed))
+					Eventually(buf).Should(gbytes.Say("Error: Failed to switch app some-app to Diego: You need the SpaceDeveloper role in space org-space to change Diego for this app"))
+				})
+			})
+		})
+	})
+
+	Describe("ToggleAll", func() {
+		var (
+			ctx          context.Context
+			results      []int
+			diegoSupport *bulkhelpersfakes.FakeDiegoFlagSetter
+			appPrinters  []*displayhelpers.AppPrinter
+
+			inFlight    int32
+			maxInFlight int32
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			command.MaxInFlight = 2
+			inFlight = 0
+			maxInFlight = 0
+
+			appPrinters = []*displayhelpers.AppPrinter{
+				{App: newApp("app-0", "app-0-guid", false)},
+				{App: newApp("app-1", "app-1-guid", true)},
+				{App: newApp("app-2", "app-2-guid", false)},
+				{App: newApp("app-3", "app-3-guid", false)},
+			}
+
+			diegoSupport = new(bulkhelpersfakes.FakeDiegoFlagSetter)
+			diegoSupport.SetDiegoFlagStub = func(guid string, _ bool) ([]string, error) {
+				n := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+						break
+					}
+				}
+
+				// finish the earlier apps last
+				if guid == "app-0-guid" {
+					time.Sleep(50 * time.Millisecond)
+				}
+				if guid == "app-3-guid" {
+					return nil, errors.New("disaster")
+				}
+				return nil, nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			results = command.ToggleAll(ctx, appPrinters, diegoSupport)
+		})
+
+		It("returns the result of each app in order", func() {
+			Expect(results).To(Equal([]int{Changed, Skipped, Changed, Failed}))
+		})
+
+		It("switches no more than MaxInFlight apps at once", func() {
+			Expect(diegoSupport.SetDiegoFlagCallCount()).To(Equal(3))
+			Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically("<=", 2))
+		})
+
+		It("reports the apps in order", func() {
+			Eventually(buf).Should(gbytes.Say("Switched app app-0"))
+			Eventually(buf).Should(gbytes.Say("Skipping app app-1"))
+			Eventually(buf).Should(gbytes.Say("Switched app app-2"))
+			Eventually(buf).Should(gbytes.Say("Error: Failed to switch app app-3"))
+		})
+
+		Context("when Live is set", func() {
+			BeforeEach(func() {
+				command.Live = true
+				command.ToggleAppsCommand.UI = terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), trace.NewLogger(false, "", ""))
+			})
+
+			It("returns the result of each app in order", func() {
+				Expect(results).To(Equal([]int{Changed, Skipped, Changed, Failed}))
+			})
+
+			It("draws every app as pending, then redraws the table with each status", func() {
+				Eventually(buf).Should(gbytes.Say(`app-0\s+pending`))
+				Eventually(buf).Should(gbytes.Say(`app-3\s+pending`))
+				Eventually(buf).Should(gbytes.Say("\033\\[5A\033\\[J"))
+				Eventually(buf).Should(gbytes.Say(`app-0\s+done`))
+				Eventually(buf).Should(gbytes.Say(`app-1\s+skipped`))
+				Eventually(buf).Should(gbytes.Say(`app-2\s+done`))
+				Eventually(buf).Should(gbytes.Say(`app-3\s+failed`))
+			})
+
+			It("reports the failures below the table", func() {
+				Eventually(buf).Should(gbytes.Say(`app-0\s+done`))
+				Eventually(buf).Should(gbytes.Say("Error: Failed to switch app app-3 to Diego: disaster"))
+				Expect(buf).NotTo(gbytes.Say("Switched app"))
+			})
+		})
+
+		Context("when an app takes longer than TimeoutPerApp", func() {
+			var release chan struct{}
+
+			BeforeEach(func() {
+				release = make(chan struct{})
+				// the request given up on outlives the test, so it must not
+				// read release once the next test has replaced it
+				released := release
+				command.TimeoutPerApp = 20 * time.Millisecond
+				diegoSupport.SetDiegoFlagStub = func(guid string, _ bool) ([]string, error) {
+					if guid == "app-2-guid" {
+						<-released
+					}
+					return nil, nil
+				}
+			})
+
+			AfterEach(func() {
+				close(release)
+			})
+
+			It("counts the app as timed out and moves on", func() {
+				Expect(results).To(Equal([]int{Changed, Skipped, TimedOut, Changed}))
+				Eventually(buf).Should(gbytes.Say("Error: Timed out switching app app-2 to Diego after 20ms"))
+			})
+
+			Context("when the app given up on is still holding the only slot", func() {
+				BeforeEach(func() {
+					command.MaxInFlight = 1
+				})
+
+				It("does not start another request until it returns", func() {
+					Expect(results).To(Equal([]int{Changed, Skipped, TimedOut, TimedOut}))
+					Expect(diegoSupport.SetDiegoFlagCallCount()).To(Equal(2))
+				})
+			})
+		})
+
+		Context("when ctx is cancelled while apps are being switched", func() {
+			BeforeEach(func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(context.Background())
+
+				command.MaxInFlight = 1
+				diegoSupport.SetDiegoFlagStub = func(guid string, _ bool) ([]string, error) {
+					cancel()
+					return nil, nil
+				}
+			})
+
+			It("finishes the app in flight and starts no more", func() {
+				Expect(diegoSupport.SetDiegoFlagCallCount()).To(Equal(1))
+				Expect(results).To(Equal([]int{Changed, NotStarted, NotStarted, NotStarted}))
+			})
+
+			It("says it is waiting for the apps in flight", func() {
+				Eventually(buf).Should(gbytes.Say("Interrupted: waiting for the apps being switched to finish"))
+			})
+		})
+	})
+
+	Describe("ConfirmChanges", func() {
+		var (
+			apps      models.Applications
+			fakeUI    *fakeAskingUI
+			confirmed bool
+			err       error
+		)
+
+		BeforeEach(func() {
+			apps = models.Applications{newApp("diego-app", "diego-app-guid", true)}
+			for i := 0; i < ConfirmationThreshold+1; i++ {
+				apps = append(apps, newApp("dea-app", "dea-app-guid", false))
+			}
+
+			fakeUI = new(fakeAskingUI)
+			command.ToggleAppsCommand.UI = fakeUI
+			command.ToggleAppsCommand.Interactive = true
+		})
+
+		JustBeforeEach(func() {
+			confirmed, err = command.ConfirmChanges(apps)
+		})
+
+		Context("when no more apps than the threshold would change", func() {
+			BeforeEach(func() {
+				apps = apps[:ConfirmationThreshold+1]
+			})
+
+			It("does not prompt", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(confirmed).To(BeTrue())
+				Expect(fakeUI.prompts).To(BeEmpty())
+			})
+		})
+
+		Context("when the user agrees", func() {
+			BeforeEach(func() {
+				fakeUI.answer = "y"
+			})
+
+			It("prompts with the number of apps that would change", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(confirmed).To(BeTrue())
+				Expect(fakeUI.prompts).To(Equal([]string{"This will change 11 apps. Continue? [y/N]"}))
+			})
+		})
+
+		Context("when the user declines", func() {
+			BeforeEach(func() {
+				fakeUI.answer = ""
+			})
+
+			It("cancels the switch", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(confirmed).To(BeFalse())
+				Eventually(buf).Should(gbytes.Say("Switching apps cancelled"))
+			})
+		})
+
+		Context("when --force is given", func() {
+			BeforeEach(func() {
+				command.Force = true
+			})
+
+			It("does not prompt", func() {
+				Expect(confirmed).To(BeTrue())
+				Expect(fakeUI.prompts).To(BeEmpty())
+			})
+		})
+
+		Context("when stdin is not a terminal", func() {
+			BeforeEach(func() {
+				command.ToggleAppsCommand.Interactive = false
+			})
+
+			It("refuses without prompting", func() {
+				Expect(err).To(Equal(ConfirmationRequiredError{Count: 11}))
+				Expect(confirmed).To(BeFalse())
+				Expect(fakeUI.prompts).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("VerifyApps", func() {
+		var (
+			unverified []*displayhelpers.AppPrinter
+			err        error
+			getCalls   int
+			toggled    []*displayhelpers.AppPrinter
+		)
+
+		BeforeEach(func() {
+			getCalls = 0
+			command.AppsGetterFunc = func(context.Context, thingdoer.ApplicationsParser, thingdoer.PaginatedRequester) (models.Applications, error) {
+				getCalls++
+				return models.Applications{
+					newApp("updated-app", "updated-app-guid", true),
+					newApp("stale-app", "stale-app-guid", false),
+				}, nil
+			}
+
+			toggled = []*displayhelpers.AppPrinter{
+				{App: newApp("updated-app", "updated-app-guid", false)},
+				{App: newApp("stale-app", "stale-app-guid", false)},
+				{App: newApp("deleted-app", "deleted-app-guid", false)},
+			}
+		})
+
+		JustBeforeEach(func() {
+			unverified, err = command.VerifyApps(context.Background(), toggled, new(thingdoerfakes.FakePaginatedRequester))
+		})
+
+		It("lists the apps once for all toggled apps", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getCalls).To(Equal(1))
+		})
+
+		It("reports apps that are not on the target runtime", func() {
+			Expect(unverified).To(Equal([]*displayhelpers.AppPrinter{toggled[1], toggled[2]}))
+			Eventually(buf).Should(gbytes.Say("App stale-app is NOT set to Diego"))
+			Eventually(buf).Should(gbytes.Say("App deleted-app is NOT set to Diego"))
+		})
+
+		Context("when listing the apps fails", func() {
+			BeforeEach(func() {
+				command.AppsGetterFunc = func(context.Context, thingdoer.ApplicationsParser, thingdoer.PaginatedRequester) (models.Applications, error) {
+					return nil, errors.New("listing failed")
+				}
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(MatchError("listing failed"))
+			})
+		})
+	})
+})
+
+var _ = Describe("WriteFailuresFile", func() {
+	var path string
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "failures")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "out.txt")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(filepath.Dir(path))
+	})
+
+	It("writes one guid per line", func() {
+		Expect(WriteFailuresFile(path, []string{"guid-1", "guid-2"})).To(Succeed())
+
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("guid-1\nguid-2\n"))
+	})
+
+	It("replaces the guids from an earlier run", func() {
+		Expect(WriteFailuresFile(path, []string{"guid-1", "guid-2"})).To(Succeed())
+		Expect(WriteFailuresFile(path, nil)).To(Succeed())
+
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(BeEmpty())
+	})
+})
+
+func newApp(name, guid string, diego bool) models.Application {
+	return models.Application{
+		ApplicationEntity: models.ApplicationEntity{
+			Name:  name,
+			Diego: diego,
+		},
+		ApplicationMetadata: models.ApplicationMetadata{
+			Guid: guid,
+		},
+	}
+}
+
+// fakeAskingUI answers prompts; the vendored terminalfakes package does
+// not build.
+type fakeAskingUI struct {
+	terminal.UI
+	answer  string
+	prompts []string
+}
+
+func (ui *fakeAskingUI) Ask(prompt string) string {
+	ui.prompts = append(ui.prompts, prompt)
+	return ui.answer
+}
+
+func captureStdout(buf *gbytes.Buffer) *os.File {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	Expect(err).NotTo(HaveOccurred())
+	os.Stdout = w
+	go func() {
+		_, err = io.Copy(buf, r)
+		buf.Close()
+		r.Close()
+	}()
+	Expect(err).NotTo(HaveOccurred())
+	return stdout
+}
@@ -1,8 +1,11 @@
 package commands_test
 
 import (
+	"errors"
+
 	. "github.com/cloudfoundry-incubator/diego-enabler/commands"
-	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry/cli/plugin/models"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -22,13 +25,48 @@ var _ = Describe("DeaApps", func() {
 	Context("when both organization and space are passed", func() {
 		BeforeEach(func() {
 			command = DeaAppsCommand{
-				Space:        "some-space",
-				Organization: "some-organization",
+				ListAppsOptions: ListAppsOptions{
+					Space:        "some-space",
+					Organization: "some-organization",
+				},
+			}
+			fakeConnection.GetOrgReturns(plugin_models.GetOrg_Model{
+				Guid: "some-organization-guid",
+				Spaces: []plugin_models.GetOrg_Space{
+					{Guid: "other-space-guid", Name: "other-space"},
+				},
+			}, nil)
+		})
+
+		It("looks the space up in that organization", func() {
+			Expect(fakeConnection.GetOrgArgsForCall(0)).To(Equal("some-organization"))
+			Expect(fakeConnection.GetSpaceCallCount()).To(Equal(0))
+		})
+
+		Context("when the space is not in the organization", func() {
+			It("returns a space not found error", func() {
+				Expect(err).To(Equal(diegohelpers.SpaceNotFoundErr{
+					SpaceName:        "some-space",
+					OrganizationName: "some-organization",
+				}))
+				Expect(err.Error()).To(Equal("Space some-space not found in organization some-organization"))
+			})
+		})
+	})
+
+	Context("when the organization cannot be found", func() {
+		BeforeEach(func() {
+			command = DeaAppsCommand{
+				ListAppsOptions: ListAppsOptions{
+					Organization: "some-organization",
+				},
 			}
+			fakeConnection.GetOrgReturns(plugin_models.GetOrg_Model{}, errors.New("org not found"))
 		})
 
-		It("returns an error", func() {
-			Expect(err).To(Equal(errorhelpers.SpecifyOrgOrSpaceError))
+		It("returns an organization not found error", func() {
+			Expect(err).To(Equal(diegohelpers.OrgNotFoundErr{OrganizationName: "some-organization"}))
+			Expect(err.Error()).To(Equal("Organization some-organization not found"))
 		})
 	})
 })
@@ -1,8 +1,13 @@
 package commands_test
 
 import (
+	"errors"
+	"os"
+
 	. "github.com/cloudfoundry-incubator/diego-enabler/commands"
-	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+	"github.com/cloudfoundry/cli/plugin/models"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -22,13 +27,122 @@ var _ = Describe("DiegoApps", func() {
 	Context("when both organization and space are passed", func() {
 		BeforeEach(func() {
 			command = DiegoAppsCommand{
-				Space:        "some-space",
-				Organization: "some-organization",
+				ListAppsOptions: ListAppsOptions{
+					Space:        "some-space",
+					Organization: "some-organization",
+				},
+			}
+			fakeConnection.GetOrgReturns(plugin_models.GetOrg_Model{
+				Guid: "some-organization-guid",
+				Spaces: []plugin_models.GetOrg_Space{
+					{Guid: "other-space-guid", Name: "other-space"},
+				},
+			}, nil)
+		})
+
+		It("looks the space up in that organization", func() {
+			Expect(fakeConnection.GetOrgArgsForCall(0)).To(Equal("some-organization"))
+			Expect(fakeConnection.GetSpaceCallCount()).To(Equal(0))
+		})
+
+		Context("when the space is not in the organization", func() {
+			It("returns a space not found error", func() {
+				Expect(err).To(Equal(diegohelpers.SpaceNotFoundErr{
+					SpaceName:        "some-space",
+					OrganizationName: "some-organization",
+				}))
+				Expect(err.Error()).To(Equal("Space some-space not found in organization some-organization"))
+			})
+		})
+	})
+
+	Context("when the organization cannot be found", func() {
+		BeforeEach(func() {
+			command = DiegoAppsCommand{
+				ListAppsOptions: ListAppsOptions{
+					Organization: "some-organization",
+				},
+			}
+			fakeConnection.GetOrgReturns(plugin_models.GetOrg_Model{}, errors.New("org not found"))
+		})
+
+		It("returns an organization not found error", func() {
+			Expect(err).To(Equal(diegohelpers.OrgNotFoundErr{OrganizationName: "some-organization"}))
+			Expect(err.Error()).To(Equal("Organization some-organization not found"))
+		})
+	})
+
+	Context("when --offset is not a multiple of --limit", func() {
+		BeforeEach(func() {
+			command = DiegoAppsCommand{
+				ListAppsOptions: ListAppsOptions{
+					Limit:  flaghelpers.PageSizeFlag{Value: 50},
+					Offset: 75,
+				},
+			}
+		})
+
+		It("returns an invalid offset error", func() {
+			Expect(err).To(Equal(InvalidOffsetError{Offset: 75, Limit: 50}))
+		})
+	})
+
+	Context("when --guids is given with --count", func() {
+		BeforeEach(func() {
+			command = DiegoAppsCommand{
+				ListAppsOptions: ListAppsOptions{
+					Guids: true,
+					Count: true,
+				},
 			}
 		})
 
 		It("returns an error", func() {
-			Expect(err).To(Equal(errorhelpers.SpecifyOrgOrSpaceError))
+			Expect(err).To(Equal(GuidsWithCountError{}))
+		})
+	})
+
+	Context("when --strict is given with --hide-inaccessible", func() {
+		BeforeEach(func() {
+			command = DiegoAppsCommand{
+				ListAppsOptions: ListAppsOptions{
+					Strict:           true,
+					HideInaccessible: true,
+				},
+			}
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(Equal(StrictWithHideInaccessibleError{}))
+		})
+	})
+
+	Context("when --offset is given without --limit", func() {
+		BeforeEach(func() {
+			command = DiegoAppsCommand{
+				ListAppsOptions: ListAppsOptions{
+					Offset: 100,
+				},
+			}
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(Equal(OffsetWithoutLimitError{}))
+		})
+	})
+
+	Context("when CF_DIEGO_OUTPUT is not a known format and --output is not given", func() {
+		BeforeEach(func() {
+			os.Setenv("CF_DIEGO_OUTPUT", "yaml")
+			command = DiegoAppsCommand{}
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("CF_DIEGO_OUTPUT")
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(Equal(InvalidOutputEnvError{PassedValue: "yaml"}))
 		})
 	})
 })
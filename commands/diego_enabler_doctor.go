@@ -0,0 +1,17 @@
+package commands
+
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/doctorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type DoctorCommand struct{}
+
+func (command DoctorCommand) Execute([]string) error {
+	cliConnection := DiegoEnabler.CLIConnection
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	return doctorhelpers.Diagnose(ctx, cliConnection, &ui.DoctorCommand{})
+}
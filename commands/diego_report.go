@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/listhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type DiegoReportCommand struct {
+	Snapshots DiegoReportPositionalArgs `positional-args:"yes"`
+	Output    string                    `long:"output" value-name:"FORMAT" choice:"table" choice:"json" default:"table" description:"Output format for the report: table or json"`
+	Refresh   bool                      `long:"refresh" description:"Fetch the spaces even if they are cached"`
+	Compare   bool                      `long:"compare" description:"Print the apps that changed between two app listings saved with --output json, instead of the report"`
+}
+
+type DiegoReportPositionalArgs struct {
+	Old string `positional-arg-name:"OLD_JSON" description:"The earlier app listing, with --compare"`
+	New string `positional-arg-name:"NEW_JSON" description:"The later app listing, with --compare"`
+}
+
+func (command DiegoReportCommand) Execute([]string) error {
+	cliConnection := DiegoEnabler.CLIConnection
+	ui.UseOutputFormat(command.Output)
+
+	if command.Compare != (command.Snapshots.New != "") {
+		return CompareArgsError{}
+	}
+
+	if command.Compare {
+		reportCommand := ui.DiegoReportCommand{Output: command.Output}
+		return listhelpers.CompareSnapshots(&reportCommand, command.Snapshots.Old, command.Snapshots.New)
+	}
+
+	reportCommand, err := listhelpers.NewDiegoReportCommand(cliConnection, command.Output)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	return listhelpers.Report(ctx, cliConnection, &reportCommand, command.Refresh)
+}
+
+// CompareArgsError is returned unless --compare is given exactly the two
+// listings to compare.
+type CompareArgsError struct{}
+
+func (CompareArgsError) Error() string {
+	return "--compare needs an old and a new app listing, such as old.json new.json, and they are only accepted with --compare"
+}
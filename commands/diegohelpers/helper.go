@@ -1,45 +1,671 @@
 package diegohelpers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
 	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
 	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+	"github.com/cloudfoundry/cli/plugin/models"
 )
 
-func ToggleDiegoSupport(on bool, cliConnection api.Connection, appName string) error {
+type ToggleOptions struct {
+	NoWait bool
+	DryRun bool
+
+	// Space picks the app by name in that space rather than in the
+	// targeted one.
+	Space string
+
+	// Output is ui.JSONOutput to print the outcome as a toggleResultJSON
+	// on stdout, with the progress messages on stderr.
+	Output string
+
+	// Force enables Diego support for apps on a stack that Diego cannot
+	// run, with a warning, instead of refusing to.
+	Force bool
+
+	// Restart restarts each started app with cf restart once its flag has
+	// been changed, so that it is staged and run on the new runtime.
+	Restart bool
+}
+
+// sayOK prints OK after a step on stdout, or on stderr with JSON output,
+// so that stdout only holds the JSON.
+func (o ToggleOptions) sayOK() {
+	if o.Output == ui.JSONOutput {
+		ui.SayOKTo(ui.LogOutput)
+		return
+	}
+	ui.SayOK()
+}
+
+type toggleResultJSON struct {
+	Name      string `json:"name"`
+	Guid      string `json:"guid"`
+	Requested bool   `json:"requested"`
+	Verified  bool   `json:"verified"`
+}
+
+// AmbiguousAppNameError is returned when the app name is used in more
+// than one space, so that the app in the targeted space might not be the
+// one meant.
+type AmbiguousAppNameError struct {
+	AppName string
+	Spaces  []string
+}
+
+func (e AmbiguousAppNameError) Error() string {
+	return fmt.Sprintf("App %s exists in several spaces (%s). Pick one with --space or --guid.", e.AppName, strings.Join(e.Spaces, ", "))
+}
+
+// SetFlagError is returned when the request to set the Diego flag failed,
+// so that the flag is unchanged and setting it again may succeed.
+type SetFlagError struct {
+	Label string
+	Err   error
+}
+
+func (e SetFlagError) Error() string {
+	return fmt.Sprintf("Failed to set the Diego flag of %s: %s", e.Label, e.Err)
+}
+
+// VerificationError is returned when the Cloud Controller accepted the
+// Diego flag but reading it back shows the old value, which points at an
+// inconsistency to investigate rather than a request to retry.
+type VerificationError struct {
+	Label string
+	On    bool
+}
+
+func (e VerificationError) Error() string {
+	return fmt.Sprintf("The Diego flag of %s was set, but verification shows it is NOT set to %t", e.Label, e.On)
+}
+
+func (e VerificationError) ExitCode() int {
+	return errorhelpers.VerificationFailureExitCode
+}
+
+type AppNotFoundInSpaceError struct {
+	AppName   string
+	SpaceName string
+}
+
+func (e AppNotFoundInSpaceError) Error() string {
+	return fmt.Sprintf("App %s not found in space %s", e.AppName, e.SpaceName)
+}
+
+func ToggleDiegoSupport(on bool, cliConnection api.Connection, appName string, options ToggleOptions) error {
+	if options.Output != ui.JSONOutput {
+		_, err := toggleByName(on, cliConnection, appName, options)
+		return err
+	}
+
+	appGuid, err := toggleByName(on, cliConnection, appName, options)
+
+	result := toggleResultJSON{
+		Name:      appName,
+		Guid:      appGuid,
+		Requested: on,
+		Verified:  err == nil && !options.NoWait,
+	}
+	if encodeErr := json.NewEncoder(os.Stdout).Encode(result); encodeErr != nil {
+		return encodeErr
+	}
+
+	// The error goes to stderr so that stdout only holds the JSON.
+	if err != nil {
+		fmt.Fprintln(os.Stderr, strings.TrimSpace(err.Error()))
+		return errorhelpers.ExitStatus{Code: errorhelpers.ExitCode(err)}
+	}
+	return nil
+}
+
+// toggleByName sets the flag of the app called appName and returns its
+// guid, if it was found.
+func toggleByName(on bool, cliConnection api.Connection, appName string, options ToggleOptions) (string, error) {
 	d := diegosupport.NewDiegoSupport(cliConnection)
 
-	fmt.Printf("Setting %s Diego support to %t\n", appName, on)
+	matches, err := d.FindAppsByName(appName)
+	if err != nil {
+		return "", err
+	}
+
+	if options.Space != "" {
+		return toggleAppInSpace(on, d, appName, matches, options)
+	}
+
+	if len(matches) > 1 {
+		return "", ambiguousAppName(appName, matches)
+	}
+
+	if options.DryRun {
+		return "", dryRunToggle(on, cliConnection, appName)
+	}
+
 	app, err := cliConnection.GetApp(appName)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if app.Diego == on {
+		ui.Info("App %s is already set to Diego=%t, no change needed\n", appName, on)
+		return app.Guid, nil
+	}
+
+	var stackName string
+	if app.Stack != nil {
+		stackName = app.Stack.Name
+	}
+	if err := checkStack(on, appName, stackName, options); err != nil {
+		return app.Guid, err
+	}
+
+	var spaceName string
+	if len(matches) == 1 {
+		spaceName = matches[0].SpaceName
+	}
+
+	ui.Info("Setting %s Diego support to %t\n", appName, on)
+	err = setDiegoFlag(on, d, appName, spaceName, app.Guid, options, func() (bool, error) {
+		app, err := cliConnection.GetApp(appName)
+		return app.Diego, err
+	})
+	if err != nil || !options.Restart {
+		return app.Guid, err
+	}
+	return app.Guid, restartApp(cliConnection, appName, app.State)
+}
+
+// RestartError is returned when the flag was changed but cf restart
+// failed, so that the app still runs where it was started.
+type RestartError struct {
+	AppName string
+	Err     error
+}
+
+func (e RestartError) Error() string {
+	return fmt.Sprintf("Diego support for %s was set, but restarting it failed: %s", e.AppName, e.Err)
+}
+
+// restartApp restarts the app with cf restart. A stopped app is left
+// stopped, since cf restart would start it.
+func restartApp(cliConnection api.Connection, appName string, state string) error {
+	if !strings.EqualFold(state, models.Started) {
+		ui.Info("App %s is not started, so it is not restarted\n", appName)
+		return nil
 	}
 
-	if output, err := d.SetDiegoFlag(app.Guid, on); err != nil {
-		return fmt.Errorf("%s\n%s", err, strings.Join(output, "\n"))
+	ui.Info("Restarting %s\n", appName)
+	if _, err := cliConnection.CliCommand("restart", appName); err != nil {
+		return RestartError{AppName: appName, Err: err}
 	}
 	ui.SayOK()
+	return nil
+}
 
-	fmt.Printf("Verifying %s Diego support is set to %t\n", appName, on)
-	app, err = cliConnection.GetApp(appName)
-	if err != nil {
-		return err
+// toggleAppInSpace sets the flag of the app with that name in
+// options.Space, out of the matches found by name.
+func toggleAppInSpace(on bool, d *diegosupport.DiegoSupport, appName string, matches []diegosupport.AppMatch, options ToggleOptions) (string, error) {
+	var inSpace []diegosupport.AppMatch
+	for _, match := range matches {
+		if match.SpaceName == options.Space {
+			inSpace = append(inSpace, match)
+		}
+	}
+
+	switch {
+	case len(inSpace) == 0:
+		return "", AppNotFoundInSpaceError{AppName: appName, SpaceName: options.Space}
+	case len(inSpace) > 1:
+		return "", ambiguousAppName(appName, inSpace)
+	}
+
+	app := inSpace[0]
+	if options.DryRun {
+		reportDryRun(on, appName, app.Diego)
+		return app.Guid, nil
 	}
 
 	if app.Diego == on {
-		ui.SayOK()
+		ui.Info("App %s is already set to Diego=%t, no change needed\n", appName, on)
+		return app.Guid, nil
+	}
+
+	if err := checkStack(on, appName, app.StackName, options); err != nil {
+		return app.Guid, err
+	}
+
+	ui.Info("Setting %s Diego support to %t\n", appName, on)
+	return app.Guid, setDiegoFlag(on, d, appName, app.SpaceName, app.Guid, options, func() (bool, error) {
+		return d.GetDiegoFlag(app.Guid)
+	})
+}
+
+// DefaultIncompatibleStacks are the stacks that Diego cannot run, unless
+// CF_DIEGO_INCOMPATIBLE_STACKS lists others.
+const DefaultIncompatibleStacks = "lucid64"
+
+// IncompatibleStackError is returned for enabling Diego support for an app
+// whose stack Diego cannot run, which the Cloud Controller allows but the
+// app then fails to stage.
+type IncompatibleStackError struct {
+	AppName   string
+	StackName string
+}
+
+func (e IncompatibleStackError) Error() string {
+	return fmt.Sprintf("App %s is on stack %s, which cannot run on Diego. Pass --force to enable Diego support anyway.", e.AppName, e.StackName)
+}
+
+// checkStack refuses to enable Diego support for an app on an incompatible
+// stack, or only warns about it with Force.
+func checkStack(on bool, appName string, stackName string, options ToggleOptions) error {
+	if !on || !isIncompatibleStack(stackName) {
+		return nil
+	}
+
+	if !options.Force {
+		return IncompatibleStackError{AppName: appName, StackName: stackName}
+	}
+	ui.Warn("App %s is on stack %s, which cannot run on Diego, so it may fail to stage\n", appName, stackName)
+	return nil
+}
+
+// isIncompatibleStack looks stackName up in the comma-separated
+// CF_DIEGO_INCOMPATIBLE_STACKS, which may be set empty to allow any stack.
+func isIncompatibleStack(stackName string) bool {
+	if stackName == "" {
+		return false
+	}
+
+	stacks, ok := os.LookupEnv("CF_DIEGO_INCOMPATIBLE_STACKS")
+	if !ok {
+		stacks = DefaultIncompatibleStacks
+	}
+	for _, stack := range strings.Split(stacks, ",") {
+		if strings.TrimSpace(stack) == stackName {
+			return true
+		}
+	}
+	return false
+}
+
+func ambiguousAppName(appName string, matches []diegosupport.AppMatch) error {
+	spaces := make([]string, len(matches))
+	for i, match := range matches {
+		spaces[i] = match.SpaceName
+	}
+	return AmbiguousAppNameError{AppName: appName, Spaces: spaces}
+}
+
+// ToggleDiegoSupportByGuid sets the flag of the app with the given guid,
+// skipping the lookup by name.
+func ToggleDiegoSupportByGuid(on bool, cliConnection api.Connection, appGuid string, options ToggleOptions) error {
+	d := diegosupport.NewDiegoSupport(cliConnection)
+	label := "app " + appGuid
+
+	if options.DryRun {
+		diego, err := d.GetDiegoFlag(appGuid)
+		if err != nil {
+			return err
+		}
+		reportDryRun(on, label, diego)
+		return nil
+	}
+
+	if on {
+		stackName, err := d.GetStackName(appGuid)
+		if err != nil {
+			return err
+		}
+		if err := checkStack(on, appGuid, stackName, options); err != nil {
+			return err
+		}
+	}
+
+	ui.Info("Setting %s Diego support to %t\n", label, on)
+	err := setDiegoFlag(on, d, label, "", appGuid, options, func() (bool, error) {
+		return d.GetDiegoFlag(appGuid)
+	})
+	if flagErr, ok := err.(SetFlagError); ok {
+		if _, ok := flagErr.Err.(errorhelpers.SpaceDeveloperRequiredError); ok {
+			flagErr.Err = errorhelpers.SpaceDeveloperRequiredError{SpaceName: spaceNameOfApp(d, appGuid)}
+			return flagErr
+		}
+	}
+	return err
+}
+
+// spaceNameOfApp looks up the name of the space of the app with the given
+// guid, or returns "" when it cannot be read.
+func spaceNameOfApp(d *diegosupport.DiegoSupport, appGuid string) string {
+	app, err := d.GetAppByGuid(appGuid)
+	if err != nil {
+		return ""
+	}
+	spaceName, err := d.GetSpaceName(app.SpaceGuid)
+	if err != nil {
+		return ""
+	}
+	return spaceName
+}
+
+// VerifyAttempts and DefaultVerifyDelay bound how long setDiegoFlag waits
+// for the flag just set to be read back, since the Cloud Controller can
+// briefly return the old one.
+const (
+	VerifyAttempts     = 3
+	DefaultVerifyDelay = 500 * time.Millisecond
+)
+
+// setDiegoFlag sets the flag and, unless NoWait is set, verifies it from
+// the Cloud Controller's response or, failing that, with currentFlag.
+// spaceName, if known, is named in the error for a user who lacks the
+// SpaceDeveloper role.
+func setDiegoFlag(
+	on bool,
+	d *diegosupport.DiegoSupport,
+	label string,
+	spaceName string,
+	appGuid string,
+	options ToggleOptions,
+	currentFlag func() (bool, error),
+) error {
+	output, err := d.SetDiegoFlag(appGuid, on)
+	if ccErr, ok := err.(diegosupport.CCErrorResponse); ok {
+		if ccErr.IsNotAuthorized() {
+			return SetFlagError{Label: label, Err: errorhelpers.SpaceDeveloperRequiredError{SpaceName: spaceName}}
+		}
+		return SetFlagError{Label: label, Err: err}
+	}
+	if err != nil {
+		return SetFlagError{Label: label, Err: fmt.Errorf("%s\n%s", err, strings.Join(output, "\n"))}
+	}
+	options.sayOK()
+
+	if options.NoWait {
+		return nil
+	}
+
+	ui.Info("Verifying %s Diego support is set to %t\n", label, on)
+	diego, ok := diegosupport.DiegoFlagFromOutput(output)
+	if !ok {
+		diego, err = waitForFlag(on, currentFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	if diego == on {
+		options.sayOK()
 	} else {
-		return fmt.Errorf("Diego support for %s is NOT set to %t\n\n", appName, on)
+		return VerificationError{Label: label, On: on}
+	}
+
+	return nil
+}
+
+// waitForFlag reads the flag with currentFlag until it is on, for at most
+// VerifyAttempts reads, and returns the last one.
+func waitForFlag(on bool, currentFlag func() (bool, error)) (bool, error) {
+	delay := DefaultVerifyDelay
+	if value := os.Getenv("CF_DIEGO_VERIFY_DELAY"); value != "" {
+		var err error
+		delay, err = time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return false, api.InvalidRetrySettingError{Name: "CF_DIEGO_VERIFY_DELAY", PassedValue: value}
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		diego, err := currentFlag()
+		if err != nil || diego == on || attempt >= VerifyAttempts {
+			return diego, err
+		}
+		time.Sleep(delay)
+	}
+}
+
+// dryRunToggle looks the app up and reports what ToggleDiegoSupport would
+// do, without ever setting the flag.
+func dryRunToggle(on bool, cliConnection api.Connection, appName string) error {
+	app, err := cliConnection.GetApp(appName)
+	if err != nil {
+		return err
 	}
 
+	reportDryRun(on, appName, app.Diego)
 	return nil
 }
 
-func IsDiegoEnabled(cliConnection api.Connection, appName string) error {
+func reportDryRun(on bool, label string, diego bool) {
+	if diego == on {
+		fmt.Printf("%s Diego support is already %t\n", label, on)
+	} else {
+		fmt.Printf("Would set %s Diego support to %t\n", label, on)
+	}
+}
+
+func ToggleDiegoSupportForApps(on bool, cliConnection api.Connection, appNames []string, options ToggleOptions) error {
+	return toggleEach(on, appNames, options, func(appName string) error {
+		return ToggleDiegoSupport(on, cliConnection, appName, options)
+	})
+}
+
+// ToggleDiegoSupportForGuids is ToggleDiegoSupportForApps for apps given by
+// guid.
+func ToggleDiegoSupportForGuids(on bool, cliConnection api.Connection, appGuids []string, options ToggleOptions) error {
+	return toggleEach(on, appGuids, options, func(appGuid string) error {
+		return ToggleDiegoSupportByGuid(on, cliConnection, appGuid, options)
+	})
+}
+
+// toggleEach calls toggle for every app, carrying on past failures, and
+// summarizes them at the end.
+func toggleEach(on bool, appNames []string, options ToggleOptions, toggle func(string) error) error {
+	var failures []string
+	var unverified int
+
+	for _, appName := range appNames {
+		err := toggle(appName)
+		if _, ok := err.(VerificationError); ok {
+			unverified++
+		}
+		if err != nil {
+			ui.SayFailed()
+			ui.Error("%s\n\n", strings.TrimSpace(err.Error()))
+			failures = append(failures, fmt.Sprintf("%s: %s", appName, strings.TrimSpace(err.Error())))
+		}
+	}
+
+	if options.DryRun {
+		fmt.Printf("Diego support would be set to %t for %d of %d apps\n", on, len(appNames)-len(failures), len(appNames))
+	} else {
+		fmt.Printf("Diego support set to %t for %d of %d apps\n", on, len(appNames)-len(failures), len(appNames))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(ui.LogOutput, "Failed apps:")
+	for _, failure := range failures {
+		fmt.Fprintf(ui.LogOutput, "   %s\n", failure)
+	}
+	fmt.Fprintln(ui.LogOutput)
+
+	return errorhelpers.BatchFailure{
+		Message:    fmt.Sprintf("Failed to set Diego support to %t for %d of %d apps", on, len(failures), len(appNames)),
+		Failed:     len(failures),
+		Attempted:  len(appNames),
+		Unverified: unverified,
+	}
+}
+
+func ReadAppNames(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadLines(file)
+}
+
+// ReadLines returns the non-blank lines of r, trimmed, such as the app
+// guids piped to --stdin.
+func ReadLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// DiegoDisabledExitCode is the exit status of has-diego-enabled when every
+// app it checked was found but at least one is not on Diego.
+const DiegoDisabledExitCode = 3
+
+// DeaDisabledExitCode is the exit status of has-dea-enabled when every app
+// it checked was found but at least one is not on the DEAs.
+const DeaDisabledExitCode = 3
+
+type CheckOptions struct {
+	Output string
+
+	// ShowLocation adds the space and org of each app, looked up with the
+	// spaces request.
+	ShowLocation bool
+}
+
+func IsDiegoEnabled(ctx context.Context, cliConnection api.Connection, appName string, options CheckOptions) error {
+	return isOnRuntime(ctx, cliConnection, appName, true, options)
+}
+
+func IsDeaEnabled(ctx context.Context, cliConnection api.Connection, appName string, options CheckOptions) error {
+	return isOnRuntime(ctx, cliConnection, appName, false, options)
+}
+
+// IsDiegoEnabledForApps prints one line per app and carries on past apps
+// that cannot be found, returning an error once all of them are checked.
+func IsDiegoEnabledForApps(ctx context.Context, cliConnection api.Connection, appNames []string, options CheckOptions) error {
+	return areOnRuntime(ctx, cliConnection, appNames, true, options)
+}
+
+func IsDeaEnabledForApps(ctx context.Context, cliConnection api.Connection, appNames []string, options CheckOptions) error {
+	return areOnRuntime(ctx, cliConnection, appNames, false, options)
+}
+
+type appRuntimeJSON struct {
+	Name         string `json:"name"`
+	Guid         string `json:"guid"`
+	Diego        bool   `json:"diego"`
+	Space        string `json:"space,omitempty"`
+	Organization string `json:"org,omitempty"`
+}
+
+// appLocator finds the space and org of apps, fetching the space of each
+// app the first time one of its apps is looked up. A nil appLocator finds
+// nothing, for when they are not shown.
+type appLocator struct {
+	ctx          context.Context
+	spaceFetcher *api.SpaceFetcher
+	spaces       map[string]models.Space
+	resolved     map[string]bool
+}
+
+func newAppLocator(ctx context.Context, cliConnection api.Connection, options CheckOptions) (*appLocator, error) {
+	if !options.ShowLocation {
+		return nil, nil
+	}
+
+	apiClient, err := api.NewClient(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	spaceFetcher, err := api.NewSpaceFetcher(cliConnection, apiClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &appLocator{
+		ctx:          ctx,
+		spaceFetcher: spaceFetcher,
+		spaces:       make(map[string]models.Space),
+		resolved:     make(map[string]bool),
+	}, nil
+}
+
+func (l *appLocator) appJSON(appName string, app plugin_models.GetAppModel) (appRuntimeJSON, error) {
+	result := appRuntimeJSON{Name: appName, Guid: app.Guid, Diego: app.Diego}
+	if l != nil {
+		printer, err := l.printer(app.SpaceGuid)
+		if err != nil {
+			return appRuntimeJSON{}, err
+		}
+		result.Space = printer.Space()
+		result.Organization = printer.Organization()
+	}
+	return result, nil
+}
+
+// describe returns " (org ORG / space SPACE)" for the app, or nothing.
+func (l *appLocator) describe(app plugin_models.GetAppModel) (string, error) {
+	if l == nil {
+		return "", nil
+	}
+	printer, err := l.printer(app.SpaceGuid)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(" (org %s / space %s)", printer.Organization(), printer.Space()), nil
+}
+
+// printer fetches the space with spaceGuid, unless it was already, and
+// returns a printer for an app in it. A space the user cannot see is
+// shown as it is when listing apps.
+func (l *appLocator) printer(spaceGuid string) (*displayhelpers.AppPrinter, error) {
+	if !l.resolved[spaceGuid] {
+		space, ok, err := l.spaceFetcher.GetSpace(l.ctx, spaceGuid)
+		if err != nil {
+			return nil, err
+		}
+		l.resolved[spaceGuid] = true
+		if ok {
+			l.spaces[space.Guid] = space
+		}
+	}
+
+	return &displayhelpers.AppPrinter{
+		App: models.Application{
+			ApplicationEntity: models.ApplicationEntity{SpaceGuid: spaceGuid},
+		},
+		Spaces: l.spaces,
+	}, nil
+}
+
+// isOnRuntime prints whether the app is on Diego, if diego is true, or on
+// the DEAs otherwise.
+func isOnRuntime(ctx context.Context, cliConnection api.Connection, appName string, diego bool, options CheckOptions) error {
 	app, err := cliConnection.GetApp(appName)
 	if err != nil {
 		return err
@@ -48,9 +674,188 @@ func IsDiegoEnabled(cliConnection api.Connection, appName string) error {
 	if app.Guid == "" {
 		return fmt.Errorf("App %s not found\n\n", appName)
 	}
+	if ctx.Err() != nil {
+		return api.ErrInterrupted
+	}
+
+	locator, err := newAppLocator(ctx, cliConnection, options)
+	if err != nil {
+		return err
+	}
+
+	enabled := app.Diego == diego
+	if !ui.Quiet {
+		if options.Output == ui.JSONOutput {
+			appJSON, err := locator.appJSON(appName, app)
+			if err != nil {
+				return err
+			}
+			err = json.NewEncoder(os.Stdout).Encode(appJSON)
+			if err != nil {
+				return err
+			}
+		} else {
+			location, err := locator.describe(app)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%t%s\n", enabled, location)
+		}
+	}
+
+	if !enabled {
+		return errorhelpers.ExitStatus{Code: runtimeDisabledExitCode(diego)}
+	}
+
+	return nil
+}
+
+// areOnRuntime checks every app in turn. With JSON output it prints an
+// array of the apps it found, and reports the others on stderr.
+func areOnRuntime(ctx context.Context, cliConnection api.Connection, appNames []string, diego bool, options CheckOptions) error {
+	var failed, disabled int
+
+	locator, err := newAppLocator(ctx, cliConnection, options)
+	if err != nil {
+		return err
+	}
+
+	output := options.Output
+	var problems io.Writer = os.Stdout
+	if output == ui.JSONOutput {
+		problems = os.Stderr
+	}
+	found := []appRuntimeJSON{}
+
+	for _, appName := range appNames {
+		// GetApp cannot be cancelled, so Ctrl-C stops before the next app
+		if ctx.Err() != nil {
+			return api.ErrInterrupted
+		}
+
+		app, err := cliConnection.GetApp(appName)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(problems, "%s: %s\n", appName, strings.TrimSpace(err.Error()))
+		case app.Guid == "":
+			failed++
+			fmt.Fprintf(problems, "%s: not found\n", appName)
+		default:
+			enabled := app.Diego == diego
+			if !enabled {
+				disabled++
+			}
+			if output == ui.JSONOutput {
+				appJSON, err := locator.appJSON(appName, app)
+				if err != nil {
+					return err
+				}
+				found = append(found, appJSON)
+			} else if !ui.Quiet {
+				location, err := locator.describe(app)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s: %t%s\n", appName, enabled, location)
+			}
+		}
+	}
+
+	if output == ui.JSONOutput && !ui.Quiet {
+		if err := json.NewEncoder(os.Stdout).Encode(found); err != nil {
+			return err
+		}
+	}
+
+	runtime := ui.Diego
+	if !diego {
+		runtime = ui.DEA
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Could not determine %s support for %d of %d apps", runtime, failed, len(appNames))
+	}
+
+	if disabled > 0 {
+		return errorhelpers.ExitStatus{Code: runtimeDisabledExitCode(diego)}
+	}
+
+	return nil
+}
+
+func runtimeDisabledExitCode(diego bool) int {
+	if diego {
+		return DiegoDisabledExitCode
+	}
+	return DeaDisabledExitCode
+}
+
+func NewAppNamesCommand(cliConnection api.Connection, output string) (ui.AppNamesCommand, error) {
+	username, err := cliConnection.Username()
+	if err != nil {
+		return ui.AppNamesCommand{}, err
+	}
+
+	traceLogger := trace.NewLogger(false, os.Getenv("CF_TRACE"), "")
+	return ui.AppNamesCommand{
+		Username: username,
+		Output:   output,
+		UI:       terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), traceLogger),
+	}, nil
+}
+
+// ResolveAppGuids prints the name, space and org of the app with each
+// guid, and returns an error once all of them are printed if any guid was
+// not found.
+func ResolveAppGuids(ctx context.Context, cliConnection api.Connection, appGuids []string, command *ui.AppNamesCommand) error {
+	command.BeforeAll(len(appGuids))
+
+	d := diegosupport.NewDiegoSupport(cliConnection)
+	locator, err := newAppLocator(ctx, cliConnection, CheckOptions{ShowLocation: true})
+	if err != nil {
+		return err
+	}
+
+	var missing int
+	names := make([]ui.AppName, 0, len(appGuids))
+	for _, appGuid := range appGuids {
+		// cf curl cannot be cancelled, so Ctrl-C stops before the next
+		// guid rather than printing part of the table
+		if ctx.Err() != nil {
+			return api.ErrInterrupted
+		}
+
+		app, err := d.GetAppByGuid(appGuid)
+		if ccErr, ok := err.(diegosupport.CCErrorResponse); ok && ccErr.IsNotFound() {
+			missing++
+			names = append(names, ui.AppName{Guid: appGuid})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		printer, err := locator.printer(app.SpaceGuid)
+		if err != nil {
+			return err
+		}
+		names = append(names, ui.AppName{
+			Guid:         appGuid,
+			Found:        true,
+			Name:         app.Name,
+			Space:        printer.Space(),
+			Organization: printer.Organization(),
+		})
+	}
 
-	fmt.Println(app.Diego)
+	if err := command.AfterAll(names); err != nil {
+		return err
+	}
 
+	if missing > 0 {
+		return fmt.Errorf("%d of %d app guids were not found", missing, len(appGuids))
+	}
 	return nil
 }
 
@@ -59,15 +864,19 @@ type OrgNotFoundErr struct {
 }
 
 func (e OrgNotFoundErr) Error() string {
-	return fmt.Sprintf("Organization not found: %s", e.OrganizationName)
+	return fmt.Sprintf("Organization %s not found", e.OrganizationName)
 }
 
 type SpaceNotFoundErr struct {
-	SpaceName string
+	SpaceName        string
+	OrganizationName string
 }
 
 func (e SpaceNotFoundErr) Error() string {
-	return fmt.Sprintf("Space not found: %s", e.SpaceName)
+	if e.OrganizationName != "" {
+		return fmt.Sprintf("Space %s not found in organization %s", e.SpaceName, e.OrganizationName)
+	}
+	return fmt.Sprintf("Space %s not found", e.SpaceName)
 }
 
 func NewAppsGetterFunc(
@@ -76,26 +885,78 @@ func NewAppsGetterFunc(
 	spaceName string,
 	runtime ui.Runtime,
 ) (thingdoer.AppsGetterFunc, error) {
-	diegoAppsCommand := thingdoer.AppsGetter{}
+	diegoAppsCommand, err := NewAppsGetter(cliConnection, orgName, spaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var appsGetterFunc = diegoAppsCommand.DiegoApps
+	switch runtime {
+	case ui.DEA:
+		appsGetterFunc = diegoAppsCommand.DeaApps
+	case ui.AllRuntimes:
+		appsGetterFunc = diegoAppsCommand.AllApps
+	}
+
+	return appsGetterFunc, nil
+}
+
+// NewAppsStreamerFunc is NewAppsGetterFunc for listings that handle the
+// apps a page at a time. It takes the AppsGetter so that the listing can
+// also narrow down the spaces it fetches to the org of the apps.
+func NewAppsStreamerFunc(appsGetter thingdoer.AppsGetter, runtime ui.Runtime) thingdoer.AppsStreamerFunc {
+	var appsStreamerFunc = appsGetter.StreamDiegoApps
+	switch runtime {
+	case ui.DEA:
+		appsStreamerFunc = appsGetter.StreamDeaApps
+	case ui.AllRuntimes:
+		appsStreamerFunc = appsGetter.StreamAllApps
+	}
+
+	return appsStreamerFunc
+}
 
-	if orgName != "" {
+func NewAppsGetter(
+	cliConnection api.Connection,
+	orgName string,
+	spaceName string,
+) (thingdoer.AppsGetter, error) {
+	appsGetter := thingdoer.AppsGetter{}
+
+	if orgName != "" && spaceName != "" {
+		org, err := cliConnection.GetOrg(orgName)
+		if err != nil || org.Guid == "" {
+			return appsGetter, OrgNotFoundErr{OrganizationName: orgName}
+		}
+		spaceGuid, ok := findSpaceGuid(org, spaceName)
+		if !ok {
+			return appsGetter, SpaceNotFoundErr{SpaceName: spaceName, OrganizationName: orgName}
+		}
+		appsGetter.OrganizationGuid = org.Guid
+		appsGetter.SpaceGuid = spaceGuid
+	} else if orgName != "" {
 		org, err := cliConnection.GetOrg(orgName)
 		if err != nil || org.Guid == "" {
-			return nil, OrgNotFoundErr{OrganizationName: orgName}
+			return appsGetter, OrgNotFoundErr{OrganizationName: orgName}
 		}
-		diegoAppsCommand.OrganizationGuid = org.Guid
+		appsGetter.OrganizationGuid = org.Guid
 	} else if spaceName != "" {
 		space, err := cliConnection.GetSpace(spaceName)
 		if err != nil || space.Guid == "" {
-			return nil, SpaceNotFoundErr{SpaceName: spaceName}
+			return appsGetter, SpaceNotFoundErr{SpaceName: spaceName}
 		}
-		diegoAppsCommand.SpaceGuid = space.Guid
+		appsGetter.OrganizationGuid = space.Organization.Guid
+		appsGetter.SpaceGuid = space.Guid
 	}
 
-	var appsGetterFunc = diegoAppsCommand.DiegoApps
-	if runtime == ui.DEA {
-		appsGetterFunc = diegoAppsCommand.DeaApps
-	}
+	return appsGetter, nil
+}
 
-	return appsGetterFunc, nil
+func findSpaceGuid(org plugin_models.GetOrg_Model, spaceName string) (string, bool) {
+	for _, space := range org.Spaces {
+		if space.Name == spaceName {
+			return space.Guid, true
+		}
+	}
+	return "", false
 }
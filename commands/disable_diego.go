@@ -1,15 +1,102 @@
 package commands
 
-import "github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
 
 type DisableDiegoCommand struct {
 	RequiredOptions DisableDiegoPositionalArgs `positional-args:"yes"`
+	AppsFile        string                     `long:"apps-file" value-name:"PATH" description:"File listing one app name per line to disable Diego support for"`
+	NoWait          bool                       `long:"no-wait" description:"Do not verify the Diego flag after setting it"`
+	DryRun          bool                       `long:"dry-run" description:"Show what would change without setting the Diego flag"`
+	Guid            string                     `long:"guid" value-name:"APP_GUID" description:"Guid of the app to disable Diego support for, instead of its name"`
+	Stdin           bool                       `long:"stdin" description:"Read one app guid per line from stdin to disable Diego support for"`
+	GuidsFile       string                     `long:"guids-file" value-name:"PATH" description:"File listing one app guid per line, such as a --failures-file, to disable Diego support for"`
+	Space           string                     `short:"s" long:"space" value-name:"SPACE" description:"Space of the app, for app names used in more than one space"`
+	Output          string                     `long:"output" value-name:"FORMAT" choice:"text" choice:"json" default:"text" description:"Output format: text or json, which prints the name, guid, requested flag and whether it was verified"`
 }
 
 type DisableDiegoPositionalArgs struct {
-	AppName string `positional-arg-name:"APP_NAME" required:"true" description:"The app name"`
+	AppName string `positional-arg-name:"APP_NAME" description:"The app name"`
 }
 
 func (command DisableDiegoCommand) Execute([]string) error {
-	return diegohelpers.ToggleDiegoSupport(false, DiegoEnabler.CLIConnection, command.RequiredOptions.AppName)
+	cliConnection := DiegoEnabler.CLIConnection
+	ui.UseOutputFormat(command.Output)
+
+	err := errorhelpers.ErrorIfStdinAndAppNameSet(command.Stdin, command.Guid, command.RequiredOptions.AppName, command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfGuidsFileAndAppNameSet(command.GuidsFile, command.Stdin, command.Guid, command.RequiredOptions.AppName, command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	readsGuids := command.Stdin || command.GuidsFile != ""
+
+	err = errorhelpers.ErrorIfSpaceAndGuidSet(command.Space, readsGuids, command.Guid)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfOutputAndManyAppsSet(command.Output, command.AppsFile, command.Guid, readsGuids, command.DryRun)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfGuidAndAppNameSet(command.Guid, command.RequiredOptions.AppName, command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	if command.Guid == "" && !readsGuids {
+		err = errorhelpers.ErrorUnlessAppNameOrAppsFileSet(command.RequiredOptions.AppName, command.AppsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = api.ErrorIfVerificationSkipped(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	options := diegohelpers.ToggleOptions{
+		NoWait: command.NoWait,
+		DryRun: command.DryRun,
+		Space:  command.Space,
+		Output: command.Output,
+	}
+
+	if command.DryRun {
+		defer ui.SayDryRun()
+	}
+
+	if command.Guid != "" {
+		return diegohelpers.ToggleDiegoSupportByGuid(false, cliConnection, command.Guid, options)
+	}
+
+	if readsGuids {
+		appGuids, err := readAppGuids(command.GuidsFile)
+		if err != nil {
+			return err
+		}
+		return diegohelpers.ToggleDiegoSupportForGuids(false, cliConnection, appGuids, options)
+	}
+
+	if command.AppsFile == "" {
+		return diegohelpers.ToggleDiegoSupport(false, cliConnection, command.RequiredOptions.AppName, options)
+	}
+
+	appNames, err := diegohelpers.ReadAppNames(command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	return diegohelpers.ToggleDiegoSupportForApps(false, cliConnection, appNames, options)
 }
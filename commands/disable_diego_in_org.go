@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/bulkhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type DisableDiegoInOrgCommand struct {
+	RequiredOptions DisableDiegoInOrgPositionalArgs `positional-args:"yes"`
+	DryRun          bool                            `long:"dry-run" description:"Show which apps would be switched without changing them"`
+	Force           bool                            `short:"f" long:"force" description:"Switch the apps without asking for confirmation"`
+	MaxInFlight     flaghelpers.ParallelFlag        `short:"p" value-name:"MAX_IN_FLIGHT" default:"5" description:"Maximum number of apps to switch in parallel (maximum: 100)"`
+	TimeoutPerApp   flaghelpers.TimeoutFlag         `long:"timeout-per-app" value-name:"DURATION" description:"Give up on an app whose Diego flag has not been set within DURATION, such as 30s, and move on"`
+	FailuresFile    string                          `long:"failures-file" value-name:"PATH" description:"Write the guid of each app that was not switched to PATH, to retry them with cf disable-diego --guids-file PATH"`
+	Live            bool                            `long:"live" description:"Show the apps in a table whose status is updated as each app is switched, instead of a line for each app, when stdout is a terminal"`
+}
+
+type DisableDiegoInOrgPositionalArgs struct {
+	OrganizationName string `positional-arg-name:"ORG_NAME" required:"true" description:"The organization name"`
+}
+
+func (command DisableDiegoInOrgCommand) Execute([]string) error {
+	cliConnection := DiegoEnabler.CLIConnection
+	orgName := command.RequiredOptions.OrganizationName
+
+	err := api.ErrorIfVerificationSkipped(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	appsGetter, err := diegohelpers.NewAppsGetter(cliConnection, orgName, "")
+	if err != nil {
+		return err
+	}
+
+	toggleAppsCommand, err := bulkhelpers.NewToggleAppsCommand(cliConnection, orgName, "", ui.DEA)
+	if err != nil {
+		return err
+	}
+
+	cmd := bulkhelpers.ToggleApps{
+		MaxInFlight:       command.MaxInFlight.Value,
+		Runtime:           ui.DEA,
+		AppsGetterFunc:    appsGetter.AllApps,
+		ToggleAppsCommand: &toggleAppsCommand,
+		DryRun:            command.DryRun,
+		Force:             command.Force,
+		TimeoutPerApp:     command.TimeoutPerApp.Value,
+		FailuresFile:      command.FailuresFile,
+		Live:              bulkhelpers.LiveOutput(command.Live),
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	return cmd.Execute(ctx, cliConnection)
+}
@@ -1,25 +1,88 @@
 package displayhelpers
 
-import "github.com/cloudfoundry-incubator/diego-enabler/models"
+import (
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+// Inaccessible is shown in place of the space and organization of an app
+// whose space the user cannot see.
+const Inaccessible = "(inaccessible)"
 
 type AppPrinter struct {
 	App    models.Application
 	Spaces map[string]models.Space
+	Stacks map[string]models.Stack
 }
 
 func (a *AppPrinter) Name() string {
 	return a.App.Name
 }
 
-func (a *AppPrinter) Organization() string {
-	spaces := a.Spaces
-	app := a.App
+func (a *AppPrinter) Guid() string {
+	return a.App.Guid
+}
 
-	if len(spaces) == 0 {
-		return ""
+func (a *AppPrinter) Diego() bool {
+	return a.App.Diego
+}
+
+func (a *AppPrinter) State() string {
+	return strings.ToLower(a.App.State)
+}
+
+func (a *AppPrinter) Instances() int {
+	return a.App.Instances
+}
+
+func (a *AppPrinter) Memory() int64 {
+	return a.App.Memory
+}
+
+func (a *AppPrinter) Stack() string {
+	if a.App.StackName != "" {
+		return a.App.StackName
+	}
+
+	stack, ok := a.Stacks[a.App.StackGuid]
+	if !ok || stack.Name == "" {
+		return a.App.StackGuid
+	}
+
+	return stack.Name
+}
+
+// Buildpack returns the buildpack the app asked for or, when it did not
+// ask for one, the buildpack detected when it was staged.
+func (a *AppPrinter) Buildpack() string {
+	if a.App.Buildpack != "" {
+		return a.App.Buildpack
 	}
+	return a.App.DetectedBuildpack
+}
+
+func (a *AppPrinter) DockerImage() string {
+	return a.App.DockerImage
+}
+
+// Accessible reports whether the app's space is among Spaces. It is
+// always true when no spaces were fetched.
+func (a *AppPrinter) Accessible() bool {
+	if a.Spaces == nil {
+		return true
+	}
+
+	_, ok := a.Spaces[a.App.SpaceGuid]
+	return ok
+}
 
-	space, ok := spaces[app.SpaceGuid]
+func (a *AppPrinter) Organization() string {
+	if !a.Accessible() {
+		return Inaccessible
+	}
+
+	space, ok := a.Spaces[a.App.SpaceGuid]
 	if !ok {
 		return ""
 	}
@@ -32,20 +95,14 @@ func (a *AppPrinter) Organization() string {
 }
 
 func (a *AppPrinter) Space() string {
-	var display string
-	spaces := a.Spaces
-	app := a.App
-
-	if len(spaces) == 0 {
-		display = app.SpaceGuid
-	} else {
-		space, ok := spaces[app.SpaceGuid]
-		if ok {
-			display = space.Name
-		} else {
-			display = app.SpaceGuid
-		}
-	}
-
-	return display
+	if !a.Accessible() {
+		return Inaccessible
+	}
+
+	space, ok := a.Spaces[a.App.SpaceGuid]
+	if !ok {
+		return a.App.SpaceGuid
+	}
+
+	return space.Name
 }
@@ -0,0 +1,154 @@
+package doctorhelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+const (
+	LoggedInCheck    = "Logged in"
+	AccessTokenCheck = "Access token"
+	ApiCheck         = "API reachable"
+	OrgsCheck        = "Org visibility"
+	SpacesCheck      = "Space visibility"
+)
+
+var (
+	errNoToken  = errors.New("The CLI has no access token")
+	errNoOrgs   = errors.New("No organizations are visible")
+	errNoSpaces = errors.New("No spaces are visible")
+)
+
+// Diagnose runs each check in turn, skipping the ones that need an earlier
+// check to have passed, and exits with status 1, after the checklist, if
+// any check failed.
+func Diagnose(ctx context.Context, cliConnection api.Connection, command *ui.DoctorCommand) error {
+	command.BeforeAll()
+
+	d := diagnosis{command: command}
+	apiClient := d.checkLogin(cliConnection)
+	if apiClient != nil && d.checkApi(ctx, cliConnection) {
+		d.checkVisible(ctx, cliConnection, apiClient, apiClient.NewGetOrganizationsRequest,
+			OrgsCheck, "organizations", errNoOrgs,
+			"Ask an org manager to add you to an organization, then run cf target -o ORG")
+		d.checkVisible(ctx, cliConnection, apiClient, apiClient.NewGetSpacesRequest,
+			SpacesCheck, "spaces", errNoSpaces,
+			"Ask a space manager to give you a role in a space, such as SpaceDeveloper to enable or disable Diego support for its apps")
+	} else {
+		for _, check := range []string{OrgsCheck, SpacesCheck} {
+			d.command.SkippedEach(check, "needs the checks above to pass")
+		}
+	}
+
+	command.AfterAll(d.failed)
+	if d.failed > 0 {
+		return errorhelpers.ExitStatus{Code: 1}
+	}
+	return nil
+}
+
+type diagnosis struct {
+	command *ui.DoctorCommand
+	failed  int
+}
+
+func (d *diagnosis) fail(check string, err error, hint string) {
+	d.failed++
+	d.command.FailedEach(check, err, hint)
+}
+
+// checkLogin returns a client for the Cloud Controller once the user is
+// logged in with a usable token.
+func (d *diagnosis) checkLogin(cliConnection api.Connection) *api.Client {
+	loggedIn, err := cliConnection.IsLoggedIn()
+	if err == nil && !loggedIn {
+		err = api.NotLoggedInError
+	}
+	if err != nil {
+		d.fail(LoggedInCheck, err, "Run cf login, or cf api and then cf login to target a different Cloud Controller")
+		d.command.SkippedEach(AccessTokenCheck, "needs you to be logged in")
+		d.command.SkippedEach(ApiCheck, "needs you to be logged in")
+		return nil
+	}
+
+	username, err := cliConnection.Username()
+	if err != nil {
+		username = "unknown user"
+	}
+	d.command.PassedEach(LoggedInCheck, fmt.Sprintf("as %s", username))
+
+	token, err := cliConnection.AccessToken()
+	if err == nil && token == "" {
+		err = errNoToken
+	}
+	var apiClient *api.Client
+	if err == nil {
+		apiClient, err = api.NewClient(cliConnection)
+	}
+	if err != nil {
+		d.fail(AccessTokenCheck, err, "Run cf login again to get a new access token")
+		d.command.SkippedEach(ApiCheck, "needs an access token")
+		return nil
+	}
+	d.command.PassedEach(AccessTokenCheck, "present")
+
+	return apiClient
+}
+
+func (d *diagnosis) checkApi(ctx context.Context, cliConnection api.Connection) bool {
+	info, err := api.GetInfo(ctx, cliConnection)
+	if err != nil {
+		d.fail(ApiCheck, err, "Check the endpoint with cf api; behind a proxy set HTTPS_PROXY, and for a private CA set CF_CA_CERT, or pass --insecure to skip verifying the certificate")
+		return false
+	}
+
+	endpoint, err := cliConnection.ApiEndpoint()
+	if err != nil {
+		endpoint = "the targeted API"
+	}
+	d.command.PassedEach(ApiCheck, fmt.Sprintf("%s, API version %s", endpoint, info.ApiVersion))
+	return true
+}
+
+// checkVisible asks for a single resource, which is enough to learn from
+// total_results how many the user can see.
+func (d *diagnosis) checkVisible(
+	ctx context.Context,
+	cliConnection api.Connection,
+	apiClient *api.Client,
+	newRequest func() (*http.Request, error),
+	check string,
+	noun string,
+	noneErr error,
+	hint string,
+) {
+	client := *apiClient
+	client.ResultsPerPage = 1
+	requester, err := api.NewPaginatedRequester(cliConnection, client.HandleFiltersAndParameters(client.Authorize(newRequest)))
+	if err != nil {
+		d.fail(check, err, hint)
+		return
+	}
+	requester.Page = 1
+
+	var total int
+	err = requester.DoEach(ctx, api.Filters{}, map[string]interface{}{}, func(body []byte) error {
+		page, err := api.PageParser{}.Parse(body)
+		total = page.TotalResults
+		return err
+	})
+	if err == nil && total == 0 {
+		err = noneErr
+	}
+	if err != nil {
+		d.fail(check, err, hint)
+		return
+	}
+	d.command.PassedEach(check, fmt.Sprintf("%d %s", total, noun))
+}
@@ -1,15 +1,111 @@
 package commands
 
-import "github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
 
 type EnableDiegoCommand struct {
 	RequiredOptions EnableDiegoPositionalArgs `positional-args:"yes"`
+	AppsFile        string                    `long:"apps-file" value-name:"PATH" description:"File listing one app name per line to enable Diego support for"`
+	NoWait          bool                      `long:"no-wait" description:"Do not verify the Diego flag after setting it"`
+	DryRun          bool                      `long:"dry-run" description:"Show what would change without setting the Diego flag"`
+	Guid            string                    `long:"guid" value-name:"APP_GUID" description:"Guid of the app to enable Diego support for, instead of its name"`
+	Stdin           bool                      `long:"stdin" description:"Read one app guid per line from stdin to enable Diego support for"`
+	GuidsFile       string                    `long:"guids-file" value-name:"PATH" description:"File listing one app guid per line, such as a --failures-file, to enable Diego support for"`
+	Space           string                    `short:"s" long:"space" value-name:"SPACE" description:"Space of the app, for app names used in more than one space"`
+	Output          string                    `long:"output" value-name:"FORMAT" choice:"text" choice:"json" default:"text" description:"Output format: text or json, which prints the name, guid, requested flag and whether it was verified"`
+	Force           bool                      `short:"f" long:"force" description:"Enable Diego support even for apps on a stack that Diego cannot run"`
+	Restart         bool                      `long:"restart" description:"Restart each started app with cf restart once Diego support is enabled for it"`
 }
 
 type EnableDiegoPositionalArgs struct {
-	AppName string `positional-arg-name:"APP_NAME" required:"true" description:"The app name"`
+	AppName string `positional-arg-name:"APP_NAME" description:"The app name"`
 }
 
 func (command EnableDiegoCommand) Execute([]string) error {
-	return diegohelpers.ToggleDiegoSupport(true, DiegoEnabler.CLIConnection, command.RequiredOptions.AppName)
+	cliConnection := DiegoEnabler.CLIConnection
+	ui.UseOutputFormat(command.Output)
+
+	err := errorhelpers.ErrorIfStdinAndAppNameSet(command.Stdin, command.Guid, command.RequiredOptions.AppName, command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfGuidsFileAndAppNameSet(command.GuidsFile, command.Stdin, command.Guid, command.RequiredOptions.AppName, command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	readsGuids := command.Stdin || command.GuidsFile != ""
+
+	err = errorhelpers.ErrorIfSpaceAndGuidSet(command.Space, readsGuids, command.Guid)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfOutputAndManyAppsSet(command.Output, command.AppsFile, command.Guid, readsGuids, command.DryRun)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfRestartAndNoAppNameSet(command.Restart, command.Guid, readsGuids, command.Space, command.Output)
+	if err != nil {
+		return err
+	}
+
+	err = errorhelpers.ErrorIfGuidAndAppNameSet(command.Guid, command.RequiredOptions.AppName, command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	if command.Guid == "" && !readsGuids {
+		err = errorhelpers.ErrorUnlessAppNameOrAppsFileSet(command.RequiredOptions.AppName, command.AppsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = api.ErrorIfVerificationSkipped(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	options := diegohelpers.ToggleOptions{
+		NoWait:  command.NoWait,
+		DryRun:  command.DryRun,
+		Space:   command.Space,
+		Output:  command.Output,
+		Force:   command.Force,
+		Restart: command.Restart,
+	}
+
+	if command.DryRun {
+		defer ui.SayDryRun()
+	}
+
+	if command.Guid != "" {
+		return diegohelpers.ToggleDiegoSupportByGuid(true, cliConnection, command.Guid, options)
+	}
+
+	if readsGuids {
+		appGuids, err := readAppGuids(command.GuidsFile)
+		if err != nil {
+			return err
+		}
+		return diegohelpers.ToggleDiegoSupportForGuids(true, cliConnection, appGuids, options)
+	}
+
+	if command.AppsFile == "" {
+		return diegohelpers.ToggleDiegoSupport(true, cliConnection, command.RequiredOptions.AppName, options)
+	}
+
+	appNames, err := diegohelpers.ReadAppNames(command.AppsFile)
+	if err != nil {
+		return err
+	}
+
+	return diegohelpers.ToggleDiegoSupportForApps(true, cliConnection, appNames, options)
 }
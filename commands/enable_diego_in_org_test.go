@@ -0,0 +1,39 @@
+package commands_test
+
+import (
+	"errors"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry/cli/plugin/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnableDiegoInOrgCommand", func() {
+	var (
+		command EnableDiegoInOrgCommand
+
+		err error
+	)
+
+	JustBeforeEach(func() {
+		err = command.Execute([]string{})
+	})
+
+	Context("when the organization cannot be found", func() {
+		BeforeEach(func() {
+			command = EnableDiegoInOrgCommand{
+				RequiredOptions: EnableDiegoInOrgPositionalArgs{
+					OrganizationName: "some-organization",
+				},
+			}
+			fakeConnection.GetOrgReturns(plugin_models.GetOrg_Model{}, errors.New("org not found"))
+		})
+
+		It("returns an organization not found error", func() {
+			Expect(err).To(Equal(diegohelpers.OrgNotFoundErr{OrganizationName: "some-organization"}))
+		})
+	})
+})
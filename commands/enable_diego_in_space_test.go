@@ -0,0 +1,39 @@
+package commands_test
+
+import (
+	"errors"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry/cli/plugin/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnableDiegoInSpaceCommand", func() {
+	var (
+		command EnableDiegoInSpaceCommand
+
+		err error
+	)
+
+	JustBeforeEach(func() {
+		err = command.Execute([]string{})
+	})
+
+	Context("when the space cannot be found", func() {
+		BeforeEach(func() {
+			command = EnableDiegoInSpaceCommand{
+				RequiredOptions: EnableDiegoInSpacePositionalArgs{
+					SpaceName: "some-space",
+				},
+			}
+			fakeConnection.GetSpaceReturns(plugin_models.GetSpace_Model{}, errors.New("space not found"))
+		})
+
+		It("returns a space not found error", func() {
+			Expect(err).To(Equal(diegohelpers.SpaceNotFoundErr{SpaceName: "some-space"}))
+		})
+	})
+})
@@ -1,17 +1,45 @@
 package commands
 
-import "github.com/cloudfoundry-incubator/diego-enabler/api"
+import (
+	"os"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
 
 type Enabler struct {
 	CLIConnection api.Connection
 
-	EnableDiego     EnableDiegoCommand     `command:"enable-diego" description:"enable Diego support for an app"`
-	DisableDiego    DisableDiegoCommand    `command:"disable-diego" description:"disable Diego support for an app"`
-	HasDiegoEnabled HasDiegoEnabledCommand `command:"has-diego-enabled" description:"Check if Diego support is enabled for an app"`
-	DiegoApps       DiegoAppsCommand       `command:"diego-apps" description:"Lists all apps running on the Diego runtime that are visible to the user"`
-	DeaApps         DeaAppsCommand         `command:"dea-apps" description:"Lists all apps running on the DEA runtime that are visible to the user"`
-	MigrateApps     MigrateAppsCommand     `command:"migrate-apps" description:"Migrate all apps to Diego/DEA"`
-	UninstallPlugin UninstallHook          `command:"CLI-MESSAGE-UNINSTALL"`
+	Quiet       func()             `short:"q" long:"quiet" description:"Only print errors and results"`
+	Verbose     func()             `long:"verbose" description:"Print the method and URL of each request to the Cloud Controller to stderr"`
+	Insecure    func()             `long:"insecure" description:"Skip verifying the certificate of the Cloud Controller, which is verified even when the CLI targets it with --skip-ssl-validation"`
+	ShowApiInfo func() error       `long:"show-api-info" description:"Print the API version of the targeted Cloud Controller, and whether Diego is its default runtime, to stderr"`
+	LogLevel    func(string) error `long:"log-level" value-name:"LEVEL" choice:"error" choice:"warn" choice:"info" description:"Least severe messages to log to stderr: error, warn or info (default)"`
+
+	EnableDiego        EnableDiegoCommand        `command:"enable-diego" description:"enable Diego support for an app"`
+	DisableDiego       DisableDiegoCommand       `command:"disable-diego" description:"disable Diego support for an app"`
+	HasDiegoEnabled    HasDiegoEnabledCommand    `command:"has-diego-enabled" description:"Check if Diego support is enabled for an app"`
+	HasDeaEnabled      HasDeaEnabledCommand      `command:"has-dea-enabled" description:"Check if an app is configured to run on the DEA runtime"`
+	DiegoApps          DiegoAppsCommand          `command:"diego-apps" description:"Lists all apps running on the Diego runtime that are visible to the user"`
+	DeaApps            DeaAppsCommand            `command:"dea-apps" description:"Lists all apps running on the DEA runtime that are visible to the user"`
+	AppsByRuntime      AppsByRuntimeCommand      `command:"apps-by-runtime" description:"Lists all apps visible to the user along with the runtime each one is on"`
+	DiegoReport        DiegoReportCommand        `command:"diego-report" description:"Summarize the Diego migration progress of each organization"`
+	AppNamesFromGuids  AppNamesFromGuidsCommand  `command:"app-names-from-guids" description:"Look up the name, space and org of app guids"`
+	MigrateApps        MigrateAppsCommand        `command:"migrate-apps" description:"Migrate all apps to Diego/DEA"`
+	EnableDiegoInOrg   EnableDiegoInOrgCommand   `command:"enable-diego-in-org" description:"Enable Diego support for all apps in an organization"`
+	EnableDiegoInSpace EnableDiegoInSpaceCommand `command:"enable-diego-in-space" description:"Enable Diego support for all apps in a space"`
+	DisableDiegoInOrg  DisableDiegoInOrgCommand  `command:"disable-diego-in-org" description:"Disable Diego support for all apps in an organization"`
+	Doctor             DoctorCommand             `command:"diego-enabler-doctor" description:"Check that the plugin can talk to the Cloud Controller"`
+	UninstallPlugin    UninstallHook             `command:"CLI-MESSAGE-UNINSTALL"`
 }
 
-var DiegoEnabler Enabler
+var DiegoEnabler = Enabler{
+	Quiet: ui.BeQuiet,
+	Verbose: func() {
+		api.RequestLog = os.Stderr
+	},
+	Insecure: func() {
+		api.Insecure = true
+	},
+	LogLevel: ui.SetLogLevel,
+}
@@ -0,0 +1,13 @@
+package errorhelpers_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestErrorhelpers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Errorhelpers Suite")
+}
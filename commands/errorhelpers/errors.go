@@ -1,6 +1,9 @@
 package errorhelpers
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var SpecifyOrgOrSpaceError = errors.New("Cannot specify org together with space.")
 
@@ -10,3 +13,142 @@ func ErrorIfOrgAndSpacesSet(orgName, spaceName string) error {
 	}
 	return nil
 }
+
+var SpecifyAppNameOrAppsFileError = errors.New("Cannot specify APP_NAME together with --apps-file.")
+var AppNameRequiredError = errors.New("the required argument `APP_NAME` was not provided")
+
+func ErrorUnlessAppNameOrAppsFileSet(appName, appsFile string) error {
+	if appName != "" && appsFile != "" {
+		return SpecifyAppNameOrAppsFileError
+	}
+	if appName == "" && appsFile == "" {
+		return AppNameRequiredError
+	}
+	return nil
+}
+
+var SpecifyGuidOrAppNameError = errors.New("Cannot specify --guid together with APP_NAME or --apps-file.")
+
+func ErrorIfGuidAndAppNameSet(appGuid, appName, appsFile string) error {
+	if appGuid != "" && (appName != "" || appsFile != "") {
+		return SpecifyGuidOrAppNameError
+	}
+	return nil
+}
+
+var SpecifyStdinOrAppNameError = errors.New("Cannot specify --stdin together with APP_NAME, --apps-file or --guid.")
+
+func ErrorIfStdinAndAppNameSet(stdin bool, appGuid, appName, appsFile string) error {
+	if stdin && (appGuid != "" || appName != "" || appsFile != "") {
+		return SpecifyStdinOrAppNameError
+	}
+	return nil
+}
+
+var SpecifyGuidsFileOrAppNameError = errors.New("Cannot specify --guids-file together with APP_NAME, --apps-file, --guid or --stdin.")
+
+func ErrorIfGuidsFileAndAppNameSet(guidsFile string, stdin bool, appGuid, appName, appsFile string) error {
+	if guidsFile != "" && (stdin || appGuid != "" || appName != "" || appsFile != "") {
+		return SpecifyGuidsFileOrAppNameError
+	}
+	return nil
+}
+
+var SpecifySpaceWithGuidError = errors.New("Cannot specify --space together with --guid, --guids-file or --stdin.")
+
+func ErrorIfSpaceAndGuidSet(spaceName string, stdin bool, appGuid string) error {
+	if spaceName != "" && (stdin || appGuid != "") {
+		return SpecifySpaceWithGuidError
+	}
+	return nil
+}
+
+var SpecifyOutputWithOneAppError = errors.New("Cannot specify --output json together with --apps-file, --guid, --guids-file, --stdin or --dry-run.")
+
+func ErrorIfOutputAndManyAppsSet(output string, appsFile, appGuid string, stdin, dryRun bool) error {
+	if output == "json" && (appsFile != "" || appGuid != "" || stdin || dryRun) {
+		return SpecifyOutputWithOneAppError
+	}
+	return nil
+}
+
+var SpecifyRestartWithOneAppNameError = errors.New("Cannot specify --restart together with --guid, --guids-file, --stdin, --space or --output json.")
+
+// ErrorIfRestartAndNoAppNameSet refuses --restart for apps that cf restart
+// cannot find by name in the targeted space, and for JSON output, which
+// the output of cf restart would mix with.
+func ErrorIfRestartAndNoAppNameSet(restart bool, appGuid string, stdin bool, spaceName, output string) error {
+	if restart && (appGuid != "" || stdin || spaceName != "" || output == "json") {
+		return SpecifyRestartWithOneAppNameError
+	}
+	return nil
+}
+
+// ExitStatus makes the plugin exit with Code without reporting a failure,
+// for commands whose exit status is part of their output.
+type ExitStatus struct {
+	Code int
+}
+
+func (e ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// PartialFailureExitCode is the exit status of a batch command when some
+// of its apps failed and the others succeeded.
+const PartialFailureExitCode = 2
+
+// VerificationFailureExitCode is the exit status when the Cloud Controller
+// accepted the Diego flag but reading it back showed the old value, as
+// opposed to 1 for a request to set it that failed.
+const VerificationFailureExitCode = 3
+
+// ExitCode is the exit status for err: its own, for errors that have an
+// ExitCode method, and 1 otherwise.
+func ExitCode(err error) int {
+	if coder, ok := err.(interface {
+		ExitCode() int
+	}); ok {
+		return coder.ExitCode()
+	}
+	return 1
+}
+
+// BatchFailure reports that Failed of the Attempted apps in a batch
+// failed, so that the exit status can tell a partial failure from a total
+// one. Unverified counts the failed apps whose flag was set but could not
+// be verified.
+type BatchFailure struct {
+	Message    string
+	Failed     int
+	Attempted  int
+	Unverified int
+}
+
+func (e BatchFailure) Error() string {
+	return e.Message
+}
+
+func (e BatchFailure) ExitCode() int {
+	switch {
+	case e.Failed < e.Attempted:
+		return PartialFailureExitCode
+	case e.Failed > 0 && e.Unverified == e.Failed:
+		return VerificationFailureExitCode
+	}
+	return 1
+}
+
+// SpaceDeveloperRequiredError replaces the 403 the Cloud Controller returns
+// for changing the Diego flag of an app without the SpaceDeveloper role.
+// SpaceName is empty when the space of the app is not known.
+type SpaceDeveloperRequiredError struct {
+	SpaceName string
+}
+
+func (e SpaceDeveloperRequiredError) Error() string {
+	if e.SpaceName == "" {
+		return "You need the SpaceDeveloper role in the space of this app to change Diego for it"
+	}
+	return fmt.Sprintf("You need the SpaceDeveloper role in space %s to change Diego for this app", e.SpaceName)
+}
@@ -0,0 +1,104 @@
+package errorhelpers_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BatchFailure", func() {
+	It("exits 2 when only some apps failed", func() {
+		Expect(BatchFailure{Failed: 1, Attempted: 3}.ExitCode()).To(Equal(PartialFailureExitCode))
+	})
+
+	It("exits 1 when every app failed", func() {
+		Expect(BatchFailure{Failed: 3, Attempted: 3}.ExitCode()).To(Equal(1))
+		Expect(BatchFailure{Failed: 3, Attempted: 3, Unverified: 2}.ExitCode()).To(Equal(1))
+	})
+
+	It("exits 3 when every app failed only verification", func() {
+		Expect(BatchFailure{Failed: 3, Attempted: 3, Unverified: 3}.ExitCode()).To(Equal(VerificationFailureExitCode))
+	})
+})
+
+var _ = Describe("ExitCode", func() {
+	It("uses the exit code of errors that have one", func() {
+		Expect(ExitCode(BatchFailure{Failed: 1, Attempted: 3})).To(Equal(PartialFailureExitCode))
+	})
+
+	It("exits 1 for other errors", func() {
+		Expect(ExitCode(SpecifyOrgOrSpaceError)).To(Equal(1))
+	})
+})
+
+var _ = Describe("ErrorIfSpaceAndGuidSet", func() {
+	It("allows --space with app names", func() {
+		Expect(ErrorIfSpaceAndGuidSet("dev", false, "")).To(Succeed())
+	})
+
+	It("refuses --space with --guid or --stdin", func() {
+		Expect(ErrorIfSpaceAndGuidSet("dev", false, "some-guid")).To(Equal(SpecifySpaceWithGuidError))
+		Expect(ErrorIfSpaceAndGuidSet("dev", true, "")).To(Equal(SpecifySpaceWithGuidError))
+	})
+})
+
+var _ = Describe("ErrorIfOutputAndManyAppsSet", func() {
+	It("allows --output json with a single app name", func() {
+		Expect(ErrorIfOutputAndManyAppsSet("json", "", "", false, false)).To(Succeed())
+		Expect(ErrorIfOutputAndManyAppsSet("text", "apps.txt", "", false, true)).To(Succeed())
+	})
+
+	It("refuses --output json with anything but an app name", func() {
+		Expect(ErrorIfOutputAndManyAppsSet("json", "apps.txt", "", false, false)).To(Equal(SpecifyOutputWithOneAppError))
+		Expect(ErrorIfOutputAndManyAppsSet("json", "", "some-guid", false, false)).To(Equal(SpecifyOutputWithOneAppError))
+		Expect(ErrorIfOutputAndManyAppsSet("json", "", "", true, false)).To(Equal(SpecifyOutputWithOneAppError))
+		Expect(ErrorIfOutputAndManyAppsSet("json", "", "", false, true)).To(Equal(SpecifyOutputWithOneAppError))
+	})
+})
+
+var _ = Describe("ErrorIfStdinAndAppNameSet", func() {
+	It("allows --stdin on its own", func() {
+		Expect(ErrorIfStdinAndAppNameSet(true, "", "", "")).To(Succeed())
+	})
+
+	It("refuses --stdin with an app name, apps file or guid", func() {
+		Expect(ErrorIfStdinAndAppNameSet(true, "", "some-app", "")).To(Equal(SpecifyStdinOrAppNameError))
+		Expect(ErrorIfStdinAndAppNameSet(true, "", "", "apps.txt")).To(Equal(SpecifyStdinOrAppNameError))
+		Expect(ErrorIfStdinAndAppNameSet(true, "some-guid", "", "")).To(Equal(SpecifyStdinOrAppNameError))
+	})
+})
+
+var _ = Describe("ErrorIfGuidsFileAndAppNameSet", func() {
+	It("allows --guids-file on its own", func() {
+		Expect(ErrorIfGuidsFileAndAppNameSet("guids.txt", false, "", "", "")).To(Succeed())
+	})
+
+	It("refuses --guids-file with any other way of naming apps", func() {
+		Expect(ErrorIfGuidsFileAndAppNameSet("guids.txt", true, "", "", "")).To(Equal(SpecifyGuidsFileOrAppNameError))
+		Expect(ErrorIfGuidsFileAndAppNameSet("guids.txt", false, "some-guid", "", "")).To(Equal(SpecifyGuidsFileOrAppNameError))
+		Expect(ErrorIfGuidsFileAndAppNameSet("guids.txt", false, "", "some-app", "")).To(Equal(SpecifyGuidsFileOrAppNameError))
+		Expect(ErrorIfGuidsFileAndAppNameSet("guids.txt", false, "", "", "apps.txt")).To(Equal(SpecifyGuidsFileOrAppNameError))
+	})
+})
+
+var _ = Describe("ErrorIfRestartAndNoAppNameSet", func() {
+	It("allows --restart with app names", func() {
+		Expect(ErrorIfRestartAndNoAppNameSet(true, "", false, "", "text")).To(Succeed())
+		Expect(ErrorIfRestartAndNoAppNameSet(false, "some-guid", true, "dev", "json")).To(Succeed())
+	})
+
+	It("refuses --restart with --guid, --stdin, --space or --output json", func() {
+		Expect(ErrorIfRestartAndNoAppNameSet(true, "some-guid", false, "", "text")).To(Equal(SpecifyRestartWithOneAppNameError))
+		Expect(ErrorIfRestartAndNoAppNameSet(true, "", true, "", "text")).To(Equal(SpecifyRestartWithOneAppNameError))
+		Expect(ErrorIfRestartAndNoAppNameSet(true, "", false, "dev", "text")).To(Equal(SpecifyRestartWithOneAppNameError))
+		Expect(ErrorIfRestartAndNoAppNameSet(true, "", false, "", "json")).To(Equal(SpecifyRestartWithOneAppNameError))
+	})
+})
+
+var _ = Describe("SpaceDeveloperRequiredError", func() {
+	It("names the space when it is known", func() {
+		Expect(SpaceDeveloperRequiredError{SpaceName: "dev"}.Error()).To(Equal("You need the SpaceDeveloper role in space dev to change Diego for this app"))
+		Expect(SpaceDeveloperRequiredError{}.Error()).To(Equal("You need the SpaceDeveloper role in the space of this app to change Diego for it"))
+	})
+})
@@ -0,0 +1,41 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"net/url"
+)
+
+type ApiEndpointFlag struct {
+	URL *url.URL
+}
+
+func (flag *ApiEndpointFlag) UnmarshalFlag(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return InvalidApiEndpointError{PassedValue: value}
+	}
+
+	flag.URL = u
+	return nil
+}
+
+// Endpoint is the URL given with the flag, or nil if it was not given.
+// go-flags allocates a url.URL for URL even then, so it is not enough to
+// check URL against nil.
+func (flag ApiEndpointFlag) Endpoint() *url.URL {
+	if flag.URL == nil || flag.URL.Host == "" {
+		return nil
+	}
+	return flag.URL
+}
+
+type InvalidApiEndpointError struct {
+	PassedValue string
+}
+
+func (e InvalidApiEndpointError) Error() string {
+	return fmt.Sprintf(
+		"Invalid API endpoint: %s\nValue for URL must be an http or https URL such as https://api.example.com",
+		e.PassedValue,
+	)
+}
@@ -0,0 +1,39 @@
+package flaghelpers_test
+
+import (
+	"net/url"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApiEndpointFlag", func() {
+	var apiEndpointFlag ApiEndpointFlag
+	BeforeEach(func() {
+		apiEndpointFlag = ApiEndpointFlag{}
+	})
+
+	It("accepts an https URL", func() {
+		Expect(apiEndpointFlag.UnmarshalFlag("https://api.example.com")).ToNot(HaveOccurred())
+		Expect(apiEndpointFlag.URL.String()).To(Equal("https://api.example.com"))
+		Expect(apiEndpointFlag.Endpoint()).To(Equal(apiEndpointFlag.URL))
+	})
+
+	It("has no endpoint when the flag is not given", func() {
+		Expect(apiEndpointFlag.Endpoint()).To(BeNil())
+		apiEndpointFlag.URL = &url.URL{User: &url.Userinfo{}}
+		Expect(apiEndpointFlag.Endpoint()).To(BeNil())
+	})
+
+	It("returns an error for a URL without a scheme", func() {
+		err := apiEndpointFlag.UnmarshalFlag("api.example.com")
+		Expect(err).To(Equal(InvalidApiEndpointError{PassedValue: "api.example.com"}))
+	})
+
+	It("returns an error for a URL with another scheme", func() {
+		err := apiEndpointFlag.UnmarshalFlag("ftp://api.example.com")
+		Expect(err).To(Equal(InvalidApiEndpointError{PassedValue: "ftp://api.example.com"}))
+	})
+})
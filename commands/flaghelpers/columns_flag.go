@@ -0,0 +1,47 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type ColumnsFlag struct {
+	Columns []string
+}
+
+func (flag *ColumnsFlag) UnmarshalFlag(value string) error {
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if !isTableColumn(column) {
+			return InvalidColumnValueError{PassedValue: column}
+		}
+		columns = append(columns, column)
+	}
+
+	flag.Columns = columns
+	return nil
+}
+
+func isTableColumn(column string) bool {
+	for _, known := range ui.TableColumns {
+		if column == known {
+			return true
+		}
+	}
+	return false
+}
+
+type InvalidColumnValueError struct {
+	PassedValue string
+}
+
+func (e InvalidColumnValueError) Error() string {
+	return fmt.Sprintf(
+		"Invalid column: %s\nValue for COLUMNS must be a comma-separated list of: %s",
+		e.PassedValue,
+		strings.Join(ui.TableColumns, ", "),
+	)
+}
@@ -0,0 +1,33 @@
+package flaghelpers_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ColumnsFlag", func() {
+	var columnsFlag ColumnsFlag
+	BeforeEach(func() {
+		columnsFlag = ColumnsFlag{}
+	})
+
+	It("accepts a comma-separated list of known columns", func() {
+		Expect(columnsFlag.UnmarshalFlag("name, org")).ToNot(HaveOccurred())
+		Expect(columnsFlag.Columns).To(Equal([]string{"name", "org"}))
+	})
+
+	Describe("unknown columns", func() {
+		It("returns an error listing the valid columns", func() {
+			err := columnsFlag.UnmarshalFlag("name,banana")
+			Expect(err).To(Equal(InvalidColumnValueError{PassedValue: "banana"}))
+			Expect(err.Error()).To(ContainSubstring("name, guid, space, org, state, instances, memory, stack, runtime"))
+		})
+
+		It("rejects an empty column", func() {
+			err := columnsFlag.UnmarshalFlag("name,")
+			Expect(err).To(Equal(InvalidColumnValueError{PassedValue: ""}))
+		})
+	})
+})
@@ -0,0 +1,47 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+)
+
+type OrderByFlag struct {
+	Field      string
+	Descending bool
+}
+
+func (flag *OrderByFlag) UnmarshalFlag(value string) error {
+	field, direction := value, ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		field, direction = value[:i], value[i+1:]
+	}
+
+	if field != api.OrderByName {
+		return InvalidOrderByValueError{PassedValue: value}
+	}
+
+	switch direction {
+	case "", "asc":
+		flag.Descending = false
+	case "desc":
+		flag.Descending = true
+	default:
+		return InvalidOrderByValueError{PassedValue: value}
+	}
+
+	flag.Field = field
+	return nil
+}
+
+type InvalidOrderByValueError struct {
+	PassedValue string
+}
+
+func (e InvalidOrderByValueError) Error() string {
+	return fmt.Sprintf(
+		"Invalid order: %s\nThe Cloud Controller can only order apps by name, optionally followed by :asc or :desc",
+		e.PassedValue,
+	)
+}
@@ -0,0 +1,47 @@
+package flaghelpers_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrderByFlag", func() {
+	var orderByFlag OrderByFlag
+	BeforeEach(func() {
+		orderByFlag = OrderByFlag{}
+	})
+
+	Describe("valid values", func() {
+		It("orders ascending by default", func() {
+			Expect(orderByFlag.UnmarshalFlag("name")).ToNot(HaveOccurred())
+			Expect(orderByFlag.Field).To(Equal("name"))
+			Expect(orderByFlag.Descending).To(BeFalse())
+		})
+
+		It("accepts a descending direction", func() {
+			Expect(orderByFlag.UnmarshalFlag("name:desc")).ToNot(HaveOccurred())
+			Expect(orderByFlag.Field).To(Equal("name"))
+			Expect(orderByFlag.Descending).To(BeTrue())
+		})
+	})
+
+	Describe("invalid values", func() {
+		Describe("fields the Cloud Controller cannot order by", func() {
+			It("returns an error", func() {
+				err := orderByFlag.UnmarshalFlag("space")
+				_, ok := err.(InvalidOrderByValueError)
+				Expect(ok).To(BeTrue())
+			})
+		})
+
+		Describe("unknown directions", func() {
+			It("returns an error", func() {
+				err := orderByFlag.UnmarshalFlag("name:sideways")
+				_, ok := err.(InvalidOrderByValueError)
+				Expect(ok).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,38 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MaxPageSize is the largest results-per-page the Cloud Controller accepts.
+const MaxPageSize = 100
+
+type PageSizeFlag struct {
+	Value int
+}
+
+func (flag *PageSizeFlag) UnmarshalFlag(value string) error {
+	val, _ := strconv.Atoi(value)
+	if val <= 0 {
+		return InvalidPageSizeValueError{PassedValue: value}
+	}
+
+	if val > MaxPageSize {
+		val = MaxPageSize
+	}
+
+	flag.Value = val
+	return nil
+}
+
+type InvalidPageSizeValueError struct {
+	PassedValue string
+}
+
+func (e InvalidPageSizeValueError) Error() string {
+	return fmt.Sprintf(
+		"Invalid page size: %s\nValue for N must be a positive integer",
+		e.PassedValue,
+	)
+}
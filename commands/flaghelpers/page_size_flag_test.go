@@ -0,0 +1,43 @@
+package flaghelpers_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PageSizeFlag", func() {
+	var pageSizeFlag PageSizeFlag
+	BeforeEach(func() {
+		pageSizeFlag = PageSizeFlag{}
+	})
+
+	Describe("positive values", func() {
+		Context("value is less than or equal to 100", func() {
+			It("does not error", func() {
+				Expect(pageSizeFlag.UnmarshalFlag("50")).ToNot(HaveOccurred())
+				Expect(pageSizeFlag.Value).To(Equal(50))
+			})
+		})
+
+		Context("value is greater than 100", func() {
+			It("caps it at 100", func() {
+				Expect(pageSizeFlag.UnmarshalFlag("500")).ToNot(HaveOccurred())
+				Expect(pageSizeFlag.Value).To(Equal(MaxPageSize))
+			})
+		})
+	})
+
+	Describe("non-positive values", func() {
+		It("returns an error for zero", func() {
+			err := pageSizeFlag.UnmarshalFlag("0")
+			Expect(err).To(Equal(InvalidPageSizeValueError{PassedValue: "0"}))
+		})
+
+		It("returns an error for non-number values", func() {
+			err := pageSizeFlag.UnmarshalFlag("banana")
+			Expect(err).To(Equal(InvalidPageSizeValueError{PassedValue: "banana"}))
+		})
+	})
+})
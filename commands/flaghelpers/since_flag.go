@@ -0,0 +1,31 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"time"
+)
+
+type SinceFlag struct {
+	Time time.Time
+}
+
+func (flag *SinceFlag) UnmarshalFlag(value string) error {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return InvalidSinceValueError{PassedValue: value}
+	}
+
+	flag.Time = t
+	return nil
+}
+
+type InvalidSinceValueError struct {
+	PassedValue string
+}
+
+func (e InvalidSinceValueError) Error() string {
+	return fmt.Sprintf(
+		"Invalid time: %s\nValue for TIME must be an RFC3339 time such as 2016-03-16T16:40:43Z",
+		e.PassedValue,
+	)
+}
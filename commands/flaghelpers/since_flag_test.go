@@ -0,0 +1,27 @@
+package flaghelpers_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SinceFlag", func() {
+	var sinceFlag SinceFlag
+	BeforeEach(func() {
+		sinceFlag = SinceFlag{}
+	})
+
+	It("parses an RFC3339 time", func() {
+		Expect(sinceFlag.UnmarshalFlag("2016-03-16T18:40:43+02:00")).ToNot(HaveOccurred())
+		Expect(sinceFlag.Time.Equal(time.Date(2016, 3, 16, 16, 40, 43, 0, time.UTC))).To(BeTrue())
+	})
+
+	It("returns an error for a date without a time", func() {
+		err := sinceFlag.UnmarshalFlag("2016-03-16")
+		Expect(err).To(Equal(InvalidSinceValueError{PassedValue: "2016-03-16"}))
+	})
+})
@@ -0,0 +1,49 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type SortFlag struct {
+	Field      string
+	Descending bool
+}
+
+func (flag *SortFlag) UnmarshalFlag(value string) error {
+	field, direction := value, ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		field, direction = value[:i], value[i+1:]
+	}
+
+	switch field {
+	case ui.SortByName, ui.SortBySpace, ui.SortByOrg:
+	default:
+		return InvalidSortValueError{PassedValue: value}
+	}
+
+	switch direction {
+	case "", "asc":
+		flag.Descending = false
+	case "desc":
+		flag.Descending = true
+	default:
+		return InvalidSortValueError{PassedValue: value}
+	}
+
+	flag.Field = field
+	return nil
+}
+
+type InvalidSortValueError struct {
+	PassedValue string
+}
+
+func (e InvalidSortValueError) Error() string {
+	return fmt.Sprintf(
+		"Invalid sort order: %s\nValue for SORT must be one of name, space or org, optionally followed by :asc or :desc",
+		e.PassedValue,
+	)
+}
@@ -0,0 +1,53 @@
+package flaghelpers_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SortFlag", func() {
+	var sortFlag SortFlag
+	BeforeEach(func() {
+		sortFlag = SortFlag{}
+	})
+
+	Describe("valid values", func() {
+		It("sorts ascending by default", func() {
+			Expect(sortFlag.UnmarshalFlag("name")).ToNot(HaveOccurred())
+			Expect(sortFlag.Field).To(Equal("name"))
+			Expect(sortFlag.Descending).To(BeFalse())
+		})
+
+		It("accepts an explicit ascending direction", func() {
+			Expect(sortFlag.UnmarshalFlag("space:asc")).ToNot(HaveOccurred())
+			Expect(sortFlag.Field).To(Equal("space"))
+			Expect(sortFlag.Descending).To(BeFalse())
+		})
+
+		It("accepts a descending direction", func() {
+			Expect(sortFlag.UnmarshalFlag("org:desc")).ToNot(HaveOccurred())
+			Expect(sortFlag.Field).To(Equal("org"))
+			Expect(sortFlag.Descending).To(BeTrue())
+		})
+	})
+
+	Describe("invalid values", func() {
+		Describe("unknown fields", func() {
+			It("returns an error", func() {
+				err := sortFlag.UnmarshalFlag("guid")
+				_, ok := err.(InvalidSortValueError)
+				Expect(ok).To(BeTrue())
+			})
+		})
+
+		Describe("unknown directions", func() {
+			It("returns an error", func() {
+				err := sortFlag.UnmarshalFlag("name:sideways")
+				_, ok := err.(InvalidSortValueError)
+				Expect(ok).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package flaghelpers
+
+import (
+	"fmt"
+	"time"
+)
+
+type TimeoutFlag struct {
+	Value time.Duration
+}
+
+func (flag *TimeoutFlag) UnmarshalFlag(value string) error {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return InvalidTimeoutValueError{PassedValue: value}
+	}
+
+	flag.Value = timeout
+	return nil
+}
+
+type InvalidTimeoutValueError struct {
+	PassedValue string
+}
+
+func (e InvalidTimeoutValueError) Error() string {
+	return fmt.Sprintf(
+		"Invalid timeout: %s\nValue for DURATION must be a positive duration such as 30s or 2m",
+		e.PassedValue,
+	)
+}
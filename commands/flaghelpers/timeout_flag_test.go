@@ -0,0 +1,31 @@
+package flaghelpers_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TimeoutFlag", func() {
+	var timeoutFlag TimeoutFlag
+	BeforeEach(func() {
+		timeoutFlag = TimeoutFlag{}
+	})
+
+	It("parses a duration", func() {
+		Expect(timeoutFlag.UnmarshalFlag("90s")).To(Succeed())
+		Expect(timeoutFlag.Value).To(Equal(90 * time.Second))
+	})
+
+	It("rejects durations that are not positive", func() {
+		Expect(timeoutFlag.UnmarshalFlag("0s")).To(Equal(InvalidTimeoutValueError{PassedValue: "0s"}))
+		Expect(timeoutFlag.UnmarshalFlag("-1m")).To(Equal(InvalidTimeoutValueError{PassedValue: "-1m"}))
+	})
+
+	It("rejects values that are not durations", func() {
+		Expect(timeoutFlag.UnmarshalFlag("banana")).To(Equal(InvalidTimeoutValueError{PassedValue: "banana"}))
+	})
+})
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+type HasDeaEnabledCommand struct {
+	Output          string                      `long:"output" value-name:"FORMAT" choice:"text" choice:"json" default:"text" description:"Output format: text or json"`
+	ShowLocation    bool                        `long:"show-location" description:"Also print the org and space of each app"`
+	RequiredOptions HasDeaEnabledPositionalArgs `positional-args:"yes"`
+}
+
+type HasDeaEnabledPositionalArgs struct {
+	AppNames []string `positional-arg-name:"APP_NAME" description:"The app names"`
+}
+
+func (command HasDeaEnabledCommand) Execute([]string) error {
+	ui.UseOutputFormat(command.Output)
+
+	appNames := command.RequiredOptions.AppNames
+	if len(appNames) == 0 {
+		return errorhelpers.AppNameRequiredError
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	switch len(appNames) {
+	case 1:
+		return diegohelpers.IsDeaEnabled(ctx, DiegoEnabler.CLIConnection, appNames[0], command.checkOptions())
+	default:
+		return diegohelpers.IsDeaEnabledForApps(ctx, DiegoEnabler.CLIConnection, appNames, command.checkOptions())
+	}
+}
+
+func (command HasDeaEnabledCommand) checkOptions() diegohelpers.CheckOptions {
+	return diegohelpers.CheckOptions{
+		Output:       command.Output,
+		ShowLocation: command.ShowLocation,
+	}
+}
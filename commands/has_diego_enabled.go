@@ -1,15 +1,43 @@
 package commands
 
-import "github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+import (
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
 
 type HasDiegoEnabledCommand struct {
+	Output          string                        `long:"output" value-name:"FORMAT" choice:"text" choice:"json" default:"text" description:"Output format: text or json"`
+	ShowLocation    bool                          `long:"show-location" description:"Also print the org and space of each app"`
 	RequiredOptions HasDiegoEnabledPositionalArgs `positional-args:"yes"`
 }
 
 type HasDiegoEnabledPositionalArgs struct {
-	AppName string `positional-arg-name:"APP_NAME" required:"true" description:"The app name"`
+	AppNames []string `positional-arg-name:"APP_NAME" description:"The app names"`
 }
 
 func (command HasDiegoEnabledCommand) Execute([]string) error {
-	return diegohelpers.IsDiegoEnabled(DiegoEnabler.CLIConnection, command.RequiredOptions.AppName)
+	ui.UseOutputFormat(command.Output)
+
+	appNames := command.RequiredOptions.AppNames
+	if len(appNames) == 0 {
+		return errorhelpers.AppNameRequiredError
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	switch len(appNames) {
+	case 1:
+		return diegohelpers.IsDiegoEnabled(ctx, DiegoEnabler.CLIConnection, appNames[0], command.checkOptions())
+	default:
+		return diegohelpers.IsDiegoEnabledForApps(ctx, DiegoEnabler.CLIConnection, appNames, command.checkOptions())
+	}
+}
+
+func (command HasDiegoEnabledCommand) checkOptions() diegohelpers.CheckOptions {
+	return diegohelpers.CheckOptions{
+		Output:       command.Output,
+		ShowLocation: command.ShowLocation,
+	}
 }
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// interruptContext returns a context that is cancelled on the first
+// Ctrl-C, so requests in flight are aborted and bulk commands stop
+// starting new switches. The handler is removed once it fires, so a second
+// Ctrl-C kills the plugin straight away. Call stop once the command is
+// done.
+func interruptContext() (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
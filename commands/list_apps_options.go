@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/listhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/ui"
+)
+
+// ListAppsOptions holds the flags shared by the app listing commands.
+type ListAppsOptions struct {
+	Organization      string                      `short:"o" long:"org" value-name:"ORG" description:"Organization to limit results to"`
+	Space             string                      `short:"s" long:"space" value-name:"SPACE" description:"Space to limit results to, in the targeted organization unless one is given with --org"`
+	Output            string                      `long:"output" value-name:"FORMAT" choice:"table" choice:"json" choice:"csv" choice:"ndjson" description:"Output format for the app list: table, json, csv or ndjson; defaults to CF_DIEGO_OUTPUT, or else table"`
+	Sort              flaghelpers.SortFlag        `long:"sort" value-name:"SORT" description:"Sort the app list by name, space or org; append :desc to reverse the order"`
+	Count             bool                        `long:"count" description:"Only print the number of apps found"`
+	Guids             bool                        `long:"guids" description:"Only print the guid of each app, one per line"`
+	PageSize          flaghelpers.PageSizeFlag    `long:"page-size" value-name:"N" description:"Number of apps to request from the Cloud Controller at a time, at most 100"`
+	Columns           flaghelpers.ColumnsFlag     `long:"columns" value-name:"COLUMNS" description:"Comma-separated table columns to show: name, guid, space, org, state, instances, memory, stack, runtime, buildpack, docker_image"`
+	NameFilter        string                      `long:"name-filter" value-name:"PATTERN" description:"Only list apps whose name matches a shell pattern such as 'web-*'"`
+	ExcludeNameFilter string                      `long:"exclude-name-filter" value-name:"PATTERN" description:"Leave out apps whose name matches a shell pattern such as 'apps-manager*'"`
+	HideInaccessible  bool                        `long:"hide-inaccessible" description:"Leave out apps in spaces you cannot see"`
+	Strict            bool                        `long:"strict" description:"Fail instead of listing apps in spaces you cannot see"`
+	ModifiableOnly    bool                        `long:"modifiable-only" description:"Only list apps in spaces where you are a SpaceDeveloper, which you can enable or disable Diego support for"`
+	Since             flaghelpers.SinceFlag       `long:"since" value-name:"TIME" description:"Only list apps updated after an RFC3339 time such as 2016-03-16T16:40:43Z"`
+	Stopped           bool                        `long:"stopped" description:"Only list stopped apps"`
+	Started           bool                        `long:"started" description:"Only list started apps"`
+	Migratable        bool                        `long:"migratable" description:"Leave out apps whose package failed to stage, which cannot be migrated"`
+	Limit             flaghelpers.PageSizeFlag    `long:"limit" value-name:"N" description:"Only list a chunk of N apps, at most 100; combine with --offset to page through them"`
+	Offset            int                         `long:"offset" value-name:"M" description:"Skip the first M apps, a multiple of --limit, in the Cloud Controller's order"`
+	Api               flaghelpers.ApiEndpointFlag `long:"api" value-name:"URL" description:"Cloud Controller to list apps from instead of the targeted one; it must accept the current access token"`
+	OrderBy           flaghelpers.OrderByFlag     `long:"order-by" value-name:"FIELD" description:"Have the Cloud Controller return apps ordered by name; append :desc to reverse the order"`
+	NoTruncate        bool                        `long:"no-truncate" description:"Do not truncate long names to fit the table in the terminal"`
+	Refresh           bool                        `long:"refresh" description:"Fetch the spaces even if they are cached"`
+	GroupBy           string                      `long:"group-by" value-name:"FIELD" choice:"org" description:"Print a separate table for each org"`
+	OutFile           string                      `long:"out-file" value-name:"PATH" description:"Write the app list to a file instead of stdout, replacing the file if it exists"`
+	ApiVersion        string                      `long:"api-version" value-name:"VERSION" choice:"v2" choice:"v3" default:"v2" description:"Cloud Controller API version to list apps with: v2 or v3"`
+	ResolveSpaces     string                      `long:"resolve-spaces" value-name:"MODE" choice:"all" choice:"lazy" default:"all" description:"Fetch every space to name the apps' spaces and orgs, or only the spaces of the listed apps: all or lazy"`
+}
+
+func (options ListAppsOptions) listApps(runtime ui.Runtime) error {
+	cliConnection := DiegoEnabler.CLIConnection
+
+	if options.Api.Endpoint() != nil && (options.Organization != "" || options.Space != "") {
+		return ApiWithOrgOrSpaceError{}
+	}
+
+	if options.Guids && options.Count {
+		return GuidsWithCountError{}
+	}
+
+	if options.Strict && options.HideInaccessible {
+		return StrictWithHideInaccessibleError{}
+	}
+
+	if options.Stopped && options.Started {
+		return StoppedWithStartedError{}
+	}
+
+	err := options.validateChunk()
+	if err != nil {
+		return err
+	}
+
+	output, err := options.output()
+	if err != nil {
+		return err
+	}
+	ui.UseOutputFormat(output)
+
+	appsGetter, err := diegohelpers.NewAppsGetter(cliConnection, options.Organization, options.Space)
+	if err != nil {
+		return err
+	}
+	appsStreamer := diegohelpers.NewAppsStreamerFunc(appsGetter, runtime)
+
+	listAppsCommand, err := listhelpers.NewListAppsCommand(cliConnection, options.Organization, options.Space, runtime)
+	if err != nil {
+		return err
+	}
+	listAppsCommand.Output = output
+	listAppsCommand.Count = options.Count
+	listAppsCommand.Guids = options.Guids
+	listAppsCommand.SortField = options.Sort.Field
+	listAppsCommand.SortDescending = options.Sort.Descending
+	listAppsCommand.Columns = options.Columns.Columns
+	listAppsCommand.GroupBy = options.GroupBy
+	if !options.NoTruncate {
+		listAppsCommand.Width = ui.TerminalWidth()
+	}
+
+	fetchOptions := listhelpers.FetchOptions{
+		PageSize:          options.PageSize.Value,
+		Limit:             options.Limit.Value,
+		Offset:            options.Offset,
+		NameFilter:        options.NameFilter,
+		ExcludeNameFilter: options.ExcludeNameFilter,
+		Since:             options.Since.Time,
+		State:             options.state(),
+		Migratable:        options.Migratable,
+		HideInaccessible:  options.HideInaccessible,
+		Strict:            options.Strict,
+		ModifiableOnly:    options.ModifiableOnly,
+		Api:               options.Api.Endpoint(),
+		OrderBy:           options.OrderBy.Field,
+		OrderDescending:   options.OrderBy.Descending,
+		Refresh:           options.Refresh,
+		ApiVersion:        options.ApiVersion,
+		ResolveSpaces:     options.ResolveSpaces,
+		OrganizationGuid:  appsGetter.OrganizationGuid,
+	}
+
+	if options.OutFile != "" {
+		file, err := os.Create(options.OutFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		listAppsCommand.SetOut(file)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	err = listhelpers.ListApps(ctx, cliConnection, appsStreamer, &listAppsCommand, fetchOptions)
+	if err != nil || options.OutFile == "" {
+		return err
+	}
+
+	listAppsCommand.AfterWriteFile(options.OutFile)
+	return nil
+}
+
+// ApiWithOrgOrSpaceError is returned for --api with --org or --space,
+// because orgs and spaces are looked up on the targeted API endpoint.
+type ApiWithOrgOrSpaceError struct{}
+
+func (ApiWithOrgOrSpaceError) Error() string {
+	return "--api cannot be combined with --org or --space"
+}
+
+type GuidsWithCountError struct{}
+
+func (GuidsWithCountError) Error() string {
+	return "--guids cannot be combined with --count"
+}
+
+type StrictWithHideInaccessibleError struct{}
+
+func (StrictWithHideInaccessibleError) Error() string {
+	return "--strict cannot be combined with --hide-inaccessible"
+}
+
+type StoppedWithStartedError struct{}
+
+func (StoppedWithStartedError) Error() string {
+	return "--stopped cannot be combined with --started"
+}
+
+// state is the app state picked with --stopped or --started, if any.
+func (options ListAppsOptions) state() string {
+	switch {
+	case options.Stopped:
+		return "STOPPED"
+	case options.Started:
+		return "STARTED"
+	}
+	return ""
+}
+
+// outputFormats are the formats --output and CF_DIEGO_OUTPUT accept.
+var outputFormats = []string{ui.TableOutput, ui.JSONOutput, ui.CSVOutput, ui.NDJSONOutput}
+
+type InvalidOutputEnvError struct {
+	PassedValue string
+}
+
+func (e InvalidOutputEnvError) Error() string {
+	return fmt.Sprintf("Invalid CF_DIEGO_OUTPUT %q: expected table, json, csv or ndjson", e.PassedValue)
+}
+
+// output is the format given with --output, or else with CF_DIEGO_OUTPUT,
+// or else a table.
+func (options ListAppsOptions) output() (string, error) {
+	if options.Output != "" {
+		return options.Output, nil
+	}
+
+	value := os.Getenv("CF_DIEGO_OUTPUT")
+	if value == "" {
+		return ui.TableOutput, nil
+	}
+
+	for _, format := range outputFormats {
+		if value == format {
+			return value, nil
+		}
+	}
+	return "", InvalidOutputEnvError{PassedValue: value}
+}
+
+func (options ListAppsOptions) validateChunk() error {
+	limit := options.Limit.Value
+	switch {
+	case limit > 0 && options.PageSize.Value > 0:
+		return LimitWithPageSizeError{}
+	case options.Offset != 0 && limit == 0:
+		return OffsetWithoutLimitError{}
+	case options.Offset < 0 || (limit > 0 && options.Offset%limit != 0):
+		return InvalidOffsetError{Offset: options.Offset, Limit: limit}
+	}
+	return nil
+}
+
+type LimitWithPageSizeError struct{}
+
+func (LimitWithPageSizeError) Error() string {
+	return "--limit cannot be combined with --page-size"
+}
+
+type OffsetWithoutLimitError struct{}
+
+func (OffsetWithoutLimitError) Error() string {
+	return "--offset requires --limit"
+}
+
+type InvalidOffsetError struct {
+	Offset int
+	Limit  int
+}
+
+func (e InvalidOffsetError) Error() string {
+	return fmt.Sprintf("Invalid offset: %d\nValue for M must be a non-negative multiple of --limit (%d)", e.Offset, e.Limit)
+}
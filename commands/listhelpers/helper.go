@@ -1,86 +1,577 @@
 package listhelpers
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry-incubator/diego-enabler/spacecache"
 	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
 	"github.com/cloudfoundry-incubator/diego-enabler/ui"
 	"github.com/cloudfoundry/cli/cf/terminal"
 	"github.com/cloudfoundry/cli/cf/trace"
 )
 
-func ListApps(cliConnection api.Connection, appsGetterFunc thingdoer.AppsGetterFunc, listAppsCommand *ui.ListAppsCommand) error {
+// FetchOptions control how apps are fetched before they are listed.
+type FetchOptions struct {
+	PageSize int
+
+	// Limit and Offset fetch a single chunk of Limit apps, starting at
+	// Offset, instead of every app. Offset must be a multiple of Limit.
+	Limit  int
+	Offset int
+
+	// NameFilter is a path.Match pattern that app names must match, and
+	// ExcludeNameFilter one that they must not.
+	NameFilter        string
+	ExcludeNameFilter string
+
+	// Since, when set, drops apps not updated after it.
+	Since time.Time
+
+	// State, when set, drops apps in any other state, such as STOPPED.
+	State string
+
+	// Migratable drops apps whose package failed to stage, since they
+	// cannot be restaged on the other runtime.
+	Migratable bool
+
+	// HideInaccessible drops apps in spaces the user cannot see.
+	HideInaccessible bool
+
+	// ModifiableOnly drops apps outside the spaces in which the user is a
+	// SpaceDeveloper, since those are the only apps the user can toggle.
+	ModifiableOnly bool
+
+	// Strict fails the listing when any app is in a space the user cannot
+	// see, rather than listing the app without its space and org.
+	Strict bool
+
+	// Api replaces the targeted API endpoint when it is set.
+	Api *url.URL
+
+	// OrderBy has the Cloud Controller return apps sorted by that field.
+	OrderBy         string
+	OrderDescending bool
+
+	// Refresh fetches the spaces even when they are cached.
+	Refresh bool
+
+	// ApiVersion is api.ApiV3 to list apps with the v3 API, which cannot
+	// filter them by runtime, so they are filtered once fetched.
+	ApiVersion string
+
+	// ResolveSpaces is ResolveSpacesLazy to fetch only the spaces of the
+	// listed apps, one at a time, instead of every space.
+	ResolveSpaces string
+
+	// OrganizationGuid, when set, is the org that every listed app is in,
+	// so that only its spaces are fetched.
+	OrganizationGuid string
+}
+
+const (
+	ResolveSpacesAll  = "all"
+	ResolveSpacesLazy = "lazy"
+)
+
+func ListApps(ctx context.Context, cliConnection api.Connection, appsStreamerFunc thingdoer.AppsStreamerFunc, listAppsCommand *ui.ListAppsCommand, options FetchOptions) error {
+	for _, pattern := range []string{options.NameFilter, options.ExcludeNameFilter} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return InvalidNameFilterError{Pattern: pattern}
+		}
+	}
+
 	listAppsCommand.BeforeAll()
 
-	appsParser := models.ApplicationsParser{}
-	spacesParser := models.SpacesParser{}
+	var appsParser thingdoer.ApplicationsParser = models.ApplicationsParser{}
+	stacksParser := models.StacksParser{}
 
 	apiClient, err := api.NewClient(cliConnection)
 	if err != nil {
 		return err
 	}
+	apiClient.ResultsPerPage = options.PageSize
+	if options.Api != nil {
+		apiClient.BaseUrl = options.Api
+	}
 
-	appRequestFactory := apiClient.HandleFiltersAndParameters(
-		apiClient.Authorize(apiClient.NewGetAppsRequest),
+	// only the apps are ordered and limited, spaces and stacks are just
+	// used to look up names
+	appClient := *apiClient
+	appClient.OrderBy = options.OrderBy
+	appClient.OrderDescending = options.OrderDescending
+	if options.Limit > 0 {
+		appClient.ResultsPerPage = options.Limit
+	}
+	appRequestFactory := appClient.HandleFiltersAndParameters(
+		appClient.Authorize(appClient.NewGetAppsRequest),
 	)
-
 	appPaginatedRequester, err := api.NewPaginatedRequester(cliConnection, appRequestFactory)
 	if err != nil {
 		return err
 	}
+	if options.ApiVersion == api.ApiV3 {
+		appsParser = models.V3ApplicationsParser{}
+		appPaginatedRequester.RequestFactory = appClient.HandleV3FiltersAndParameters(
+			appClient.Authorize(appClient.NewGetV3AppsRequest),
+		)
+		appPaginatedRequester.PageParser = api.V3PageParser{}
+	}
+	if !listAppsCommand.Guids {
+		appPaginatedRequester.Progress = ui.PageProgress("apps")
+		appPaginatedRequester.Totals = ui.FetchTotals("apps")
+	}
+	if options.Limit > 0 {
+		appPaginatedRequester.Page = options.Offset/options.Limit + 1
+	}
 
-	apps, err := appsGetterFunc(
-		appsParser,
-		appPaginatedRequester,
-	)
+	spaceSource, err := newSpaceSource(cliConnection, apiClient, listAppsCommand.Username, options.OrganizationGuid, options.Refresh)
 	if err != nil {
 		return err
 	}
 
-	spaceRequestFactory := apiClient.HandleFiltersAndParameters(
-		apiClient.Authorize(apiClient.NewGetSpacesRequest),
+	var spaceFetcher *api.SpaceFetcher
+	if options.ResolveSpaces == ResolveSpacesLazy {
+		spaceFetcher, err = api.NewSpaceFetcher(cliConnection, apiClient)
+		if err != nil {
+			return err
+		}
+	}
+
+	stackRequestFactory := apiClient.HandleFiltersAndParameters(
+		apiClient.Authorize(apiClient.NewGetStacksRequest),
 	)
-	spacesPaginatedRequester, err := api.NewPaginatedRequester(cliConnection, spaceRequestFactory)
+	stacksPaginatedRequester, err := api.NewPaginatedRequester(cliConnection, stackRequestFactory)
 	if err != nil {
 		return err
 	}
 
-	spaces, err := thingdoer.Spaces(
-		spacesParser,
-		spacesPaginatedRequester,
+	var (
+		apps            models.Applications
+		spaces          models.Spaces
+		spacesListed    = true
+		stacks          models.Stacks
+		developerSpaces models.Spaces
+
+		fetchAllApps, fetchSpaces, fetchDevSpaces func() error
+	)
+
+	fetchApps := func(handle thingdoer.AppsHandler) error {
+		return appsStreamerFunc(ctx, appsParser, appPaginatedRequester, handle)
+	}
+	// strict mode can only fail before any app is printed once every app
+	// is known to be accessible, so it does not stream
+	streaming := listAppsCommand.Streams() && !options.Strict
+
+	if !streaming {
+		// every app has to be fetched before any is printed, so they
+		// might as well be fetched alongside the spaces and stacks
+		fetchAllApps = func() error {
+			return fetchApps(func(page models.Applications) error {
+				apps = append(apps, page...)
+				return nil
+			})
+		}
+	}
+	if spaceFetcher == nil {
+		fetchSpaces = func() (err error) {
+			spaces, spacesListed, err = spaceSource.fetch(ctx)
+			return err
+		}
+	}
+	if options.ModifiableOnly {
+		fetchDevSpaces = func() (err error) {
+			developerSpaces, err = fetchDeveloperSpaces(ctx, cliConnection, spaceSource.requestFactory)
+			return err
+		}
+	}
+	err = fetchConcurrently(
+		fetchAllApps,
+		fetchSpaces,
+		func() (err error) {
+			stacks, err = thingdoer.Stacks(ctx, stacksParser, stacksPaginatedRequester)
+			return err
+		},
+		fetchDevSpaces,
 	)
 	if err != nil {
 		return err
 	}
 
+	lister := newAppLister(options, listAppsCommand.Runtime, spaces, stacks)
+	if !spacesListed {
+		// with no spaces to look them up in, every app is accessible
+		// and shown with its space guid
+		lister.spaces = nil
+	}
+	lister.spaceFetcher = spaceFetcher
+	if options.ModifiableOnly {
+		lister.modifiable = make(map[string]bool)
+		for _, space := range developerSpaces {
+			lister.modifiable[space.Guid] = true
+		}
+	}
+
+	if streaming {
+		err = fetchApps(func(page models.Applications) error {
+			appPrinters, err := lister.printers(ctx, page)
+			if err != nil {
+				return err
+			}
+			for _, appPrinter := range appPrinters {
+				if err := listAppsCommand.PrintStreamed(appPrinter); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(lister.inaccessible) > 0 {
+			return InaccessibleSpacesError{AppNames: lister.inaccessible}
+		}
+
+		listAppsCommand.AfterStream()
+		return nil
+	}
+
+	appPrinters, err := lister.printers(ctx, apps)
+	if err != nil {
+		return err
+	}
+	if len(lister.inaccessible) > 0 {
+		return InaccessibleSpacesError{AppNames: lister.inaccessible}
+	}
+
+	return listAppsCommand.AfterAll(appPrinters)
+}
+
+// appLister turns fetched apps into printers, leaving out the apps that
+// the fetch options filter out, and remembers the apps in spaces the user
+// cannot see for strict mode.
+type appLister struct {
+	options      FetchOptions
+	runtime      ui.Runtime
+	spaces       map[string]models.Space
+	stacks       map[string]models.Stack
+	inaccessible []string
+
+	// modifiable holds the guids of the spaces the user can change apps
+	// in, when only those apps are listed.
+	modifiable map[string]bool
+
+	// spaceFetcher, when set, fetches the spaces of the apps as they are
+	// listed, and resolved holds the guids of the spaces already fetched,
+	// including the ones the user cannot see.
+	spaceFetcher *api.SpaceFetcher
+	resolved     map[string]bool
+}
+
+func newAppLister(options FetchOptions, runtime ui.Runtime, spaces models.Spaces, stacks models.Stacks) *appLister {
 	spaceMap := make(map[string]models.Space)
 	for _, space := range spaces {
 		spaceMap[space.Guid] = space
 	}
 
+	stackMap := make(map[string]models.Stack)
+	for _, stack := range stacks {
+		stackMap[stack.Guid] = stack
+	}
+
+	return &appLister{options: options, runtime: runtime, spaces: spaceMap, stacks: stackMap, resolved: make(map[string]bool)}
+}
+
+func (l *appLister) printers(ctx context.Context, apps models.Applications) ([]ui.ApplicationPrinter, error) {
+	if l.options.ApiVersion == api.ApiV3 {
+		apps = filterByRuntime(apps, l.runtime)
+	}
+	if l.options.NameFilter != "" {
+		apps = filterByName(apps, l.options.NameFilter, true)
+	}
+	if l.options.ExcludeNameFilter != "" {
+		apps = filterByName(apps, l.options.ExcludeNameFilter, false)
+	}
+	if !l.options.Since.IsZero() {
+		apps = filterChangedSince(apps, l.options.Since)
+	}
+	if l.options.State != "" {
+		apps = filterByState(apps, l.options.State)
+	}
+	if l.options.Migratable {
+		apps = filterMigratable(apps)
+	}
+	if l.modifiable != nil {
+		apps = filterBySpace(apps, l.modifiable)
+	}
+	if err := l.resolveSpaces(ctx, apps); err != nil {
+		return nil, err
+	}
+
 	var appPrinters []ui.ApplicationPrinter
 	for _, a := range apps {
-		appPrinters = append(appPrinters, &displayhelpers.AppPrinter{
+		appPrinter := &displayhelpers.AppPrinter{
 			App:    a,
-			Spaces: spaceMap,
-		})
+			Spaces: l.spaces,
+			Stacks: l.stacks,
+		}
+		if !appPrinter.Accessible() {
+			if l.options.Strict {
+				l.inaccessible = append(l.inaccessible, a.Name)
+			}
+			if l.options.HideInaccessible {
+				continue
+			}
+		}
+		appPrinters = append(appPrinters, appPrinter)
+	}
+	return appPrinters, nil
+}
+
+// resolveSpaces fetches the spaces of apps that have not been fetched yet,
+// when spaces are resolved lazily. A space the user cannot see is left
+// out, so that its apps are inaccessible as they are otherwise.
+func (l *appLister) resolveSpaces(ctx context.Context, apps models.Applications) error {
+	if l.spaceFetcher == nil {
+		return nil
+	}
+
+	for _, app := range apps {
+		if l.resolved[app.SpaceGuid] {
+			continue
+		}
+
+		space, ok, err := l.spaceFetcher.GetSpace(ctx, app.SpaceGuid)
+		if err != nil {
+			return err
+		}
+		l.resolved[app.SpaceGuid] = true
+		if ok {
+			l.spaces[space.Guid] = space
+		}
 	}
+	return nil
+}
 
-	listAppsCommand.AfterAll(appPrinters)
+// fetchDeveloperSpaces fetches the spaces in which the logged-in user is a
+// SpaceDeveloper. They are never cached, as roles change more often than
+// spaces do.
+func fetchDeveloperSpaces(ctx context.Context, cliConnection api.Connection, spaceRequestFactory api.RequestFactory) (models.Spaces, error) {
+	userGuid, err := cliConnection.UserGuid()
+	if err != nil {
+		return nil, err
+	}
+
+	requester, err := api.NewPaginatedRequester(cliConnection, spaceRequestFactory)
+	if err != nil {
+		return nil, err
+	}
 
+	return thingdoer.DeveloperSpaces(ctx, models.SpacesParser{}, requester, userGuid)
+}
+
+// fetchConcurrently runs the fetches that are not nil at the same time
+// and, once they have all finished, returns the error of the first of them
+// that failed.
+func fetchConcurrently(fetches ...func() error) error {
+	errs := make([]error, len(fetches))
+	var done sync.WaitGroup
+	for i, fetch := range fetches {
+		if fetch == nil {
+			continue
+		}
+		done.Add(1)
+		go func(i int, fetch func() error) {
+			defer done.Done()
+			errs[i] = fetch()
+		}(i, fetch)
+	}
+	done.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// spaceSource fetches the spaces that apps are looked up in, to show their
+// space and org.
+type spaceSource struct {
+	requestFactory api.RequestFactory
+	requester      *api.PaginatedRequester
+	cache          *spacecache.Cache
+	filter         api.Filter
+	refresh        bool
+}
+
+// newSpaceSource fetches every space, through the cache unless refresh is
+// set, or only the spaces of the org with organizationGuid when it is
+// given. The cache holds every space, so it is left alone then.
+func newSpaceSource(cliConnection api.Connection, apiClient *api.Client, username string, organizationGuid string, refresh bool) (*spaceSource, error) {
+	requestFactory := apiClient.HandleFiltersAndParameters(
+		apiClient.Authorize(apiClient.NewGetSpacesRequest),
+	)
+	requester, err := api.NewPaginatedRequester(cliConnection, requestFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &spaceSource{
+		requestFactory: requestFactory,
+		requester:      requester,
+		filter:         api.Filters{},
+		refresh:        refresh,
+	}
+	if organizationGuid != "" {
+		source.filter = api.Filters{api.EqualFilter{Name: "organization_guid", Value: organizationGuid}}
+		return source, nil
+	}
+
+	source.cache, err = spacecache.New(apiClient.BaseUrl.String(), username)
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// fetch returns the cached spaces, when there are any and refresh is not
+// set, and otherwise fetches the spaces and updates the cache. When the
+// token is not allowed to list spaces, such as a token scoped to a single
+// space, it warns and returns listed false instead of failing.
+func (s *spaceSource) fetch(ctx context.Context) (spaces models.Spaces, listed bool, err error) {
+	if s.cache != nil && !s.refresh {
+		if spaces, ok := s.cache.Load(); ok {
+			return spaces, true, nil
+		}
+	}
+
+	spaces, listed, err = thingdoer.VisibleSpaces(ctx, models.SpacesParser{}, s.requester, s.filter)
+	if err != nil {
+		return nil, false, err
+	}
+	if !listed {
+		ui.WarnSpacesNotListed()
+		return nil, false, nil
+	}
+
+	if s.cache != nil {
+		// failing to save only means the next run fetches the spaces again
+		s.cache.Save(spaces)
+	}
+	return spaces, true, nil
+}
+
+// InaccessibleSpacesError is returned in strict mode for apps whose space
+// the user cannot see.
+type InaccessibleSpacesError struct {
+	AppNames []string
+}
+
+func (e InaccessibleSpacesError) Error() string {
+	return fmt.Sprintf(
+		"Cannot list apps in spaces you cannot see: %d apps are inaccessible: %s",
+		len(e.AppNames),
+		strings.Join(e.AppNames, ", "),
+	)
+}
+
+type InvalidNameFilterError struct {
+	Pattern string
+}
+
+func (e InvalidNameFilterError) Error() string {
+	return fmt.Sprintf("Invalid name filter %s: expected a shell pattern such as 'web-*'", e.Pattern)
+}
+
+// filterByRuntime keeps the apps on runtime, or every app for AllRuntimes.
+func filterByRuntime(apps models.Applications, runtime ui.Runtime) models.Applications {
+	if runtime == ui.AllRuntimes {
+		return apps
+	}
+
+	var matching models.Applications
+	for _, app := range apps {
+		if app.Diego == (runtime == ui.Diego) {
+			matching = append(matching, app)
+		}
+	}
+	return matching
+}
+
+// filterByName keeps the apps whose name matches pattern, or, when keep is
+// false, the apps whose name does not.
+func filterByName(apps models.Applications, pattern string, keep bool) models.Applications {
+	var matching models.Applications
+	for _, app := range apps {
+		if matched, _ := path.Match(pattern, app.Name); matched == keep {
+			matching = append(matching, app)
+		}
+	}
+	return matching
+}
+
+// filterBySpace keeps the apps in one of spaceGuids.
+func filterBySpace(apps models.Applications, spaceGuids map[string]bool) models.Applications {
+	var matching models.Applications
+	for _, app := range apps {
+		if spaceGuids[app.SpaceGuid] {
+			matching = append(matching, app)
+		}
+	}
+	return matching
+}
+
+func filterByState(apps models.Applications, state string) models.Applications {
+	var matching models.Applications
+	for _, app := range apps {
+		if app.State == state {
+			matching = append(matching, app)
+		}
+	}
+	return matching
+}
+
+func filterMigratable(apps models.Applications) models.Applications {
+	var matching models.Applications
+	for _, app := range apps {
+		if app.Migratable() {
+			matching = append(matching, app)
+		}
+	}
+	return matching
+}
+
+// filterChangedSince keeps apps created or updated after since, dropping
+// apps the Cloud Controller gave no timestamps for.
+func filterChangedSince(apps models.Applications, since time.Time) models.Applications {
+	var changed models.Applications
+	for _, app := range apps {
+		if lastChanged, ok := app.LastChanged(); ok && lastChanged.After(since) {
+			changed = append(changed, app)
+		}
+	}
+	return changed
+}
+
 func NewListAppsCommand(cliConnection api.Connection, orgName string, spaceName string, runtime ui.Runtime) (ui.ListAppsCommand, error) {
 	username, err := cliConnection.Username()
 	if err != nil {
 		return ui.ListAppsCommand{}, err
 	}
 
-	if spaceName != "" {
+	if spaceName != "" && orgName == "" {
 		space, err := cliConnection.GetSpace(spaceName)
 		if err != nil || space.Guid == "" {
 			return ui.ListAppsCommand{}, err
@@ -101,3 +592,122 @@ func NewListAppsCommand(cliConnection api.Connection, orgName string, spaceName
 	}
 	return cmd, nil
 }
+
+// Report counts every app visible to the user by organization and runtime.
+func Report(ctx context.Context, cliConnection api.Connection, reportCommand *ui.DiegoReportCommand, refresh bool) error {
+	reportCommand.BeforeAll()
+
+	apiClient, err := api.NewClient(cliConnection)
+	if err != nil {
+		return err
+	}
+
+	appRequestFactory := apiClient.HandleFiltersAndParameters(
+		apiClient.Authorize(apiClient.NewGetAppsRequest),
+	)
+	appPaginatedRequester, err := api.NewPaginatedRequester(cliConnection, appRequestFactory)
+	if err != nil {
+		return err
+	}
+	appPaginatedRequester.Progress = ui.PageProgress("apps")
+	appPaginatedRequester.Totals = ui.FetchTotals("apps")
+
+	spaceSource, err := newSpaceSource(cliConnection, apiClient, reportCommand.Username, "", refresh)
+	if err != nil {
+		return err
+	}
+
+	var (
+		apps         models.Applications
+		spaces       models.Spaces
+		spacesListed bool
+	)
+	err = fetchConcurrently(
+		func() (err error) {
+			apps, err = thingdoer.AppsGetter{}.AllApps(ctx, models.ApplicationsParser{}, appPaginatedRequester)
+			return err
+		},
+		func() (err error) {
+			spaces, spacesListed, err = spaceSource.fetch(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	var spaceMap map[string]models.Space
+	if spacesListed {
+		spaceMap = make(map[string]models.Space)
+		for _, space := range spaces {
+			spaceMap[space.Guid] = space
+		}
+	}
+
+	var appPrinters []ui.ApplicationPrinter
+	for _, a := range apps {
+		appPrinters = append(appPrinters, &displayhelpers.AppPrinter{
+			App:    a,
+			Spaces: spaceMap,
+		})
+	}
+
+	return reportCommand.AfterAll(appPrinters)
+}
+
+// CompareSnapshots prints how the apps in the JSON listing at newPath
+// differ from those at oldPath.
+func CompareSnapshots(reportCommand *ui.DiegoReportCommand, oldPath, newPath string) error {
+	before, err := readSnapshot(oldPath)
+	if err != nil {
+		return err
+	}
+
+	after, err := readSnapshot(newPath)
+	if err != nil {
+		return err
+	}
+
+	return reportCommand.PrintChanges(oldPath, newPath, models.CompareSnapshots(before, after))
+}
+
+func readSnapshot(path string) (models.Applications, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := models.SnapshotParser{}.Parse(body)
+	if err != nil {
+		return nil, InvalidSnapshotError{Path: path, Err: err}
+	}
+	return apps, nil
+}
+
+// InvalidSnapshotError is returned for a snapshot that is not the JSON
+// array printed by a listing command with --output json.
+type InvalidSnapshotError struct {
+	Path string
+	Err  error
+}
+
+func (e InvalidSnapshotError) Error() string {
+	return fmt.Sprintf("%s is not an app listing saved with --output json: %s", e.Path, e.Err)
+}
+
+func NewDiegoReportCommand(cliConnection api.Connection, output string) (ui.DiegoReportCommand, error) {
+	username, err := cliConnection.Username()
+	if err != nil {
+		return ui.DiegoReportCommand{}, err
+	}
+
+	traceEnv := os.Getenv("CF_TRACE")
+	traceLogger := trace.NewLogger(false, traceEnv, "")
+	tUI := terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), traceLogger)
+
+	return ui.DiegoReportCommand{
+		Username: username,
+		Output:   output,
+		UI:       tUI,
+	}, nil
+}
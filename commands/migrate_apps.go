@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/diegohelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/flaghelpers"
@@ -36,6 +37,11 @@ func (command MigrateAppsCommand) Execute([]string) error {
 		return err
 	}
 
+	err = api.ErrorIfVerificationSkipped(cliConnection)
+	if err != nil {
+		return err
+	}
+
 	appsGetter, err := diegohelpers.NewAppsGetterFunc(cliConnection, command.Organization, command.Space, runtime.Flip())
 	if err != nil {
 		return err
@@ -53,5 +59,8 @@ func (command MigrateAppsCommand) Execute([]string) error {
 		MigrateAppsCommand: &migrateAppsCommand,
 	}
 
-	return cmd.Execute(cliConnection)
+	ctx, stop := interruptContext()
+	defer stop()
+
+	return cmd.Execute(ctx, cliConnection)
 }
@@ -1,15 +1,17 @@
 package migratehelpers
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"sync"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport"
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
 	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
@@ -29,7 +31,7 @@ type MigrateApps struct {
 	MigrateAppsCommand *ui.MigrateAppsCommand
 }
 
-func (cmd *MigrateApps) Execute(cliConnection api.Connection) error {
+func (cmd *MigrateApps) Execute(ctx context.Context, cliConnection api.Connection) error {
 	cmd.MigrateAppsCommand.BeforeAll() //move me to the command
 
 	apiClient, err := api.NewClient(cliConnection)
@@ -47,6 +49,7 @@ func (cmd *MigrateApps) Execute(cliConnection api.Connection) error {
 	}
 
 	apps, err := cmd.AppsGetterFunc(
+		ctx,
 		models.ApplicationsParser{},
 		appPaginatedRequester,
 	)
@@ -63,22 +66,41 @@ func (cmd *MigrateApps) Execute(cliConnection api.Connection) error {
 		return err
 	}
 
-	spaces, err := thingdoer.Spaces(
+	spaces, listed, err := thingdoer.VisibleSpaces(
+		ctx,
 		models.SpacesParser{},
 		spacePaginatedRequester,
+		api.Filters{},
 	)
 	if err != nil {
 		return err
 	}
 
-	spaceMap := make(map[string]models.Space)
-	for _, space := range spaces {
-		spaceMap[space.Guid] = space
+	var spaceMap map[string]models.Space
+	if listed {
+		spaceMap = make(map[string]models.Space)
+		for _, space := range spaces {
+			spaceMap[space.Guid] = space
+		}
+	} else {
+		ui.WarnSpacesNotListed()
 	}
 
-	warnings, errors := cmd.migrateApps(cliConnection, apps, spaceMap, cmd.MaxInFlight)
+	attempts, warnings, errors := cmd.migrateApps(ctx, cliConnection, apps, spaceMap, cmd.MaxInFlight)
+	if ctx.Err() != nil {
+		cmd.MigrateAppsCommand.AfterInterrupt(attempts, warnings, errors, len(apps)-attempts)
+		return api.ErrInterrupted
+	}
 	cmd.MigrateAppsCommand.AfterAll(len(apps), warnings, errors)
 
+	if errors > 0 {
+		return errorhelpers.BatchFailure{
+			Message:   fmt.Sprintf("Failed to migrate %d of %d apps to %s", errors, len(apps), cmd.Runtime),
+			Failed:    errors,
+			Attempted: len(apps) - warnings,
+		}
+	}
+
 	return nil
 }
 
@@ -132,7 +154,7 @@ func (cmd *MigrateApps) MigrateApp(
 
 	_, err := diegoSupport.SetDiegoFlag(appPrinter.App.Guid, cmd.Runtime == ui.Diego)
 	if err != nil {
-		if strings.Contains(err.Error(), "NotAuthorized") {
+		if ccErr, ok := err.(diegosupport.CCErrorResponse); ok && ccErr.IsNotAuthorized() {
 			cmd.MigrateAppsCommand.UserWarning(appPrinter)
 			return Warning
 		} else {
@@ -156,12 +178,14 @@ func (cmd *MigrateApps) MigrateApp(
 	return Success
 }
 
-func (cmd *MigrateApps) migrateApps(cliConnection api.Connection, apps models.Applications, spaceMap map[string]models.Space, maxInFlight int) (int, int) {
+// migrateApps stops starting migrations once ctx is cancelled, waits for
+// the ones in flight and returns how many were attempted.
+func (cmd *MigrateApps) migrateApps(ctx context.Context, cliConnection api.Connection, apps models.Applications, spaceMap map[string]models.Space, maxInFlight int) (int, int, int) {
 	if len(apps) < maxInFlight {
 		maxInFlight = len(apps)
 	}
 
-	runningAppsChan := generateAppsChan(apps)
+	runningAppsChan := generateAppsChan(ctx, apps, cmd.MigrateAppsCommand.Interrupted)
 	outputsChan, waitDone := processAppsChan(cliConnection, spaceMap, cmd.MigrateApp, runningAppsChan, maxInFlight, len(apps))
 
 	waitDone.Wait()
@@ -170,12 +194,21 @@ func (cmd *MigrateApps) migrateApps(cliConnection api.Connection, apps models.Ap
 	return outputAppsChan(outputsChan)
 }
 
-func generateAppsChan(apps models.Applications) chan models.Application {
+func generateAppsChan(ctx context.Context, apps models.Applications, interrupted func()) chan models.Application {
 	runningAppsChan := make(chan models.Application)
 	go func() {
 		defer close(runningAppsChan)
 		for _, app := range apps {
-			runningAppsChan <- app
+			if ctx.Err() != nil {
+				interrupted()
+				return
+			}
+			select {
+			case runningAppsChan <- app:
+			case <-ctx.Done():
+				interrupted()
+				return
+			}
 		}
 	}()
 
@@ -213,11 +246,13 @@ func processAppsChan(
 	return output, &waitDone
 }
 
-func outputAppsChan(outputsChan chan int) (int, int) {
+func outputAppsChan(outputsChan chan int) (int, int, int) {
+	attempts := 0
 	warnings := 0
 	errors := 0
 
 	for result := range outputsChan {
+		attempts++
 		switch result {
 		case Warning:
 			warnings++
@@ -226,5 +261,5 @@ func outputAppsChan(outputsChan chan int) (int, int) {
 		default:
 		}
 	}
-	return warnings, errors
+	return attempts, warnings, errors
 }
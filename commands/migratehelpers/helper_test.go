@@ -8,6 +8,7 @@ import (
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
 	. "github.com/cloudfoundry-incubator/diego-enabler/commands/migratehelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands/migratehelpers/migratehelpersfakes"
+	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport"
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
 	"github.com/cloudfoundry-incubator/diego-enabler/ui"
 
@@ -74,7 +75,11 @@ var _ = Describe("MigrateApps", func() {
 
 			Context("when the user does not have permissions to migrate apps", func() {
 				BeforeEach(func() {
-					diegoSupport.SetDiegoFlagReturns(nil, errors.New("CF-NotAuthorized - You are not authorized to perform the requested action"))
+					diegoSupport.SetDiegoFlagReturns(nil, diegosupport.CCErrorResponse{
+						Code:        10003,
+						Description: "You are not authorized to perform the requested action",
+						ErrorCode:   "CF-NotAuthorized",
+					})
 				})
 
 				It("returns a warning", func() {
@@ -1,6 +1,8 @@
 package main_test
 
 import (
+	"os"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gexec"
@@ -22,6 +24,9 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	return []byte(path)
 }, func(data []byte) {
 	validPluginPath = string(data)
+
+	// Keep failed verifications from waiting between reads.
+	os.Setenv("CF_DIEGO_VERIFY_DELAY", "1ms")
 })
 
 // gexec.Build leaves a compiled binary behind in /tmp.
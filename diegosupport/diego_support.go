@@ -2,9 +2,14 @@ package diegosupport
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
 )
 
 //go:generate counterfeiter . CliConnection
@@ -14,43 +19,355 @@ type CliConnection interface {
 
 type DiegoSupport struct {
 	cli CliConnection
+
+	// MaxRetries and Sleep control the retries of requests that the
+	// Cloud Controller rate limits.
+	MaxRetries int
+	Sleep      func(time.Duration)
 }
 
-type diegoError struct {
+// CCErrorResponse is the error body the Cloud Controller returns when it
+// refuses to update an app.
+type CCErrorResponse struct {
 	Code        int64  `json:"code,omitempty"`
 	Description string `json:"description,omitempty"`
 	ErrorCode   string `json:"error_code,omitempty"`
+
+	// StatusCode is the HTTP status of the response, when it is known.
+	StatusCode int `json:"-"`
+}
+
+func (e CCErrorResponse) Error() string {
+	if e.Description == "" && e.ErrorCode == "" && e.StatusCode != 0 {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Description == "" {
+		return e.ErrorCode
+	}
+	return e.Description
+}
+
+func (e CCErrorResponse) IsNotAuthorized() bool {
+	return e.ErrorCode == "CF-NotAuthorized" || e.StatusCode == http.StatusForbidden
+}
+
+func (e CCErrorResponse) IsNotFound() bool {
+	return e.ErrorCode == "CF-AppNotFound"
 }
 
 func NewDiegoSupport(cli CliConnection) *DiegoSupport {
 	return &DiegoSupport{
-		cli: cli,
+		cli:        cli,
+		MaxRetries: api.DefaultMaxRetries,
+		Sleep:      time.Sleep,
 	}
 }
 
+// SetDiegoFlag returns a CCErrorResponse for which IsNotAuthorized is true
+// when the Cloud Controller refuses the change with a 403, even if the body
+// of the response is not a Cloud Controller error.
 func (d *DiegoSupport) SetDiegoFlag(appGuid string, enable bool) ([]string, error) {
-	output, err := d.cli.CliCommandWithoutTerminalOutput("curl", "/v2/apps/"+appGuid, "-X", "PUT", "-d", `{"diego":`+strconv.FormatBool(enable)+`}`)
+	status, output, err := d.curlWithStatus("/v2/apps/"+appGuid, "-X", "PUT", "-d", `{"diego":`+strconv.FormatBool(enable)+`}`)
 	if err != nil {
 		return output, err
 	}
 
-	if err = checkDiegoError(strings.Join(output, "")); err != nil {
+	err = checkDiegoError(strings.Join(output, ""))
+	if status == http.StatusForbidden {
+		ccErr, _ := err.(CCErrorResponse)
+		ccErr.StatusCode = status
+		return output, ccErr
+	}
+	if err != nil {
 		return output, err
 	}
 
 	return output, nil
 }
 
+// GetDiegoFlag fetches the app with the given guid and returns its diego
+// flag.
+func (d *DiegoSupport) GetDiegoFlag(appGuid string) (bool, error) {
+	output, err := d.curl("/v2/apps/" + appGuid)
+	if err != nil {
+		return false, err
+	}
+
+	if err = checkDiegoError(strings.Join(output, "")); err != nil {
+		return false, err
+	}
+
+	diego, ok := DiegoFlagFromOutput(output)
+	if !ok {
+		return false, fmt.Errorf("Could not read the diego flag of app %s", appGuid)
+	}
+
+	return diego, nil
+}
+
+// AppInfo is an app found by GetAppByGuid.
+type AppInfo struct {
+	Guid      string
+	Name      string
+	SpaceGuid string
+}
+
+type appByGuidResponse struct {
+	Metadata struct {
+		Guid string `json:"guid"`
+	} `json:"metadata"`
+	Entity struct {
+		Name      string `json:"name"`
+		SpaceGuid string `json:"space_guid"`
+	} `json:"entity"`
+}
+
+// GetAppByGuid fetches the name and space of the app with the given guid.
+// The error is a CCErrorResponse for which IsNotFound is true when there
+// is no such app.
+func (d *DiegoSupport) GetAppByGuid(appGuid string) (AppInfo, error) {
+	output, err := d.curl("/v2/apps/" + appGuid)
+	if err != nil {
+		return AppInfo{}, err
+	}
+
+	jsonRsp := strings.Join(output, "")
+	if err = checkDiegoError(jsonRsp); err != nil {
+		return AppInfo{}, err
+	}
+
+	var response appByGuidResponse
+	if err = json.Unmarshal([]byte(jsonRsp), &response); err != nil {
+		return AppInfo{}, err
+	}
+
+	return AppInfo{
+		Guid:      response.Metadata.Guid,
+		Name:      response.Entity.Name,
+		SpaceGuid: response.Entity.SpaceGuid,
+	}, nil
+}
+
+type spaceResponse struct {
+	Entity struct {
+		Name string `json:"name"`
+	} `json:"entity"`
+}
+
+// GetSpaceName fetches the space with the given guid and returns its name.
+func (d *DiegoSupport) GetSpaceName(spaceGuid string) (string, error) {
+	output, err := d.curl("/v2/spaces/" + spaceGuid)
+	if err != nil {
+		return "", err
+	}
+
+	jsonRsp := strings.Join(output, "")
+	if err = checkDiegoError(jsonRsp); err != nil {
+		return "", err
+	}
+
+	var response spaceResponse
+	if err = json.Unmarshal([]byte(jsonRsp), &response); err != nil {
+		return "", err
+	}
+
+	return response.Entity.Name, nil
+}
+
+type appStackResponse struct {
+	Entity struct {
+		Stack struct {
+			Entity struct {
+				Name string `json:"name"`
+			} `json:"entity"`
+		} `json:"stack"`
+	} `json:"entity"`
+}
+
+// GetStackName fetches the app with the given guid together with its stack
+// and returns the name of the stack, or "" if the app has none.
+func (d *DiegoSupport) GetStackName(appGuid string) (string, error) {
+	output, err := d.curl("/v2/apps/" + appGuid + "?inline-relations-depth=1")
+	if err != nil {
+		return "", err
+	}
+
+	jsonRsp := strings.Join(output, "")
+	if err = checkDiegoError(jsonRsp); err != nil {
+		return "", err
+	}
+
+	var response appStackResponse
+	if err = json.Unmarshal([]byte(jsonRsp), &response); err != nil {
+		return "", err
+	}
+
+	return response.Entity.Stack.Entity.Name, nil
+}
+
+// AppMatch is an app found by FindAppsByName.
+type AppMatch struct {
+	Guid      string
+	Diego     bool
+	SpaceName string
+	StackName string
+}
+
+type appsResponse struct {
+	NextUrl   string `json:"next_url"`
+	Resources []struct {
+		Metadata struct {
+			Guid string `json:"guid"`
+		} `json:"metadata"`
+		Entity struct {
+			Diego bool `json:"diego"`
+			Space struct {
+				Entity struct {
+					Name string `json:"name"`
+				} `json:"entity"`
+			} `json:"space"`
+			Stack struct {
+				Entity struct {
+					Name string `json:"name"`
+				} `json:"entity"`
+			} `json:"stack"`
+		} `json:"entity"`
+	} `json:"resources"`
+}
+
+// FindAppsByName returns the apps with the given name in every space the
+// user can see, so that a name that is used in more than one space can be
+// told apart from one that is not. It follows next_url through every page,
+// and inlines only the space and stack of each app.
+func (d *DiegoSupport) FindAppsByName(appName string) ([]AppMatch, error) {
+	path := "/v2/apps?q=" + url.QueryEscape("name:"+appName) + "&inline-relations-depth=1&include-relations=space,stack&results-per-page=100"
+
+	matches := []AppMatch{}
+	for path != "" {
+		output, err := d.curl(path)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonRsp := strings.Join(output, "")
+		if err = checkDiegoError(jsonRsp); err != nil {
+			return nil, err
+		}
+
+		var response appsResponse
+		if err = json.Unmarshal([]byte(jsonRsp), &response); err != nil {
+			return nil, err
+		}
+
+		for _, resource := range response.Resources {
+			matches = append(matches, AppMatch{
+				Guid:      resource.Metadata.Guid,
+				Diego:     resource.Entity.Diego,
+				SpaceName: resource.Entity.Space.Entity.Name,
+				StackName: resource.Entity.Stack.Entity.Name,
+			})
+		}
+		path = response.NextUrl
+	}
+	return matches, nil
+}
+
+// curl runs cf curl with the response headers, retrying requests that are
+// rate limited with a 429 after their Retry-After delay, and returns the
+// response body.
+func (d *DiegoSupport) curl(args ...string) ([]string, error) {
+	_, body, err := d.curlWithStatus(args...)
+	return body, err
+}
+
+// curlWithStatus is curl that also returns the HTTP status of the
+// response, or 0 if the output had no status line.
+func (d *DiegoSupport) curlWithStatus(args ...string) (int, []string, error) {
+	args = append(append([]string{"curl"}, args...), "-H", "User-Agent: "+api.UserAgent, "-i")
+
+	delay := api.DefaultRetryDelay
+	for attempt := 0; ; attempt++ {
+		output, err := d.cli.CliCommandWithoutTerminalOutput(args...)
+		if err != nil {
+			return 0, output, err
+		}
+
+		status, header, body := splitCurlOutput(output)
+		if status != http.StatusTooManyRequests || attempt >= d.MaxRetries {
+			return status, body, nil
+		}
+
+		wait := delay
+		if retryAfter, ok := api.RetryAfter(header.Get("Retry-After"), time.Now()); ok {
+			wait = retryAfter
+		}
+		d.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// splitCurlOutput splits the output of cf curl -i into the status, headers
+// and body. Output without a status line is all body.
+func splitCurlOutput(output []string) (int, http.Header, []string) {
+	text := strings.Replace(strings.Join(output, "\n"), "\r\n", "\n", -1)
+	if !strings.HasPrefix(text, "HTTP/") {
+		return 0, nil, output
+	}
+
+	parts := strings.SplitN(text, "\n\n", 2)
+	lines := strings.Split(parts[0], "\n")
+
+	var status int
+	if fields := strings.Fields(lines[0]); len(fields) > 1 {
+		status, _ = strconv.Atoi(fields[1])
+	}
+
+	header := http.Header{}
+	for _, line := range lines[1:] {
+		if i := strings.Index(line, ":"); i > 0 {
+			header.Add(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+		}
+	}
+
+	var body []string
+	if len(parts) == 2 {
+		body = strings.Split(parts[1], "\n")
+	}
+	return status, header, body
+}
+
+type appResource struct {
+	Entity struct {
+		Diego *bool `json:"diego"`
+	} `json:"entity"`
+}
+
+// DiegoFlagFromOutput reads the diego flag from the app resource that the
+// Cloud Controller returns from SetDiegoFlag. ok is false if the output is
+// not an app resource with a diego field.
+func DiegoFlagFromOutput(output []string) (diego bool, ok bool) {
+	var app appResource
+	if err := json.Unmarshal([]byte(strings.Join(output, "")), &app); err != nil {
+		return false, false
+	}
+
+	if app.Entity.Diego == nil {
+		return false, false
+	}
+
+	return *app.Entity.Diego, true
+}
+
 func checkDiegoError(jsonRsp string) error {
 	b := []byte(jsonRsp)
-	diegoErr := diegoError{}
+	diegoErr := CCErrorResponse{}
 	err := json.Unmarshal(b, &diegoErr)
 	if err != nil {
 		return err
 	}
 
 	if diegoErr.ErrorCode != "" || diegoErr.Code != 0 {
-		return errors.New(diegoErr.ErrorCode + " - " + diegoErr.Description)
+		return diegoErr
 	}
 
 	return nil
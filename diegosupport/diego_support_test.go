@@ -2,7 +2,9 @@ package diegosupport_test
 
 import (
 	"errors"
+	"time"
 
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport"
 	"github.com/cloudfoundry-incubator/diego-enabler/diegosupport/diegosupportfakes"
 
@@ -55,6 +57,12 @@ var _ = Describe("DiegoSupport", func() {
 				Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[4]).To(Equal("-d"))
 				Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[5]).To(Equal(`{"diego":true}`))
 			})
+
+			It("identifies the plugin in the User-Agent header", func() {
+				diegoSupport.SetDiegoFlag("test-app-guid", true)
+
+				Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[6:]).To(Equal([]string{"-H", "User-Agent: " + api.UserAgent, "-i"}))
+			})
 		})
 
 		Context("when we do not encounter an error from the API call", func() {
@@ -68,6 +76,43 @@ var _ = Describe("DiegoSupport", func() {
 			})
 		})
 
+		Context("when the Cloud Controller rate limits the request", func() {
+			var sleeps []time.Duration
+
+			BeforeEach(func() {
+				sleeps = nil
+				diegoSupport.Sleep = func(d time.Duration) {
+					sleeps = append(sleeps, d)
+				}
+			})
+
+			It("waits for as long as Retry-After says and returns the body of the retry", func() {
+				fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(...string) ([]string, error) {
+					if fakeCliConnection.CliCommandWithoutTerminalOutputCallCount() == 1 {
+						return []string{"HTTP/1.1 429 Too Many Requests\r\nRetry-After: 2\r\n\r\n", `{"error_code": "CF-RateLimitExceeded"}`}, nil
+					}
+					return []string{"HTTP/1.1 201 Created\r\nContent-Type: application/json\r\n\r\n", `{"entity": {"diego": true}}`}, nil
+				}
+
+				output, err := diegoSupport.SetDiegoFlag("test-app-guid", true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(2))
+				Expect(sleeps).To(Equal([]time.Duration{2 * time.Second}))
+				diego, ok := diegosupport.DiegoFlagFromOutput(output)
+				Expect(ok).To(BeTrue())
+				Expect(diego).To(BeTrue())
+			})
+
+			It("gives up after the maximum number of retries", func() {
+				fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{"HTTP/1.1 429 Too Many Requests\r\n\r\n", `{"code": 10013, "description": "Rate Limit Exceeded", "error_code": "CF-RateLimitExceeded"}`}, nil)
+
+				_, err := diegoSupport.SetDiegoFlag("test-app-guid", true)
+				Expect(err).To(MatchError("Rate Limit Exceeded"))
+				Expect(fakeCliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(api.DefaultMaxRetries + 1))
+				Expect(sleeps).To(HaveLen(api.DefaultMaxRetries))
+			})
+		})
+
 		Context("when we encounter an error from the API call", func() {
 			It("returns the output and the error from 'curl'", func() {
 				fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{"This is the fake output from curl", "some other content"}, errors.New("error from curl"))
@@ -88,9 +133,171 @@ var _ = Describe("DiegoSupport", func() {
 				Expect(output[0]).To(ContainSubstring(`"code": 10000`))
 				Expect(output[0]).To(ContainSubstring("diego not supported"))
 				Expect(output[0]).To(ContainSubstring("12345"))
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("12345 - diego not supported"))
+				Expect(err).To(Equal(diegosupport.CCErrorResponse{
+					Code:        10000,
+					Description: "diego not supported",
+					ErrorCode:   "12345",
+				}))
+				Expect(err.Error()).To(Equal("diego not supported"))
 			})
+
+			It("tells an authorization failure apart", func() {
+				response := []string{`{"code": 10003, "description": "You are not authorized to perform the requested action", "error_code": "CF-NotAuthorized"}`}
+				fakeCliConnection.CliCommandWithoutTerminalOutputReturns(response, nil)
+
+				_, err := diegoSupport.SetDiegoFlag("test-app-guid", false)
+				ccErr, ok := err.(diegosupport.CCErrorResponse)
+				Expect(ok).To(BeTrue())
+				Expect(ccErr.IsNotAuthorized()).To(BeTrue())
+				Expect(ccErr.IsNotFound()).To(BeFalse())
+			})
+
+			It("tells a 403 apart even when its body is not a Cloud Controller error", func() {
+				response := []string{"HTTP/1.1 403 Forbidden\r\nContent-Type: text/plain\r\n\r\n", "Forbidden"}
+				fakeCliConnection.CliCommandWithoutTerminalOutputReturns(response, nil)
+
+				_, err := diegoSupport.SetDiegoFlag("test-app-guid", true)
+				Expect(err).To(Equal(diegosupport.CCErrorResponse{StatusCode: 403}))
+				Expect(err.(diegosupport.CCErrorResponse).IsNotAuthorized()).To(BeTrue())
+				Expect(err.Error()).To(Equal("403 Forbidden"))
+			})
+		})
+	})
+
+	Describe("GetDiegoFlag", func() {
+		It("curls the app by guid and reads its diego flag", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"entity":{"diego":true}}`}, nil)
+
+			diego, err := diegoSupport.GetDiegoFlag("test-app-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diego).To(BeTrue())
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)).To(Equal([]string{"curl", "/v2/apps/test-app-guid", "-H", "User-Agent: " + api.UserAgent, "-i"}))
+		})
+
+		It("returns the Cloud Controller error when the app is not found", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"code": 100004, "description": "The app could not be found: test-app-guid", "error_code": "CF-AppNotFound"}`}, nil)
+
+			_, err := diegoSupport.GetDiegoFlag("test-app-guid")
+			ccErr, ok := err.(diegosupport.CCErrorResponse)
+			Expect(ok).To(BeTrue())
+			Expect(ccErr.IsNotFound()).To(BeTrue())
+		})
+	})
+
+	Describe("GetAppByGuid", func() {
+		It("curls the app by guid and reads its name and space", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"metadata":{"guid":"test-app-guid"},"entity":{"name":"test-app","space_guid":"some-space-guid"}}`}, nil)
+
+			app, err := diegoSupport.GetAppByGuid("test-app-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app).To(Equal(diegosupport.AppInfo{Guid: "test-app-guid", Name: "test-app", SpaceGuid: "some-space-guid"}))
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[1]).To(Equal("/v2/apps/test-app-guid"))
+		})
+
+		It("returns the Cloud Controller error when the app is not found", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"code": 100004, "description": "The app could not be found: test-app-guid", "error_code": "CF-AppNotFound"}`}, nil)
+
+			_, err := diegoSupport.GetAppByGuid("test-app-guid")
+			ccErr, ok := err.(diegosupport.CCErrorResponse)
+			Expect(ok).To(BeTrue())
+			Expect(ccErr.IsNotFound()).To(BeTrue())
+		})
+	})
+
+	Describe("GetSpaceName", func() {
+		It("curls the space by guid and reads its name", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"metadata":{"guid":"some-space-guid"},"entity":{"name":"dev"}}`}, nil)
+
+			spaceName, err := diegoSupport.GetSpaceName("some-space-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spaceName).To(Equal("dev"))
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[1]).To(Equal("/v2/spaces/some-space-guid"))
+		})
+
+		It("returns the Cloud Controller error when the space is not found", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"code": 40004, "description": "The app space could not be found: some-space-guid", "error_code": "CF-SpaceNotFound"}`}, nil)
+
+			_, err := diegoSupport.GetSpaceName("some-space-guid")
+			Expect(err).To(MatchError(ContainSubstring("The app space could not be found")))
+		})
+	})
+
+	Describe("GetStackName", func() {
+		It("curls the app by guid with its stack and reads the stack name", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"metadata":{"guid":"test-app-guid"},"entity":{"stack":{"entity":{"name":"lucid64"}}}}`}, nil)
+
+			stackName, err := diegoSupport.GetStackName("test-app-guid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stackName).To(Equal("lucid64"))
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[1]).To(Equal("/v2/apps/test-app-guid?inline-relations-depth=1"))
+		})
+
+		It("returns the Cloud Controller error when the app is not found", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"code": 100004, "description": "The app could not be found: test-app-guid", "error_code": "CF-AppNotFound"}`}, nil)
+
+			_, err := diegoSupport.GetStackName("test-app-guid")
+			ccErr, ok := err.(diegosupport.CCErrorResponse)
+			Expect(ok).To(BeTrue())
+			Expect(ccErr.IsNotFound()).To(BeTrue())
+		})
+	})
+
+	Describe("FindAppsByName", func() {
+		It("curls the apps with that name and returns each with its space and stack", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"resources":[`,
+				`{"metadata":{"guid":"first-guid"},"entity":{"diego":true,"space":{"entity":{"name":"dev"}},"stack":{"entity":{"name":"cflinuxfs2"}}}},`,
+				`{"metadata":{"guid":"second-guid"},"entity":{"diego":false,"space":{"entity":{"name":"prod"}}}}`,
+				`]}`}, nil)
+
+			matches, err := diegoSupport.FindAppsByName("my app")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matches).To(Equal([]diegosupport.AppMatch{
+				{Guid: "first-guid", Diego: true, SpaceName: "dev", StackName: "cflinuxfs2"},
+				{Guid: "second-guid", Diego: false, SpaceName: "prod"},
+			}))
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)[1]).To(Equal("/v2/apps?q=name%3Amy+app&inline-relations-depth=1&include-relations=space,stack&results-per-page=100"))
+		})
+
+		It("follows next_url through every page", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				if args[1] == "/v2/apps?page=2" {
+					return []string{`{"next_url":null,"resources":[{"metadata":{"guid":"second-guid"},"entity":{"space":{"entity":{"name":"prod"}}}}]}`}, nil
+				}
+				return []string{`{"next_url":"/v2/apps?page=2","resources":[{"metadata":{"guid":"first-guid"},"entity":{"space":{"entity":{"name":"dev"}}}}]}`}, nil
+			}
+
+			matches, err := diegoSupport.FindAppsByName("my-app")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matches).To(Equal([]diegosupport.AppMatch{
+				{Guid: "first-guid", SpaceName: "dev"},
+				{Guid: "second-guid", SpaceName: "prod"},
+			}))
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(2))
+		})
+
+		It("returns the Cloud Controller error", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{`{"code": 10002, "description": "Authentication error", "error_code": "CF-NotAuthenticated"}`}, nil)
+
+			_, err := diegoSupport.FindAppsByName("my-app")
+			Expect(err).To(Equal(diegosupport.CCErrorResponse{Code: 10002, Description: "Authentication error", ErrorCode: "CF-NotAuthenticated"}))
+		})
+	})
+
+	Describe("DiegoFlagFromOutput", func() {
+		It("reads the diego flag from the updated app", func() {
+			diego, ok := diegosupport.DiegoFlagFromOutput([]string{`{"metadata":{"guid":"test-app-guid"},`, `"entity":{"diego":true}}`})
+			Expect(ok).To(BeTrue())
+			Expect(diego).To(BeTrue())
+		})
+
+		It("is not ok when the output has no diego field", func() {
+			_, ok := diegosupport.DiegoFlagFromOutput([]string{`{"entity":{"name":"test-app"}}`})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("is not ok when the output is not JSON", func() {
+			_, ok := diegosupport.DiegoFlagFromOutput([]string{"not json"})
+			Expect(ok).To(BeFalse())
 		})
 	})
 })
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/commands"
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/errorhelpers"
 	"github.com/cloudfoundry-incubator/diego-enabler/ui"
 	"github.com/cloudfoundry/cli/plugin"
 	"github.com/jessevdk/go-flags"
@@ -25,49 +27,228 @@ func (c *DiegoEnabler) GetMetadata() plugin.PluginMetadata {
 				Name:     "enable-diego",
 				HelpText: "Migrate app to the Diego runtime",
 				UsageDetails: plugin.Usage{
-					Usage: `cf enable-diego APP_NAME
+					Usage: `cf enable-diego (APP_NAME | --apps-file PATH | --guid APP_GUID | --guids-file PATH | --stdin) [-s SPACE] [--no-wait] [--dry-run] [--output FORMAT] [-f] [--restart]
 
 WARNING:
-   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.`,
+   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.
+
+OPTIONS:
+   --apps-file   File listing one app name per line; every app is attempted and failures are summarized at the end
+   --guid        Guid of the app, such as one from diego-apps --output json, instead of its name
+   --guids-file  File listing one app guid per line, such as a --failures-file; every app is attempted
+   --stdin       Read one app guid per line from stdin, e.g. diego-apps --output json | jq -r '.[].guid'; every app is attempted
+   --space, -s   Space of the app, for app names used in more than one space
+   --no-wait     Do not verify the Diego flag after setting it
+   --dry-run     Show what would change without setting the Diego flag
+   --output      Output format: text, the default, or json, which prints {"name":...,"guid":...,"requested":...,"verified":...} for a single APP_NAME; failures exit non-zero as listed under EXIT STATUS
+   --force, -f   Enable Diego support for an app named by APP_NAME or --apps-file even if its stack is listed in CF_DIEGO_INCOMPATIBLE_STACKS, with a warning
+   --restart     Run cf restart for each started app named by APP_NAME or --apps-file once Diego support is enabled for it
+
+EXIT STATUS:
+   0 on success, 1 if setting the flag failed, 3 if it was set but verification shows the old value; with --apps-file, --guids-file or --stdin, 2 if only some of the apps failed`,
 				},
 			},
 			{
 				Name:     "disable-diego",
 				HelpText: "Migrate app to the DEA runtime",
 				UsageDetails: plugin.Usage{
-					Usage: `cf disable-diego APP_NAME
+					Usage: `cf disable-diego (APP_NAME | --apps-file PATH | --guid APP_GUID | --guids-file PATH | --stdin) [-s SPACE] [--no-wait] [--dry-run] [--output FORMAT]
 
 WARNING:
-   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.`,
+   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.
+
+OPTIONS:
+   --apps-file   File listing one app name per line; every app is attempted and failures are summarized at the end
+   --guid        Guid of the app, such as one from diego-apps --output json, instead of its name
+   --guids-file  File listing one app guid per line, such as a --failures-file; every app is attempted
+   --stdin       Read one app guid per line from stdin, e.g. diego-apps --output json | jq -r '.[].guid'; every app is attempted
+   --space, -s   Space of the app, for app names used in more than one space
+   --no-wait     Do not verify the Diego flag after setting it
+   --dry-run     Show what would change without setting the Diego flag
+   --output      Output format: text, the default, or json, which prints {"name":...,"guid":...,"requested":...,"verified":...} for a single APP_NAME; failures exit non-zero as listed under EXIT STATUS
+
+EXIT STATUS:
+   0 on success, 1 if setting the flag failed, 3 if it was set but verification shows the old value; with --apps-file, --guids-file or --stdin, 2 if only some of the apps failed`,
 				},
 			},
 			{
 				Name:     "has-diego-enabled",
 				HelpText: "Report whether an app is configured to run on the Diego runtime",
 				UsageDetails: plugin.Usage{
-					Usage: "cf has-diego-enabled APP_NAME",
+					Usage: `cf has-diego-enabled APP_NAME [APP_NAME...] [--output FORMAT] [--show-location] [--quiet]
+
+Exits with status 0 if Diego is enabled for every app, 3 if it is not
+enabled for at least one of them, and 1 if an app could not be checked.
+
+OPTIONS:
+   --output      Output format: text, the default, or json, which prints {"name":...,"guid":...,"diego":...}, or an array of those for several apps
+   --show-location
+                 Also print the org and space of each app, with "space" and "org" fields in json
+   -q, --quiet   Only report through the exit status`,
+				},
+			},
+			{
+				Name:     "has-dea-enabled",
+				HelpText: "Report whether an app is configured to run on the DEA runtime",
+				UsageDetails: plugin.Usage{
+					Usage: `cf has-dea-enabled APP_NAME [APP_NAME...] [--output FORMAT] [--show-location] [--quiet]
+
+Exits with status 0 if every app is on the DEA runtime, 3 if at least one
+of them is not, and 1 if an app could not be checked.
+
+OPTIONS:
+   --output      Output format: text, the default, or json, which prints {"name":...,"guid":...,"diego":...}, or an array of those for several apps
+   --show-location
+                 Also print the org and space of each app, with "space" and "org" fields in json
+   -q, --quiet   Only report through the exit status`,
 				},
 			},
 			{
 				Name:     "diego-apps",
 				HelpText: "Lists all apps running on the Diego runtime that are visible to the user",
 				UsageDetails: plugin.Usage{
-					Usage: `cf diego-apps [-o ORG | -s SPACE]
+					Usage: `cf diego-apps [-o ORG] [-s SPACE] [--output FORMAT] [--sort FIELD[:desc]] [--count | --guids] [--page-size N] [--columns COLUMNS] [--name-filter PATTERN] [--exclude-name-filter PATTERN] [--hide-inaccessible] [--strict] [--modifiable-only] [--since TIME] [--stopped | --started] [--migratable] [--limit N [--offset M]] [--api URL] [--order-by name[:desc]] [--no-truncate] [--refresh] [--group-by org] [--out-file PATH] [--api-version v2|v3] [--resolve-spaces all|lazy]
 
 OPTIONS:
-   -o      Organization to restrict the app migration to,
-   -s      Space in the targeted organization to limit results to`,
+   -o, --org     Organization to limit results to
+   -s, --space   Space to limit results to, in the targeted organization unless one is given with -o
+   --output      Output format for the app list: table, json, csv or ndjson, one JSON object per line (Default: $CF_DIEGO_OUTPUT, or else table)
+   --sort        Sort the app list by name, space or org; append :desc to reverse the order
+   --count       Only print the number of apps found
+   --guids       Only print the guid of each app, one per line, with no table or other output; combine with -o and -s to pick the apps
+   --page-size   Number of apps to request from the Cloud Controller at a time, at most 100
+   --columns     Comma-separated table columns to show: name, guid, space, org, state, instances, memory, stack, runtime, buildpack, docker_image
+   --name-filter Only list apps whose name matches a shell pattern such as 'web-*'
+   --exclude-name-filter
+                 Leave out apps whose name matches a shell pattern, such as 'apps-manager*' to hide system apps
+   --hide-inaccessible
+                 Leave out apps in spaces you cannot see, which are otherwise shown in space and org (inaccessible)
+   --strict      Fail, naming the apps, if any app is in a space you cannot see, so that the list is known to be complete
+   --modifiable-only
+                 Only list apps in spaces where you are a SpaceDeveloper, which are the apps you can enable or disable Diego support for
+   --since       Only list apps updated, or created if never updated, after an RFC3339 time such as 2016-03-16T16:40:43Z
+   --stopped     Only list stopped apps, such as DEA apps that can be migrated without downtime
+   --started     Only list started apps
+   --migratable  Leave out apps whose package failed to stage, which would fail to stage on the other runtime too
+   --limit       Only list a chunk of N apps (at most 100); cannot be combined with --page-size
+   --offset      Skip the first M apps; must be a multiple of --limit. Chunks follow the Cloud Controller's own ordering, not --sort, which only orders the apps within a chunk
+   --api         Cloud Controller to list apps from instead of the targeted one; it must accept the current access token and cannot be combined with -o or -s
+   --order-by    Have the Cloud Controller return apps ordered by name, so that chunks from --limit and --offset follow name order; append :desc to reverse it
+   --no-truncate Do not truncate long app, space and org names to fit the table in the terminal
+   --refresh     Fetch the spaces even if they are cached; see CF_DIEGO_SPACE_CACHE_TTL
+   --group-by    Print a separate table for each org, in order of org name
+   --out-file    Write the table, JSON or CSV to PATH instead of stdout, without colors, replacing the file if it exists
+   --api-version List apps with the v2 or v3 Cloud Controller API (Default: v2); v3 reports every app as on Diego and leaves out instances and memory
+   --resolve-spaces
+                 Fetch every space (all), or only the space of each listed app, one request per space (lazy), to show the space and org (Default: all)`,
 				},
 			},
 			{
 				Name:     "dea-apps",
 				HelpText: "Lists all apps running on the DEA runtime that are visible to the user",
 				UsageDetails: plugin.Usage{
-					Usage: `cf dea-apps [-o ORG | -s SPACE]
+					Usage: `cf dea-apps [-o ORG] [-s SPACE] [--output FORMAT] [--sort FIELD[:desc]] [--count | --guids] [--page-size N] [--columns COLUMNS] [--name-filter PATTERN] [--exclude-name-filter PATTERN] [--hide-inaccessible] [--strict] [--modifiable-only] [--since TIME] [--stopped | --started] [--migratable] [--limit N [--offset M]] [--api URL] [--order-by name[:desc]] [--no-truncate] [--refresh] [--group-by org] [--out-file PATH] [--api-version v2|v3] [--resolve-spaces all|lazy]
 
 OPTIONS:
-   -o      Organization to restrict the app migration to,
-   -s      Space in the targeted organization to limit results to`,
+   -o, --org     Organization to limit results to
+   -s, --space   Space to limit results to, in the targeted organization unless one is given with -o
+   --output      Output format for the app list: table, json, csv or ndjson, one JSON object per line (Default: $CF_DIEGO_OUTPUT, or else table)
+   --sort        Sort the app list by name, space or org; append :desc to reverse the order
+   --count       Only print the number of apps found
+   --guids       Only print the guid of each app, one per line, with no table or other output; combine with -o and -s to pick the apps
+   --page-size   Number of apps to request from the Cloud Controller at a time, at most 100
+   --columns     Comma-separated table columns to show: name, guid, space, org, state, instances, memory, stack, runtime, buildpack, docker_image
+   --name-filter Only list apps whose name matches a shell pattern such as 'web-*'
+   --exclude-name-filter
+                 Leave out apps whose name matches a shell pattern, such as 'apps-manager*' to hide system apps
+   --hide-inaccessible
+                 Leave out apps in spaces you cannot see, which are otherwise shown in space and org (inaccessible)
+   --strict      Fail, naming the apps, if any app is in a space you cannot see, so that the list is known to be complete
+   --modifiable-only
+                 Only list apps in spaces where you are a SpaceDeveloper, which are the apps you can enable or disable Diego support for
+   --since       Only list apps updated, or created if never updated, after an RFC3339 time such as 2016-03-16T16:40:43Z
+   --stopped     Only list stopped apps, such as DEA apps that can be migrated without downtime
+   --started     Only list started apps
+   --migratable  Leave out apps whose package failed to stage, which would fail to stage on the other runtime too
+   --limit       Only list a chunk of N apps (at most 100); cannot be combined with --page-size
+   --offset      Skip the first M apps; must be a multiple of --limit. Chunks follow the Cloud Controller's own ordering, not --sort, which only orders the apps within a chunk
+   --api         Cloud Controller to list apps from instead of the targeted one; it must accept the current access token and cannot be combined with -o or -s
+   --order-by    Have the Cloud Controller return apps ordered by name, so that chunks from --limit and --offset follow name order; append :desc to reverse it
+   --no-truncate Do not truncate long app, space and org names to fit the table in the terminal
+   --refresh     Fetch the spaces even if they are cached; see CF_DIEGO_SPACE_CACHE_TTL
+   --group-by    Print a separate table for each org, in order of org name
+   --out-file    Write the table, JSON or CSV to PATH instead of stdout, without colors, replacing the file if it exists
+   --api-version List apps with the v2 or v3 Cloud Controller API (Default: v2); v3 reports every app as on Diego and leaves out instances and memory
+   --resolve-spaces
+                 Fetch every space (all), or only the space of each listed app, one request per space (lazy), to show the space and org (Default: all)`,
+				},
+			},
+			{
+				Name:     "apps-by-runtime",
+				HelpText: "Lists all apps visible to the user along with the runtime each one is on",
+				UsageDetails: plugin.Usage{
+					Usage: `cf apps-by-runtime [-o ORG] [-s SPACE] [--diego true|false] [--output FORMAT] [--sort FIELD[:desc]] [--count | --guids] [--page-size N] [--columns COLUMNS] [--name-filter PATTERN] [--exclude-name-filter PATTERN] [--hide-inaccessible] [--strict] [--modifiable-only] [--since TIME] [--stopped | --started] [--migratable] [--limit N [--offset M]] [--api URL] [--order-by name[:desc]] [--no-truncate] [--refresh] [--group-by org] [--out-file PATH] [--api-version v2|v3] [--resolve-spaces all|lazy]
+
+OPTIONS:
+   --diego       Only list apps whose Diego flag is true, like diego-apps, or false, like dea-apps
+   -o, --org     Organization to limit results to
+   -s, --space   Space to limit results to, in the targeted organization unless one is given with -o
+   --output      Output format for the app list: table, json, csv or ndjson, one JSON object per line (Default: $CF_DIEGO_OUTPUT, or else table)
+   --sort        Sort the app list by name, space or org; append :desc to reverse the order
+   --count       Only print the number of apps found
+   --guids       Only print the guid of each app, one per line, with no table or other output; combine with -o and -s to pick the apps
+   --page-size   Number of apps to request from the Cloud Controller at a time, at most 100
+   --columns     Comma-separated table columns to show: name, guid, space, org, state, instances, memory, stack, runtime, buildpack, docker_image
+   --name-filter Only list apps whose name matches a shell pattern such as 'web-*'
+   --exclude-name-filter
+                 Leave out apps whose name matches a shell pattern, such as 'apps-manager*' to hide system apps
+   --hide-inaccessible
+                 Leave out apps in spaces you cannot see, which are otherwise shown in space and org (inaccessible)
+   --strict      Fail, naming the apps, if any app is in a space you cannot see, so that the list is known to be complete
+   --modifiable-only
+                 Only list apps in spaces where you are a SpaceDeveloper, which are the apps you can enable or disable Diego support for
+   --since       Only list apps updated, or created if never updated, after an RFC3339 time such as 2016-03-16T16:40:43Z
+   --stopped     Only list stopped apps, such as DEA apps that can be migrated without downtime
+   --started     Only list started apps
+   --migratable  Leave out apps whose package failed to stage, which would fail to stage on the other runtime too
+   --limit       Only list a chunk of N apps (at most 100); cannot be combined with --page-size
+   --offset      Skip the first M apps; must be a multiple of --limit. Chunks follow the Cloud Controller's own ordering, not --sort, which only orders the apps within a chunk
+   --api         Cloud Controller to list apps from instead of the targeted one; it must accept the current access token and cannot be combined with -o or -s
+   --order-by    Have the Cloud Controller return apps ordered by name, so that chunks from --limit and --offset follow name order; append :desc to reverse it
+   --no-truncate Do not truncate long app, space and org names to fit the table in the terminal
+   --refresh     Fetch the spaces even if they are cached; see CF_DIEGO_SPACE_CACHE_TTL
+   --group-by    Print a separate table for each org, in order of org name
+   --out-file    Write the table, JSON or CSV to PATH instead of stdout, without colors, replacing the file if it exists
+   --api-version List apps with the v2 or v3 Cloud Controller API (Default: v2); v3 reports every app as on Diego and leaves out instances and memory
+   --resolve-spaces
+                 Fetch every space (all), or only the space of each listed app, one request per space (lazy), to show the space and org (Default: all)`,
+				},
+			},
+			{
+				Name:     "diego-report",
+				HelpText: "Summarize the Diego migration progress of each organization",
+				UsageDetails: plugin.Usage{
+					Usage: `cf diego-report [--output FORMAT] [--refresh]
+   cf diego-report --compare OLD_JSON NEW_JSON [--output FORMAT]
+
+OPTIONS:
+   --output      Output format for the report: table or json
+   --refresh     Fetch the spaces even if they are cached; see CF_DIEGO_SPACE_CACHE_TTL
+   --compare     Print the apps added, removed or changed between two listings saved with diego-apps, dea-apps or apps-by-runtime --output json`,
+				},
+			},
+			{
+				Name:     "app-names-from-guids",
+				HelpText: "Look up the name, space and org of app guids",
+				UsageDetails: plugin.Usage{
+					Usage: `cf app-names-from-guids (--guids-file PATH | --stdin) [--output FORMAT]
+
+Exits with status 1 if any guid is not found, after printing every guid.
+
+OPTIONS:
+   --guids-file  File listing one app guid per line, such as guids copied from logs
+   --stdin       Read one app guid per line from stdin, e.g. diego-apps --guids | cf app-names-from-guids --stdin
+   --output      Output format for the mapping: table or json`,
 				},
 			},
 			{
@@ -82,7 +263,85 @@ WARNING:
 OPTIONS:
    -o      Organization to restrict the app migration to
    -s      Space in the targeted organization to restrict the app migration to
-   -p      Maximum number of apps to migrate in parallel (Default: 1, maximum: 100)`,
+   -p      Maximum number of apps to migrate in parallel (Default: 1, maximum: 100)
+
+EXIT STATUS:
+   0 if every app was switched, 2 if only some of the apps failed, 1 if all of them failed or the command could not run`,
+				},
+			},
+			{
+				Name:     "enable-diego-in-org",
+				HelpText: "Migrate all apps in an organization to the Diego runtime",
+				UsageDetails: plugin.Usage{
+					Usage: `cf enable-diego-in-org ORG_NAME [--dry-run] [-f] [-p MAX_IN_FLIGHT] [--timeout-per-app DURATION] [--failures-file PATH] [--live]
+
+WARNING:
+   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.
+
+OPTIONS:
+   --dry-run     Show which apps would be switched without changing them
+   -f, --force   Switch the apps without asking for confirmation when more than 10 would change
+   -p            Maximum number of apps to switch in parallel (Default: 5, maximum: 100)
+   --timeout-per-app   Give up on an app whose Diego flag has not been set within DURATION, such as 30s, and count it as timed out
+   --failures-file     Write the guid of each app that was not switched to PATH, to retry them with cf enable-diego --guids-file PATH
+   --live              Show the apps in a table whose status is updated as each app is switched; lines are printed instead when stdout is not a terminal
+
+EXIT STATUS:
+   0 if every app was switched, 2 if only some of the apps failed, 1 if all of them failed or the command could not run`,
+				},
+			},
+			{
+				Name:     "enable-diego-in-space",
+				HelpText: "Migrate all apps in a space of the targeted organization to the Diego runtime",
+				UsageDetails: plugin.Usage{
+					Usage: `cf enable-diego-in-space SPACE_NAME [--dry-run] [-f] [-p MAX_IN_FLIGHT] [--timeout-per-app DURATION] [--failures-file PATH] [--live]
+
+WARNING:
+   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.
+
+OPTIONS:
+   --dry-run     Show which apps would be switched without changing them
+   -f, --force   Switch the apps without asking for confirmation when more than 10 would change
+   -p            Maximum number of apps to switch in parallel (Default: 5, maximum: 100)
+   --timeout-per-app   Give up on an app whose Diego flag has not been set within DURATION, such as 30s, and count it as timed out
+   --failures-file     Write the guid of each app that was not switched to PATH, to retry them with cf enable-diego --guids-file PATH
+   --live              Show the apps in a table whose status is updated as each app is switched; lines are printed instead when stdout is not a terminal
+
+EXIT STATUS:
+   0 if every app was switched, 2 if only some of the apps failed, 1 if all of them failed or the command could not run`,
+				},
+			},
+			{
+				Name:     "disable-diego-in-org",
+				HelpText: "Migrate all apps in an organization back to the DEA runtime",
+				UsageDetails: plugin.Usage{
+					Usage: `cf disable-diego-in-org ORG_NAME [--dry-run] [-f] [-p MAX_IN_FLIGHT] [--timeout-per-app DURATION] [--failures-file PATH] [--live]
+
+WARNING:
+   Migration of a running app causes a restart. Stopped apps will be configured to run on the target runtime but are not started.
+
+OPTIONS:
+   --dry-run     Show which apps would be switched without changing them
+   -f, --force   Switch the apps without asking for confirmation when more than 10 would change
+   -p            Maximum number of apps to switch in parallel (Default: 5, maximum: 100)
+   --timeout-per-app   Give up on an app whose Diego flag has not been set within DURATION, such as 30s, and count it as timed out
+   --failures-file     Write the guid of each app that was not switched to PATH, to retry them with cf disable-diego --guids-file PATH
+   --live              Show the apps in a table whose status is updated as each app is switched; lines are printed instead when stdout is not a terminal
+
+EXIT STATUS:
+   0 if every app was switched, 2 if only some of the apps failed, 1 if all of them failed or the command could not run`,
+				},
+			},
+			{
+				Name:     "diego-enabler-doctor",
+				HelpText: "Check that the plugin can talk to the Cloud Controller",
+				UsageDetails: plugin.Usage{
+					Usage: `cf diego-enabler-doctor
+
+Checks that you are logged in with an access token, that the Cloud Controller answers /v2/info, and that you can see at least one org and space. Each check is printed as PASS, FAIL with a hint on fixing it, or SKIP when an earlier check failed.
+
+EXIT STATUS:
+   0 if every check passed, 1 otherwise`,
 				},
 			},
 		},
@@ -95,13 +354,65 @@ func main() {
 
 func (c *DiegoEnabler) Run(cliConnection plugin.CliConnection, args []string) {
 	commands.DiegoEnabler.CLIConnection = cliConnection
+	api.UserAgent = userAgent(c.GetMetadata().Version)
 	parser := flags.NewParser(&commands.DiegoEnabler, flags.HelpFlag|flags.PassDoubleDash)
 	parser.NamespaceDelimiter = "-"
 
 	_, err := parser.ParseArgs(args)
+	if status, ok := err.(errorhelpers.ExitStatus); ok {
+		os.Exit(status.Code)
+	}
 	if err != nil {
 		ui.SayFailed()
-		fmt.Printf("Error: %s\n", err.Error())
-		os.Exit(1)
+
+		var commandName string
+		if len(args) > 0 {
+			commandName = args[0]
+		}
+
+		if flagsErr, ok := err.(*flags.Error); ok && (flagsErr.Type == flags.ErrUnknownCommand || flagsErr.Type == flags.ErrCommandRequired) {
+			ui.Error("Unknown command '%s'. Run 'cf plugins' to list the commands of the %s plugin.\n", commandName, c.GetMetadata().Name)
+			os.Exit(1)
+		}
+
+		ui.Error("%s\n", err.Error())
+		if isUsageError(err) {
+			c.showUsage(commandName)
+		}
+		os.Exit(errorhelpers.ExitCode(err))
+	}
+}
+
+// isUsageError reports whether err is about how the command was called,
+// such as a missing argument or an unknown flag, rather than about what it
+// tried to do.
+func isUsageError(err error) bool {
+	if err == errorhelpers.AppNameRequiredError {
+		return true
+	}
+
+	flagsErr, ok := err.(*flags.Error)
+	if !ok {
+		return false
+	}
+
+	switch flagsErr.Type {
+	case flags.ErrRequired, flags.ErrExpectedArgument, flags.ErrUnknownFlag, flags.ErrInvalidChoice:
+		return true
 	}
+	return false
+}
+
+func (c *DiegoEnabler) showUsage(commandName string) {
+	for _, command := range c.GetMetadata().Commands {
+		if command.Name == commandName {
+			fmt.Printf("\nUSAGE:\n   %s\n", command.UsageDetails.Usage)
+			return
+		}
+	}
+}
+
+// userAgent names the plugin and its version, e.g. diego-enabler/1.2.2.
+func userAgent(version plugin.VersionType) string {
+	return fmt.Sprintf("diego-enabler/%d.%d.%d", version.Major, version.Minor, version.Build)
 }
@@ -2,7 +2,14 @@ package main_test
 
 import (
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cloudfoundry/cli/plugin/models"
 	"github.com/cloudfoundry/cli/testhelpers/rpc_server"
@@ -24,9 +31,7 @@ var _ = Describe("DiegoEnabler", func() {
 
 		BeforeEach(func() {
 			rpcHandlers = &fake_rpc_handlers.FakeHandlers{}
-		})
 
-		JustBeforeEach(func() {
 			//set rpc.CallCoreCommand to a successful call
 			rpcHandlers.CallCoreCommandStub = func(_ []string, retVal *bool) error {
 				*retVal = true
@@ -38,7 +43,9 @@ var _ = Describe("DiegoEnabler", func() {
 				*retVal = []string{"{}"}
 				return nil
 			}
+		})
 
+		JustBeforeEach(func() {
 			ts, err = test_rpc_server.NewTestRpcServer(rpcHandlers)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -51,6 +58,16 @@ var _ = Describe("DiegoEnabler", func() {
 			ts.Stop()
 		})
 
+		It("fails with a dedicated message for an unknown command", func() {
+			session, err := gexec.Start(exec.Command(validPluginPath, ts.Port(), "enable-dieg"), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			session.Wait()
+			Expect(session).To(gbytes.Say("FAILED"))
+			Expect(session.Err).To(gbytes.Say("Unknown command 'enable-dieg'"))
+			Expect(session.ExitCode()).To(Equal(1))
+		})
+
 		Context("enable-diego", func() {
 			var args []string
 
@@ -64,13 +81,26 @@ var _ = Describe("DiegoEnabler", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				session.Wait()
-				Expect(session).To(gbytes.Say("required argument `APP_NAME` was not provided"))
+				Expect(session.Err).To(gbytes.Say("required argument `APP_NAME` was not provided"))
+				Expect(session).To(gbytes.Say("USAGE:\n   cf enable-diego \\(APP_NAME"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+
+			It("prints the usage for an unknown flag", func() {
+				args = []string{ts.Port(), "enable-diego", "--bogus", "test-app"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("unknown flag `bogus'"))
+				Expect(session).To(gbytes.Say("USAGE:"))
+				Expect(session.ExitCode()).To(Equal(1))
 			})
 
 			Context("when the args are properly provided", func() {
 				BeforeEach(func() {
 					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-						*retVal = plugin_models.GetAppModel{}
+						*retVal = plugin_models.GetAppModel{Diego: rpcHandlers.GetAppCallCount() > 1}
 						return nil
 					}
 				})
@@ -97,182 +127,1930 @@ var _ = Describe("DiegoEnabler", func() {
 					Expect(err).NotTo(HaveOccurred())
 
 					session.Wait()
-					Expect(rpcHandlers.CallCoreCommandCallCount()).To(Equal(1))
-
-					output, _ := rpcHandlers.CallCoreCommandArgsForCall(0)
-					Expect(output[1]).To(ContainSubstring("v2/apps/test-app-guid"))
-					Expect(output[5]).To(ContainSubstring(`"diego":true`))
+					puts := putCalls(rpcHandlers)
+					Expect(puts).To(HaveLen(1))
+					Expect(puts[0][1]).To(ContainSubstring("v2/apps/test-app-guid"))
+					Expect(puts[0][5]).To(ContainSubstring(`"diego":true`))
 				})
-			})
 
-			Context("when the app is not found", func() {
-				BeforeEach(func() {
-					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid"}
-						return errors.New("error in GetApp")
-					}
+				Context("when the Cloud Controller returns the updated app", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+							*retVal = []string{`{"metadata":{"guid":"test-app-guid"},"entity":{"diego":true}}`}
+							return nil
+						}
+					})
+
+					It("verifies the flag from the response without calling GetApp() again", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(1))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
 				})
 
-				It("exits with error when GetApp() returns error", func() {
-					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
-					Expect(err).NotTo(HaveOccurred())
+				Context("when the flag only shows up on a later read", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							diego := rpcHandlers.GetAppCallCount() >= 3
+							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: diego}
+							return nil
+						}
+					})
 
-					session.Wait()
+					It("reads it again before declaring failure", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
 
-					Expect(session).To(gbytes.Say("error in GetApp"))
-					Expect(session.ExitCode()).To(Equal(1))
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(3))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
 				})
-			})
 
-			Context("when the app was successfully changed to Diego", func() {
-				BeforeEach(func() {
-					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
-						return nil
-					}
+				Context("when the flag never shows up", func() {
+					It("gives up after three reads", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(4))
+						Expect(session.Err).To(gbytes.Say("The Diego flag of test-app was set, but verification shows it is NOT set to true"))
+						Expect(session.ExitCode()).To(Equal(3))
+					})
 				})
 
-				It("exit 0 after veriftying the flag is correct set", func() {
-					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
-					Expect(err).NotTo(HaveOccurred())
+				Context("when the CLI targets the Cloud Controller with --skip-ssl-validation", func() {
+					BeforeEach(func() {
+						rpcHandlers.IsSSLDisabledStub = func(_ string, retVal *bool) error {
+							*retVal = true
+							return nil
+						}
+					})
 
-					session.Wait()
+					It("refuses to set the flag", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
 
-					Expect(session).To(gbytes.Say("Verifying test-app Diego support is set to true"))
-					Expect(session).To(gbytes.Say("OK"))
-					Expect(session.ExitCode()).To(Equal(0))
-				})
-			})
+						session.Wait()
+						Expect(putCalls(rpcHandlers)).To(BeEmpty())
+						Expect(session.Err).To(gbytes.Say("Pass --insecure to set the Diego flag anyway"))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
 
-			Context("when the change to Diego failed", func() {
-				BeforeEach(func() {
-					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: false}
-						return nil
-					}
+					It("sets the flag when --insecure is given", func() {
+						args = append(args, "--insecure")
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(putCalls(rpcHandlers)).To(HaveLen(1))
+					})
 				})
 
-				It("exit 1 after veriftying the flag is not correct set", func() {
-					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
-					Expect(err).NotTo(HaveOccurred())
+				Context("when --no-wait is given", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--no-wait", "test-app"}
+					})
 
-					session.Wait()
+					It("does not call GetApp() again to verify the flag", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
 
-					Expect(session).To(gbytes.Say("Verifying test-app Diego support is set to true"))
-					Expect(session).To(gbytes.Say("FAILED"))
-					Expect(session.ExitCode()).To(Equal(1))
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(1))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
 				})
-			})
-		})
-
-		Context("disable-diego", func() {
-			var args []string
 
-			JustBeforeEach(func() {
-				args = []string{ts.Port(), "disable-diego", "test-app"}
-			})
+				Context("when --restart is given", func() {
+					var state string
 
-			It("needs APP_NAME as argument", func() {
-				args = []string{ts.Port(), "disable-diego"}
-				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
-				Expect(err).NotTo(HaveOccurred())
+					BeforeEach(func() {
+						state = "started"
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", State: state}
+							return nil
+						}
+					})
 
-				session.Wait()
-				Expect(session).To(gbytes.Say("required argument `APP_NAME` was not provided"))
-			})
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--no-wait", "--restart", "test-app"}
+					})
 
-			Context("when the app is found", func() {
-				BeforeEach(func() {
-					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid"}
-						return nil
+					restarts := func() [][]string {
+						var calls [][]string
+						for i := 0; i < rpcHandlers.CallCoreCommandCallCount(); i++ {
+							callArgs, _ := rpcHandlers.CallCoreCommandArgsForCall(i)
+							if callArgs[0] == "restart" {
+								calls = append(calls, callArgs)
+							}
+						}
+						return calls
 					}
-				})
 
-				It("sets diego flag with /v2/apps endpoint", func() {
-					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
-					Expect(err).NotTo(HaveOccurred())
+					It("restarts the app once its flag is set", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
 
-					session.Wait()
-					Expect(rpcHandlers.CallCoreCommandCallCount()).To(Equal(1))
+						session.Wait()
+						Expect(putCalls(rpcHandlers)).To(HaveLen(1))
+						Expect(restarts()).To(Equal([][]string{{"restart", "test-app"}}))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
 
-					output, _ := rpcHandlers.CallCoreCommandArgsForCall(0)
-					Expect(output[1]).To(ContainSubstring("v2/apps/test-app-guid"))
-					Expect(output[5]).To(ContainSubstring(`"diego":false`))
-				})
-			})
+					Context("when the app is stopped", func() {
+						BeforeEach(func() {
+							state = "stopped"
+						})
 
-			Context("has-diego-enabled", func() {
-				var args []string
+						It("leaves it stopped", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
 
-				JustBeforeEach(func() {
-					args = []string{ts.Port(), "has-diego-enabled", "test-app"}
+							session.Wait()
+							Expect(restarts()).To(BeEmpty())
+							Expect(session.Err).To(gbytes.Say("App test-app is not started, so it is not restarted"))
+							Expect(session.ExitCode()).To(Equal(0))
+						})
+					})
+
+					It("refuses --guid", func() {
+						args = []string{ts.Port(), "enable-diego", "--restart", "--guid", "test-app-guid"}
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("Cannot specify --restart together with --guid, --guids-file, --stdin, --space or --output json."))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
 				})
 
-				It("needs APP_NAME as argument", func() {
-					args = []string{ts.Port(), "has-diego-enabled"}
-					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
-					Expect(err).NotTo(HaveOccurred())
+				Context("when --dry-run is given", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--dry-run", "test-app"}
+					})
 
-					session.Wait()
-					Expect(session).To(gbytes.Say("required argument `APP_NAME` was not provided"))
+					It("reports the change without setting the diego flag", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(putCalls(rpcHandlers)).To(BeEmpty())
+						Expect(session).To(gbytes.Say("Would set test-app Diego support to true"))
+						Expect(session).To(gbytes.Say("DRY RUN — no changes made"))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
 				})
 
-				Context("when the params are properly provided", func() {
+				Context("when --guid is given", func() {
 					BeforeEach(func() {
-						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-							*retVal = plugin_models.GetAppModel{}
+						rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+							*retVal = []string{`{"metadata":{"guid":"other-app-guid"},"entity":{"diego":true}}`}
 							return nil
 						}
 					})
 
-					It("calls GetApp() to get app model", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--guid", "other-app-guid"}
+					})
 
+					It("sets the diego flag of that app without looking it up by name", func() {
 						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
 						Expect(err).NotTo(HaveOccurred())
 
 						session.Wait()
-						Expect(rpcHandlers.GetAppCallCount()).To(Equal(1))
+						Expect(session.ExitCode()).To(Equal(0))
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(0))
+
+						puts := putCalls(rpcHandlers)
+						Expect(puts).To(HaveLen(1))
+						Expect(puts[0][1]).To(ContainSubstring("v2/apps/other-app-guid"))
+					})
+
+					It("refuses an app name as well", func() {
+						args = append(args, "test-app")
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("Cannot specify --guid together with APP_NAME or --apps-file."))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+
+					Context("when the user lacks the SpaceDeveloper role", func() {
+						BeforeEach(func() {
+							var (
+								mu     sync.Mutex
+								curled []string
+							)
+							rpcHandlers.CallCoreCommandStub = func(args []string, retVal *bool) error {
+								mu.Lock()
+								defer mu.Unlock()
+								curled = args
+								*retVal = true
+								return nil
+							}
+							rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+								mu.Lock()
+								defer mu.Unlock()
+								switch {
+								case strings.Contains(strings.Join(curled, " "), "PUT"):
+									*retVal = []string{"HTTP/1.1 403 Forbidden", "", `{"code":10003,"description":"You are not authorized to perform the requested action","error_code":"CF-NotAuthorized"}`}
+								case curled[1] == "/v2/spaces/some-space-guid":
+									*retVal = []string{`{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space"}}`}
+								default:
+									*retVal = []string{`{"metadata":{"guid":"other-app-guid"},"entity":{"name":"other-app","space_guid":"some-space-guid"}}`}
+								}
+								return nil
+							}
+						})
+
+						It("names the space of the app", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Err).To(gbytes.Say("You need the SpaceDeveloper role in space some-space to change Diego for this app"))
+							Expect(session.ExitCode()).To(Equal(1))
+						})
 					})
 				})
 
-				Context("when the app does not exist", func() {
+				Context("when --stdin is given", func() {
 					BeforeEach(func() {
-						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-							*retVal = plugin_models.GetAppModel{Guid: ""}
+						rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+							*retVal = []string{`{"metadata":{"guid":"some-guid"},"entity":{"diego":true}}`}
 							return nil
 						}
 					})
 
-					It("notifies user app is not found", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--stdin"}
+					})
+
+					It("sets the diego flag of every app guid read from stdin", func() {
+						command := exec.Command(validPluginPath, args...)
+						command.Stdin = strings.NewReader("first-app-guid\n\nsecond-app-guid\n")
+						session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(0))
+						puts := putCalls(rpcHandlers)
+						Expect(puts).To(HaveLen(2))
+						Expect(puts[0][1]).To(ContainSubstring("v2/apps/first-app-guid"))
+						Expect(puts[1][1]).To(ContainSubstring("v2/apps/second-app-guid"))
+
+						Expect(session).To(gbytes.Say("Diego support set to true for 2 of 2 apps"))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
+
+					It("refuses an app name as well", func() {
+						args = append(args, "test-app")
 						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
 						Expect(err).NotTo(HaveOccurred())
 
 						session.Wait()
-						Expect(session).To(gbytes.Say("App test-app not found"))
+						Expect(session.Err).To(gbytes.Say("Cannot specify --stdin together with APP_NAME, --apps-file or --guid."))
 						Expect(session.ExitCode()).To(Equal(1))
 					})
 				})
 
-				Context("when the app is on Diego", func() {
+				Context("when --guids-file is given", func() {
+					var guidsFile string
+
 					BeforeEach(func() {
-						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
-							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
+						file, err := ioutil.TempFile("", "diego-enabler-guids")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = file.WriteString("first-app-guid\n\nsecond-app-guid\n")
+						Expect(err).NotTo(HaveOccurred())
+						Expect(file.Close()).To(Succeed())
+						guidsFile = file.Name()
+
+						rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+							*retVal = []string{`{"metadata":{"guid":"some-guid"},"entity":{"diego":true}}`}
 							return nil
 						}
 					})
 
-					It("outputs the app's Diego flag value", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--guids-file", guidsFile}
+					})
+
+					AfterEach(func() {
+						os.Remove(guidsFile)
+					})
+
+					It("sets the diego flag of every app guid in the file", func() {
 						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
 						Expect(err).NotTo(HaveOccurred())
 
 						session.Wait()
-						Expect(session).To(gbytes.Say("true"))
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(0))
+						puts := putCalls(rpcHandlers)
+						Expect(puts).To(HaveLen(2))
+						Expect(puts[0][1]).To(ContainSubstring("v2/apps/first-app-guid"))
+						Expect(puts[1][1]).To(ContainSubstring("v2/apps/second-app-guid"))
+
+						Expect(session).To(gbytes.Say("Diego support set to true for 2 of 2 apps"))
 						Expect(session.ExitCode()).To(Equal(0))
 					})
+
+					It("refuses --stdin as well", func() {
+						args = append(args, "--stdin")
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("Cannot specify --guids-file together with APP_NAME, --apps-file, --guid or --stdin."))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+				})
+
+				Context("when --output json is given", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--output", "json", "test-app"}
+					})
+
+					Context("when the flag is set", func() {
+						BeforeEach(func() {
+							rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+								*retVal = []string{`{"metadata":{"guid":"test-app-guid"},"entity":{"diego":true}}`}
+								return nil
+							}
+						})
+
+						It("prints the verified outcome as JSON and nothing else", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.ExitCode()).To(Equal(0))
+							Expect(session.Out.Contents()).To(MatchJSON(`{"name":"test-app","guid":"test-app-guid","requested":true,"verified":true}`))
+						})
+
+						It("logs the progress to stderr", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Err).To(gbytes.Say("Setting test-app Diego support to true"))
+							Expect(session.Err).To(gbytes.Say("OK"))
+						})
+
+						It("logs nothing but errors with --quiet", func() {
+							args = append(args, "--quiet")
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Err.Contents()).To(BeEmpty())
+							Expect(session.Out.Contents()).To(MatchJSON(`{"name":"test-app","guid":"test-app-guid","requested":true,"verified":true}`))
+						})
+					})
+
+					It("prints verified false and exits non-zero when the flag is not set", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.ExitCode()).To(Equal(3))
+						Expect(session.Out.Contents()).To(MatchJSON(`{"name":"test-app","guid":"test-app-guid","requested":true,"verified":false}`))
+						Expect(session.Err).To(gbytes.Say("The Diego flag of test-app was set, but verification shows it is NOT set to true"))
+					})
+
+					It("refuses --apps-file, --guid, --stdin and --dry-run", func() {
+						args = []string{ts.Port(), "enable-diego", "--output", "json", "--dry-run", "test-app"}
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("Cannot specify --output json together with --apps-file, --guid, --guids-file, --stdin or --dry-run."))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
 				})
 
+				Context("when --quiet is given", func() {
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--quiet", "--no-wait", "test-app"}
+					})
+
+					It("does not print progress messages", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.ExitCode()).To(Equal(0))
+						Expect(session.Out.Contents()).To(BeEmpty())
+					})
+				})
 			})
-		})
-	})
-})
+
+			Context("when the app name is used in several spaces", func() {
+				BeforeEach(func() {
+					rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+						*retVal = []string{`{"resources":[`,
+							`{"metadata":{"guid":"dev-guid"},"entity":{"diego":false,"space":{"entity":{"name":"dev"}}}},`,
+							`{"metadata":{"guid":"prod-guid"},"entity":{"diego":false,"space":{"entity":{"name":"prod"}}}}`,
+							`]}`}
+						return nil
+					}
+				})
+
+				It("refuses to guess which app is meant", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say(`App test-app exists in several spaces \(dev, prod\). Pick one with --space or --guid.`))
+					Expect(putCalls(rpcHandlers)).To(BeEmpty())
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+
+				It("sets the flag of the app in the space given with --space", func() {
+					args = []string{ts.Port(), "enable-diego", "--no-wait", "--space", "prod", "test-app"}
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					puts := putCalls(rpcHandlers)
+					Expect(puts).To(HaveLen(1))
+					Expect(puts[0][1]).To(ContainSubstring("v2/apps/prod-guid"))
+					Expect(rpcHandlers.GetAppCallCount()).To(Equal(0))
+					Expect(session.ExitCode()).To(Equal(0))
+				})
+
+				It("refuses --space together with --guid", func() {
+					args = []string{ts.Port(), "enable-diego", "--space", "prod", "--guid", "prod-guid"}
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("Cannot specify --space together with --guid, --guids-file or --stdin."))
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("when the app is not found", func() {
+				BeforeEach(func() {
+					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid"}
+						return errors.New("error in GetApp")
+					}
+				})
+
+				It("exits with error when GetApp() returns error", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("error in GetApp"))
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("when the app is already on Diego", func() {
+				BeforeEach(func() {
+					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
+						return nil
+					}
+				})
+
+				It("exits 0 without setting the flag", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("App test-app is already set to Diego=true, no change needed"))
+					Expect(putCalls(rpcHandlers)).To(BeEmpty())
+					Expect(session.ExitCode()).To(Equal(0))
+				})
+			})
+
+			Context("when the app was successfully changed to Diego", func() {
+				BeforeEach(func() {
+					lookups := 0
+					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+						lookups++
+						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: lookups > 1}
+						return nil
+					}
+				})
+
+				It("exit 0 after veriftying the flag is correct set", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("Verifying test-app Diego support is set to true"))
+					Expect(session).To(gbytes.Say("OK"))
+					Expect(session.ExitCode()).To(Equal(0))
+				})
+
+				It("logs only warnings with --log-level warn", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, append(args, "--log-level", "warn")...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).NotTo(gbytes.Say("Setting test-app Diego support"))
+					Expect(session).NotTo(gbytes.Say("OK"))
+					Expect(session.ExitCode()).To(Equal(0))
+				})
+			})
+
+			Context("when the app is on a stack that Diego cannot run", func() {
+				BeforeEach(func() {
+					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+						*retVal = plugin_models.GetAppModel{
+							Guid:  "test-app-guid",
+							Diego: rpcHandlers.GetAppCallCount() > 1,
+							Stack: &plugin_models.GetApp_Stack{Name: "lucid64"},
+						}
+						return nil
+					}
+				})
+
+				It("refuses to set the flag", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("App test-app is on stack lucid64, which cannot run on Diego. Pass --force to enable Diego support anyway."))
+					Expect(putCalls(rpcHandlers)).To(BeEmpty())
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+
+				It("sets the flag with a warning when --force is given", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, append(args, "--force")...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("Warning: App test-app is on stack lucid64, which cannot run on Diego"))
+					Expect(putCalls(rpcHandlers)).To(HaveLen(1))
+					Expect(session.ExitCode()).To(Equal(0))
+				})
+
+				Context("when the app is given with --guid", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+							*retVal = []string{`{"metadata":{"guid":"test-app-guid"},"entity":{"diego":true,"stack":{"entity":{"name":"lucid64"}}}}`}
+							return nil
+						}
+					})
+
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "enable-diego", "--guid", "test-app-guid"}
+					})
+
+					It("refuses to set the flag", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+
+						Expect(session.Err).To(gbytes.Say("App test-app-guid is on stack lucid64, which cannot run on Diego. Pass --force to enable Diego support anyway."))
+						Expect(putCalls(rpcHandlers)).To(BeEmpty())
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+
+					It("sets the flag with a warning when --force is given", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, append(args, "--force")...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+
+						Expect(session.Err).To(gbytes.Say("Warning: App test-app-guid is on stack lucid64, which cannot run on Diego"))
+						Expect(putCalls(rpcHandlers)).To(HaveLen(1))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when the change to Diego failed", func() {
+				BeforeEach(func() {
+					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: false}
+						return nil
+					}
+				})
+
+				It("exits 3 after verifying the flag is not correctly set", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(session.Err).To(gbytes.Say("Verifying test-app Diego support is set to true"))
+					Expect(session).To(gbytes.Say("FAILED"))
+					Expect(session.Err).To(gbytes.Say("The Diego flag of test-app was set, but verification shows it is NOT set to true"))
+					Expect(session.ExitCode()).To(Equal(3))
+				})
+
+				Context("when the Cloud Controller refuses to set the flag", func() {
+					BeforeEach(func() {
+						var (
+							mu  sync.Mutex
+							put bool
+						)
+						rpcHandlers.CallCoreCommandStub = func(args []string, retVal *bool) error {
+							mu.Lock()
+							defer mu.Unlock()
+							put = len(args) > 3 && args[3] == "PUT"
+							*retVal = true
+							return nil
+						}
+						rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+							mu.Lock()
+							defer mu.Unlock()
+							if put {
+								*retVal = []string{`{"code":170001,"description":"Staging error","error_code":"CF-StagingError"}`}
+							} else {
+								*retVal = []string{`{"resources":[]}`}
+							}
+							return nil
+						}
+					})
+
+					It("exits 1 with a failure to set the flag, without verifying it", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("Failed to set the Diego flag of test-app: Staging error"))
+						Expect(session.Err).NotTo(gbytes.Say("Verifying"))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+				})
+			})
+
+			Context("when an apps file is given", func() {
+				var appsFile string
+
+				BeforeEach(func() {
+					file, err := ioutil.TempFile("", "diego-enabler-apps")
+					Expect(err).NotTo(HaveOccurred())
+					_, err = file.WriteString("test-app\n\nmissing-app\n")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(file.Close()).To(Succeed())
+					appsFile = file.Name()
+
+					lookups := 0
+					rpcHandlers.GetAppStub = func(appName string, retVal *plugin_models.GetAppModel) error {
+						if appName == "missing-app" {
+							return errors.New("App missing-app not found")
+						}
+						lookups++
+						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: lookups > 1}
+						return nil
+					}
+				})
+
+				JustBeforeEach(func() {
+					args = []string{ts.Port(), "enable-diego", "--apps-file", appsFile}
+				})
+
+				AfterEach(func() {
+					os.Remove(appsFile)
+				})
+
+				It("attempts every app, summarizes the failures and exits 2 for a partial failure", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+
+					Expect(putCalls(rpcHandlers)).To(HaveLen(1))
+					Expect(session).To(gbytes.Say("Diego support set to true for 1 of 2 apps"))
+					Expect(session.Err).To(gbytes.Say("missing-app: App missing-app not found"))
+					Expect(session.ExitCode()).To(Equal(2))
+				})
+			})
+		})
+
+		Context("disable-diego", func() {
+			var args []string
+
+			JustBeforeEach(func() {
+				args = []string{ts.Port(), "disable-diego", "test-app"}
+			})
+
+			It("needs APP_NAME as argument", func() {
+				args = []string{ts.Port(), "disable-diego"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("required argument `APP_NAME` was not provided"))
+			})
+
+			Context("when the app is found", func() {
+				BeforeEach(func() {
+					rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+						*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
+						return nil
+					}
+				})
+
+				It("sets diego flag with /v2/apps endpoint", func() {
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+					puts := putCalls(rpcHandlers)
+					Expect(puts).To(HaveLen(1))
+					Expect(puts[0][1]).To(ContainSubstring("v2/apps/test-app-guid"))
+					Expect(puts[0][5]).To(ContainSubstring(`"diego":false`))
+				})
+			})
+
+			Context("has-diego-enabled", func() {
+				var args []string
+
+				JustBeforeEach(func() {
+					args = []string{ts.Port(), "has-diego-enabled", "test-app"}
+				})
+
+				It("needs APP_NAME as argument", func() {
+					args = []string{ts.Port(), "has-diego-enabled"}
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+					Expect(session.Err).To(gbytes.Say("required argument `APP_NAME` was not provided"))
+				})
+
+				Context("when the params are properly provided", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{}
+							return nil
+						}
+					})
+
+					It("calls GetApp() to get app model", func() {
+
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when the app does not exist", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: ""}
+							return nil
+						}
+					})
+
+					It("notifies user app is not found", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("App test-app not found"))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+				})
+
+				Context("when several apps are given", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(appName string, retVal *plugin_models.GetAppModel) error {
+							switch appName {
+							case "missing-app":
+								*retVal = plugin_models.GetAppModel{}
+							default:
+								*retVal = plugin_models.GetAppModel{Guid: appName + "-guid", Diego: appName == "diego-app"}
+							}
+							return nil
+						}
+					})
+
+					JustBeforeEach(func() {
+						args = []string{ts.Port(), "has-diego-enabled", "diego-app", "missing-app", "dea-app"}
+					})
+
+					It("prints a line per app and fails if any app was not found", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(rpcHandlers.GetAppCallCount()).To(Equal(3))
+						Expect(session).To(gbytes.Say("diego-app: true"))
+						Expect(session).To(gbytes.Say("missing-app: not found"))
+						Expect(session).To(gbytes.Say("dea-app: false"))
+						Expect(session.Err).To(gbytes.Say("Could not determine Diego support for 1 of 3 apps"))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+
+					Context("when interrupted while checking the apps", func() {
+						var (
+							mu      sync.Mutex
+							session *gexec.Session
+						)
+
+						BeforeEach(func() {
+							rpcHandlers.GetAppStub = func(appName string, retVal *plugin_models.GetAppModel) error {
+								mu.Lock()
+								process := session.Command.Process
+								mu.Unlock()
+
+								// Ctrl-C while the first app is looked up
+								process.Signal(os.Interrupt)
+								time.Sleep(200 * time.Millisecond)
+								*retVal = plugin_models.GetAppModel{Guid: appName + "-guid", Diego: true}
+								return nil
+							}
+						})
+
+						It("stops before the next app on the first Ctrl-C", func() {
+							mu.Lock()
+							var err error
+							session, err = gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							mu.Unlock()
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(rpcHandlers.GetAppCallCount()).To(Equal(1))
+							Expect(session.Err).To(gbytes.Say("Interrupted"))
+							Expect(session.ExitCode()).To(Equal(1))
+						})
+					})
+
+					Context("when --output json is given", func() {
+						JustBeforeEach(func() {
+							args = []string{ts.Port(), "has-diego-enabled", "--output", "json", "diego-app", "missing-app", "dea-app"}
+						})
+
+						It("prints the apps it found as a JSON array and the others on stderr", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Err).To(gbytes.Say("missing-app: not found"))
+							Expect(session.Out).To(gbytes.Say(`\[{"name":"diego-app","guid":"diego-app-guid","diego":true},{"name":"dea-app","guid":"dea-app-guid","diego":false}\]`))
+							Expect(session.ExitCode()).To(Equal(1))
+						})
+
+						It("writes FAILED to stderr so that stdout is only the JSON", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Out.Contents()).To(MatchJSON(`[{"name":"diego-app","guid":"diego-app-guid","diego":true},{"name":"dea-app","guid":"dea-app-guid","diego":false}]`))
+							Expect(session.Err).To(gbytes.Say("FAILED"))
+						})
+					})
+				})
+
+				Context("when the app is on Diego", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
+							return nil
+						}
+					})
+
+					It("outputs the app's Diego flag value", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session).To(gbytes.Say("true"))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
+
+					Context("when --output json is given", func() {
+						JustBeforeEach(func() {
+							args = []string{ts.Port(), "has-diego-enabled", "--output", "json", "test-app"}
+						})
+
+						It("prints the app as a JSON object", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Out.Contents()).To(MatchJSON(`{"name":"test-app","guid":"test-app-guid","diego":true}`))
+							Expect(session.ExitCode()).To(Equal(0))
+						})
+					})
+
+					Context("when --show-location is given", func() {
+						var cc *httptest.Server
+
+						BeforeEach(func() {
+							cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+								// only the space of the app is fetched, not every space
+								if r.URL.Path != "/v2/spaces/test-space-guid" {
+									w.WriteHeader(http.StatusNotFound)
+									return
+								}
+								w.Write([]byte(`{"metadata":{"guid":"test-space-guid"},"entity":{"name":"test-space","organization":{"entity":{"name":"test-org"}}}}`))
+							}))
+
+							rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+								*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true, SpaceGuid: "test-space-guid"}
+								return nil
+							}
+							rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+								*retVal = true
+								return nil
+							}
+							rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+								*retVal = cc.URL
+								return nil
+							}
+							rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+								*retVal = "bearer some-token"
+								return nil
+							}
+						})
+
+						AfterEach(func() {
+							cc.Close()
+						})
+
+						JustBeforeEach(func() {
+							args = []string{ts.Port(), "has-diego-enabled", "--show-location", "test-app"}
+						})
+
+						It("prints the org and space of the app", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session).To(gbytes.Say(`true \(org test-org / space test-space\)`))
+							Expect(session.ExitCode()).To(Equal(0))
+						})
+					})
+				})
+
+				Context("when the app is not on Diego", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: false}
+							return nil
+						}
+					})
+
+					It("outputs the app's Diego flag value and exits with status 3", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session).To(gbytes.Say("false"))
+						Expect(session).NotTo(gbytes.Say("FAILED"))
+						Expect(session.ExitCode()).To(Equal(3))
+					})
+
+					Context("when --quiet is given", func() {
+						JustBeforeEach(func() {
+							args = []string{ts.Port(), "has-diego-enabled", "--quiet", "test-app"}
+						})
+
+						It("only reports through the exit status", func() {
+							session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+							Expect(err).NotTo(HaveOccurred())
+
+							session.Wait()
+							Expect(session.Out.Contents()).To(BeEmpty())
+							Expect(session.ExitCode()).To(Equal(3))
+						})
+					})
+				})
+			})
+
+			Context("has-dea-enabled", func() {
+				var args []string
+
+				JustBeforeEach(func() {
+					args = []string{ts.Port(), "has-dea-enabled", "test-app"}
+				})
+
+				Context("when the app does not exist", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: ""}
+							return nil
+						}
+					})
+
+					It("notifies user app is not found", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session.Err).To(gbytes.Say("App test-app not found"))
+						Expect(session.ExitCode()).To(Equal(1))
+					})
+				})
+
+				Context("when the app is on the DEAs", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: false}
+							return nil
+						}
+					})
+
+					It("outputs true", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session).To(gbytes.Say("true"))
+						Expect(session.ExitCode()).To(Equal(0))
+					})
+				})
+
+				Context("when the app is on Diego", func() {
+					BeforeEach(func() {
+						rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+							*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
+							return nil
+						}
+					})
+
+					It("outputs false and exits with status 3", func() {
+						session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+						Expect(err).NotTo(HaveOccurred())
+
+						session.Wait()
+						Expect(session).To(gbytes.Say("false"))
+						Expect(session).NotTo(gbytes.Say("FAILED"))
+						Expect(session.ExitCode()).To(Equal(3))
+					})
+				})
+			})
+		})
+
+		Context("diego-report --compare", func() {
+			var before, after string
+
+			writeListing := func(contents string) string {
+				file, err := ioutil.TempFile("", "diego-enabler-listing")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = file.WriteString(contents)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file.Close()).To(Succeed())
+				return file.Name()
+			}
+
+			BeforeEach(func() {
+				before = writeListing(`[{"name":"web","guid":"web-guid","diego":false},{"name":"old","guid":"old-guid","diego":false}]`)
+				after = writeListing(`[{"name":"web","guid":"web-guid","diego":true}]`)
+			})
+
+			AfterEach(func() {
+				os.Remove(before)
+				os.Remove(after)
+			})
+
+			It("prints the apps that changed between the listings", func() {
+				args := []string{ts.Port(), "diego-report", "--compare", before, after}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session).To(gbytes.Say("removed: old \\(old-guid\\)"))
+				Expect(session).To(gbytes.Say("changed: web \\(web-guid\\)\n   diego: \"false\" -> \"true\""))
+				Expect(session).To(gbytes.Say("0 added, 1 removed, 1 changed"))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+
+			It("needs both listings", func() {
+				args := []string{ts.Port(), "diego-report", "--compare", before}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("--compare needs an old and a new app listing"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("enable-diego-in-org", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"app-guid"},"entity":{"name":"some-app","diego":false,"space_guid":"some-space-guid"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+				rpcHandlers.GetOrgStub = func(_ string, retVal *plugin_models.GetOrg_Model) error {
+					retVal.Guid = "some-org-guid"
+					retVal.Name = "some-org"
+					retVal.Spaces = []plugin_models.GetOrg_Space{{Guid: "some-space-guid", Name: "some-space"}}
+					return nil
+				}
+				rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+					*retVal = []string{"HTTP/1.1 403 Forbidden", "", `{"code":10003,"description":"You are not authorized to perform the requested action","error_code":"CF-NotAuthorized"}`}
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("names the space of an app the user lacks the SpaceDeveloper role for", func() {
+				args := []string{ts.Port(), "enable-diego-in-org", "some-org", "-f"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(putCalls(rpcHandlers)).To(HaveLen(1))
+				Expect(session.Out).To(gbytes.Say("You need the SpaceDeveloper role in space some-space to change Diego for this app"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("app-names-from-guids", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v2/spaces/test-space-guid" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Write([]byte(`{"metadata":{"guid":"test-space-guid"},"entity":{"name":"test-space","organization":{"entity":{"name":"test-org"}}}}`))
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+			})
+
+			BeforeEach(func() {
+				var (
+					mu     sync.Mutex
+					curled string
+				)
+				rpcHandlers.CallCoreCommandStub = func(args []string, retVal *bool) error {
+					mu.Lock()
+					defer mu.Unlock()
+					curled = args[1]
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.GetOutputAndResetStub = func(_ bool, retVal *[]string) error {
+					mu.Lock()
+					defer mu.Unlock()
+					if curled == "/v2/apps/test-app-guid" {
+						*retVal = []string{`{"metadata":{"guid":"test-app-guid"},"entity":{"name":"test-app","space_guid":"test-space-guid"}}`}
+					} else {
+						*retVal = []string{`{"code":100004,"description":"The app could not be found","error_code":"CF-AppNotFound"}`}
+					}
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("prints the name, space and org of each guid and reports unknown guids", func() {
+				command := exec.Command(validPluginPath, ts.Port(), "app-names-from-guids", "--stdin")
+				command.Stdin = strings.NewReader("test-app-guid\nmissing-guid\n")
+				session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session).To(gbytes.Say(`test-app-guid +test-app +test-space +test-org`))
+				Expect(session).To(gbytes.Say(`missing-guid +not found`))
+				Expect(session.Err).To(gbytes.Say("1 of 2 app guids were not found"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+
+			Context("when interrupted while looking up the guids", func() {
+				var (
+					mu      sync.Mutex
+					session *gexec.Session
+					curls   int
+				)
+
+				BeforeEach(func() {
+					curls = 0
+					rpcHandlers.CallCoreCommandStub = func(args []string, retVal *bool) error {
+						mu.Lock()
+						curls++
+						process := session.Command.Process
+						mu.Unlock()
+
+						// Ctrl-C while the first guid is looked up
+						process.Signal(os.Interrupt)
+						time.Sleep(200 * time.Millisecond)
+						*retVal = true
+						return nil
+					}
+				})
+
+				It("stops before the next guid on the first Ctrl-C without printing the table", func() {
+					command := exec.Command(validPluginPath, ts.Port(), "app-names-from-guids", "--stdin")
+					command.Stdin = strings.NewReader("test-app-guid\nother-guid\nmissing-guid\n")
+					mu.Lock()
+					var err error
+					session, err = gexec.Start(command, GinkgoWriter, GinkgoWriter)
+					mu.Unlock()
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+					mu.Lock()
+					Expect(curls).To(Equal(1))
+					mu.Unlock()
+					Expect(session.Out).NotTo(gbytes.Say("test-app-guid"))
+					Expect(session.Err).To(gbytes.Say("Interrupted"))
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+			})
+
+			It("needs either --guids-file or --stdin", func() {
+				session, err := gexec.Start(exec.Command(validPluginPath, ts.Port(), "app-names-from-guids"), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("Specify either --guids-file or --stdin."))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("--show-api-info", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v2/info" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Write([]byte(`{"name":"vcap","api_version":"2.54.0","default_to_diego_backend":true}`))
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+				rpcHandlers.GetAppStub = func(_ string, retVal *plugin_models.GetAppModel) error {
+					*retVal = plugin_models.GetAppModel{Guid: "test-app-guid", Diego: true}
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("prints the API version and default runtime to stderr before running the command", func() {
+				args := []string{ts.Port(), "has-diego-enabled", "--show-api-info", "test-app"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("API version: +2.54.0"))
+				Expect(session.Err).To(gbytes.Say("Diego default: +true"))
+				Expect(session.Out).To(gbytes.Say("true"))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+		})
+
+		Context("apps-by-runtime", func() {
+			It("only accepts true or false for --diego", func() {
+				args := []string{ts.Port(), "apps-by-runtime", "--diego", "maybe"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("Invalid value `maybe' for option `--diego'"))
+				Expect(session).To(gbytes.Say("USAGE:"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("diego-enabler-doctor", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/info":
+						w.Write([]byte(`{"name":"vcap","api_version":"2.54.0"}`))
+					case "/v2/organizations":
+						w.Write([]byte(`{"total_results":1,"total_pages":1,"resources":[{}]}`))
+					case "/v2/spaces":
+						w.Write([]byte(`{"total_results":0,"total_pages":0,"resources":[]}`))
+					default:
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+				rpcHandlers.UsernameStub = func(_ string, retVal *string) error {
+					*retVal = "some-user"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			Context("when the user is logged in", func() {
+				BeforeEach(func() {
+					rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+						*retVal = true
+						return nil
+					}
+				})
+
+				It("prints a checklist with a hint for each failed check", func() {
+					args := []string{ts.Port(), "diego-enabler-doctor"}
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+					Expect(session.Out).To(gbytes.Say(`\[PASS\] Logged in: as some-user`))
+					Expect(session.Out).To(gbytes.Say(`\[PASS\] Access token: present`))
+					Expect(session.Out).To(gbytes.Say(`\[PASS\] API reachable: .*API version 2.54.0`))
+					Expect(session.Out).To(gbytes.Say(`\[PASS\] Org visibility: 1 organizations`))
+					Expect(session.Out).To(gbytes.Say(`\[FAIL\] Space visibility: No spaces are visible`))
+					Expect(session.Out).To(gbytes.Say("Ask a space manager"))
+					Expect(session.Out).To(gbytes.Say("1 checks failed"))
+					Expect(session.Out).NotTo(gbytes.Say("FAILED"))
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+			})
+
+			Context("when the user is not logged in", func() {
+				BeforeEach(func() {
+					rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+						*retVal = false
+						return nil
+					}
+				})
+
+				It("skips the checks that need the user to be logged in", func() {
+					args := []string{ts.Port(), "diego-enabler-doctor"}
+					session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+
+					session.Wait()
+					Expect(session.Out).To(gbytes.Say(`\[FAIL\] Logged in: You must be logged in`))
+					Expect(session.Out).To(gbytes.Say("Run cf login"))
+					Expect(session.Out).To(gbytes.Say(`\[SKIP\] Access token`))
+					Expect(session.Out).To(gbytes.Say(`\[SKIP\] API reachable`))
+					Expect(session.Out).To(gbytes.Say(`\[SKIP\] Org visibility`))
+					Expect(session.Out).To(gbytes.Say(`\[SKIP\] Space visibility`))
+					Expect(session.ExitCode()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("diego-apps --modifiable-only", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[` +
+							`{"metadata":{"guid":"dev-app-guid"},"entity":{"name":"dev-app","diego":true,"space_guid":"dev-space-guid"}},` +
+							`{"metadata":{"guid":"audited-app-guid"},"entity":{"name":"audited-app","diego":true,"space_guid":"audited-space-guid"}}]}`))
+					case "/v2/spaces":
+						if r.URL.Query().Get("q") == "developer_guid:some-user-guid" {
+							w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"dev-space-guid"},"entity":{"name":"dev-space"}}]}`))
+							return
+						}
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"dev-space-guid"},"entity":{"name":"dev-space"}},{"metadata":{"guid":"audited-space-guid"},"entity":{"name":"audited-space"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+				rpcHandlers.UserGuidStub = func(_ string, retVal *string) error {
+					*retVal = "some-user-guid"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("only lists the apps in spaces where the user is a developer", func() {
+				args := []string{ts.Port(), "diego-apps", "--modifiable-only", "--guids"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(string(session.Out.Contents())).To(Equal("dev-app-guid\n"))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+		})
+
+		Context("diego-apps --resolve-spaces lazy", func() {
+			var (
+				cc            *httptest.Server
+				spaceRequests []string
+				listedSpaces  bool
+			)
+
+			BeforeEach(func() {
+				spaceRequests = nil
+				listedSpaces = false
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[` +
+							`{"metadata":{"guid":"app-1-guid"},"entity":{"name":"app-1","diego":true,"space_guid":"some-space-guid"}},` +
+							`{"metadata":{"guid":"app-2-guid"},"entity":{"name":"app-2","diego":true,"space_guid":"some-space-guid"}}]}`))
+					case "/v2/spaces":
+						listedSpaces = true
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					case "/v2/spaces/some-space-guid":
+						spaceRequests = append(spaceRequests, r.URL.Path)
+						w.Write([]byte(`{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space","organization":{"metadata":{"guid":"some-org-guid"},"entity":{"name":"some-org"}}}}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("fetches each space of the listed apps once instead of every space", func() {
+				args := []string{ts.Port(), "diego-apps", "--resolve-spaces", "lazy", "--columns", "name,space,org"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Out).To(gbytes.Say(`app-1\s+some-space\s+some-org`))
+				Expect(session.Out).To(gbytes.Say(`app-2\s+some-space\s+some-org`))
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(spaceRequests).To(HaveLen(1))
+				Expect(listedSpaces).To(BeFalse())
+			})
+		})
+
+		Context("diego-apps -o ORG", func() {
+			var (
+				cc           *httptest.Server
+				spaceQueries []string
+			)
+
+			BeforeEach(func() {
+				spaceQueries = nil
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"app-guid"},"entity":{"name":"some-app","diego":true,"space_guid":"some-space-guid"}}]}`))
+					case "/v2/spaces":
+						spaceQueries = append(spaceQueries, r.URL.Query().Get("q"))
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+				rpcHandlers.GetOrgStub = func(_ string, retVal *plugin_models.GetOrg_Model) error {
+					retVal.Guid = "some-org-guid"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("only fetches the spaces of that org", func() {
+				args := []string{ts.Port(), "diego-apps", "-o", "some-org", "--columns", "name,space"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Out).To(gbytes.Say(`some-app\s+some-space`))
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(spaceQueries).To(Equal([]string{"organization_guid:some-org-guid"}))
+			})
+		})
+
+		Context("diego-apps --limit", func() {
+			var (
+				cc        *httptest.Server
+				mu        sync.Mutex
+				pageSizes map[string][]string
+			)
+
+			BeforeEach(func() {
+				pageSizes = map[string][]string{}
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					pageSizes[r.URL.Path] = append(pageSizes[r.URL.Path], r.URL.Query().Get("results-per-page"))
+					mu.Unlock()
+
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":2,"resources":[{"metadata":{"guid":"app-guid"},"entity":{"name":"some-app","diego":true,"space_guid":"some-space-guid"}}]}`))
+					case "/v2/spaces":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("only limits the page size of the apps", func() {
+				args := []string{ts.Port(), "diego-apps", "--limit", "1", "--columns", "name,space"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Out).To(gbytes.Say(`some-app\s+some-space`))
+				Expect(session.ExitCode()).To(Equal(0))
+
+				mu.Lock()
+				defer mu.Unlock()
+				Expect(pageSizes["/v2/apps"]).To(Equal([]string{"1"}))
+				Expect(pageSizes["/v2/spaces"]).NotTo(ContainElement("1"))
+			})
+		})
+
+		Context("diego-apps without --api", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"app-guid"},"entity":{"name":"some-app","diego":true,"space_guid":"some-space-guid"}}]}`))
+					case "/v2/spaces":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+				rpcHandlers.GetOrgStub = func(_ string, retVal *plugin_models.GetOrg_Model) error {
+					retVal.Guid = "some-org-guid"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("lists the apps of the targeted endpoint", func() {
+				args := []string{ts.Port(), "diego-apps", "--columns", "name,space"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Out).To(gbytes.Say(`some-app\s+some-space`))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+
+			It("accepts -o ORG", func() {
+				args := []string{ts.Port(), "diego-apps", "-o", "some-org", "--columns", "name,space"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).NotTo(gbytes.Say("--api cannot be combined"))
+				Expect(session.Out).To(gbytes.Say(`some-app\s+some-space`))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+		})
+
+		Context("diego-apps --migratable", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[` +
+							`{"metadata":{"guid":"staged-guid"},"entity":{"name":"staged-app","diego":true,"space_guid":"some-space-guid","package_state":"STAGED"}},` +
+							`{"metadata":{"guid":"failed-guid"},"entity":{"name":"failed-app","diego":true,"space_guid":"some-space-guid","package_state":"FAILED"}}]}`))
+					case "/v2/spaces":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("leaves out the apps whose package failed to stage", func() {
+				args := []string{ts.Port(), "diego-apps", "--migratable", "--guids"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(string(session.Out.Contents())).To(Equal("staged-guid\n"))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+		})
+
+		Context("diego-apps --strict --output ndjson", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[` +
+							`{"metadata":{"guid":"visible-guid"},"entity":{"name":"visible-app","diego":true,"space_guid":"some-space-guid"}},` +
+							`{"metadata":{"guid":"hidden-guid"},"entity":{"name":"hidden-app","diego":true,"space_guid":"hidden-space-guid"}}]}`))
+					case "/v2/spaces":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"some-space-guid"},"entity":{"name":"some-space"}}]}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("fails without printing any app when one is in a space the user cannot see", func() {
+				args := []string{ts.Port(), "diego-apps", "--strict", "--output", "ndjson"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Out).NotTo(gbytes.Say("visible-app"))
+				Expect(session.Err).To(gbytes.Say("hidden-app"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+		})
+
+		Context("diego-apps with a space-scoped token", func() {
+			var cc *httptest.Server
+
+			BeforeEach(func() {
+				cc = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v2/apps":
+						w.Write([]byte(`{"total_pages":1,"resources":[{"metadata":{"guid":"app-guid"},"entity":{"name":"some-app","diego":true,"space_guid":"some-space-guid"}}]}`))
+					case "/v2/spaces":
+						w.WriteHeader(http.StatusForbidden)
+						w.Write([]byte(`{"code":10003,"description":"You are not authorized to perform the requested action","error_code":"CF-NotAuthorized"}`))
+					default:
+						w.Write([]byte(`{"total_pages":1,"resources":[]}`))
+					}
+				}))
+
+				rpcHandlers.IsLoggedInStub = func(_ string, retVal *bool) error {
+					*retVal = true
+					return nil
+				}
+				rpcHandlers.ApiEndpointStub = func(_ string, retVal *string) error {
+					*retVal = cc.URL
+					return nil
+				}
+				rpcHandlers.AccessTokenStub = func(_ string, retVal *string) error {
+					*retVal = "bearer some-token"
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				cc.Close()
+			})
+
+			It("lists the apps with the guid of their space and warns once", func() {
+				args := []string{ts.Port(), "diego-apps", "--columns", "name,space"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Out).To(gbytes.Say(`some-app\s+some-space-guid`))
+				Expect(strings.Count(string(session.Err.Contents()), "Warning: Your token is not allowed to list spaces")).To(Equal(1))
+				Expect(session.ExitCode()).To(Equal(0))
+			})
+		})
+
+		Context("diego-apps", func() {
+			It("rejects unknown columns", func() {
+				args := []string{ts.Port(), "diego-apps", "--columns", "name,banana"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("Invalid column: banana"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+
+			It("rejects malformed name filters", func() {
+				args := []string{ts.Port(), "diego-apps", "--name-filter", "web-["}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("Invalid name filter web-\\["))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+
+			It("rejects malformed exclude name filters", func() {
+				args := []string{ts.Port(), "diego-apps", "--exclude-name-filter", "apps-manager["}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("Invalid name filter apps-manager\\["))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+
+			It("rejects --stopped together with --started", func() {
+				args := []string{ts.Port(), "diego-apps", "--stopped", "--started"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("--stopped cannot be combined with --started"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+
+			It("rejects --api together with --org", func() {
+				args := []string{ts.Port(), "diego-apps", "--api", "https://api.example.com", "-o", "some-org"}
+				session, err := gexec.Start(exec.Command(validPluginPath, args...), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				session.Wait()
+				Expect(session.Err).To(gbytes.Say("--api cannot be combined with --org or --space"))
+				Expect(session.ExitCode()).To(Equal(1))
+			})
+		})
+	})
+})
+
+// putCalls returns the args of the curl commands that set a Diego flag,
+// leaving out the lookups by name.
+func putCalls(rpcHandlers *fake_rpc_handlers.FakeHandlers) [][]string {
+	var puts [][]string
+	for i := 0; i < rpcHandlers.CallCoreCommandCallCount(); i++ {
+		args, _ := rpcHandlers.CallCoreCommandArgsForCall(i)
+		for _, arg := range args {
+			if arg == "PUT" {
+				puts = append(puts, args)
+				break
+			}
+		}
+	}
+	return puts
+}
@@ -1,11 +1,29 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
 
 type Applications []Application
 
 type ApplicationMetadata struct {
-	Guid string `json:"guid"`
+	Guid      string     `json:"guid"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"` // nil until the app is first updated
+}
+
+// LastChanged returns when the app was last updated, or created if it
+// never has been, and false when the Cloud Controller reported neither.
+func (m ApplicationMetadata) LastChanged() (time.Time, bool) {
+	switch {
+	case m.UpdatedAt != nil:
+		return *m.UpdatedAt, true
+	case m.CreatedAt != nil:
+		return *m.CreatedAt, true
+	}
+	return time.Time{}, false
 }
 
 const (
@@ -13,30 +31,59 @@ const (
 	Stopped = "STOPPED"
 )
 
+// Package states of a v2 app. An app whose package failed to stage cannot
+// be restaged on Diego either.
+const (
+	PackagePending = "PENDING"
+	PackageStaged  = "STAGED"
+	PackageFailed  = "FAILED"
+)
+
 type ApplicationEntity struct {
 	Name string `json:"name"`
-	//BuildpackUrl         string
+	// Buildpack is the buildpack the app asked for, by name or git URL,
+	// and DetectedBuildpack the one that staged it otherwise.
+	Buildpack         string `json:"buildpack"`
+	DetectedBuildpack string `json:"detected_buildpack"`
+	// DockerImage is set for apps pushed from a docker image.
+	DockerImage string `json:"docker_image"`
 	//Command              string
 	Diego bool
 	//DetectedStartCommand string
 	//DiskQuota            int64 // in Megabytes
 	//EnvironmentVars      map[string]interface{}
-	//InstanceCount        int
-	//Memory               int64 // in Megabytes
+	Instances int   `json:"instances"`
+	Memory    int64 `json:"memory"` // in Megabytes
 	//RunningInstances     int
 	//HealthCheckTimeout   int
 	State     string `json:"state"`
 	SpaceGuid string `json:"space_guid"`
-	//PackageUpdatedAt     *time.Time
-	//PackageState         string
+	StackGuid string `json:"stack_guid"`
+	// StackName is set instead of StackGuid by the v3 API, which names the
+	// stack rather than linking to it.
+	StackName string `json:"-"`
+	// SpaceName and OrganizationName are set instead of SpaceGuid by
+	// snapshots, which name the space and org of the app rather than link
+	// to them.
+	SpaceName        string `json:"-"`
+	OrganizationName string `json:"-"`
+	// PackageState is empty, and PackageUpdatedAt nil, when the Cloud
+	// Controller does not report them, such as for apps from the v3 API.
+	PackageState     string     `json:"package_state"`
+	PackageUpdatedAt *time.Time `json:"package_updated_at"`
 	//StagingFailedReason  string
 	//AppPorts             []int
-	//Stack                *GetApp_Stack
 	//Instances            []GetApp_AppInstanceFields
 	//Routes               []GetApp_RouteSummary
 	//Services             []GetApp_ServiceSummary
 }
 
+// Migratable reports whether the app can be restaged on another runtime,
+// which is unknown, and so assumed, when its package state is not reported.
+func (e ApplicationEntity) Migratable() bool {
+	return e.PackageState != PackageFailed
+}
+
 type ApplicationsResponse struct {
 	Resources Applications `json:"resources"`
 }
@@ -59,3 +106,44 @@ func (a ApplicationsParser) Parse(body []byte) (Applications, error) {
 
 	return response.Resources, nil
 }
+
+// FieldChange is an app field that differs between two versions of the
+// app, named as in the Cloud Controller's JSON.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Diff lists the fields of the entity that differ in other. The guid and
+// timestamps are left out, since they identify the app rather than
+// describe it.
+func (a Application) Diff(other Application) []FieldChange {
+	var changes []FieldChange
+	compare := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, FieldChange{Field: field, Old: before, New: after})
+		}
+	}
+
+	compare("name", a.Name, other.Name)
+	compare("diego", strconv.FormatBool(a.Diego), strconv.FormatBool(other.Diego))
+	compare("state", a.State, other.State)
+	compare("instances", strconv.Itoa(a.Instances), strconv.Itoa(other.Instances))
+	compare("memory", strconv.FormatInt(a.Memory, 10), strconv.FormatInt(other.Memory, 10))
+	compare("buildpack", a.Buildpack, other.Buildpack)
+	compare("detected_buildpack", a.DetectedBuildpack, other.DetectedBuildpack)
+	compare("docker_image", a.DockerImage, other.DockerImage)
+	compare("space_guid", a.SpaceGuid, other.SpaceGuid)
+	compare("space", a.SpaceName, other.SpaceName)
+	compare("org", a.OrganizationName, other.OrganizationName)
+	compare("stack_guid", a.StackGuid, other.StackGuid)
+	compare("stack", a.StackName, other.StackName)
+	return changes
+}
+
+// Equal reports whether other is the same app with the same fields, as
+// compared by Diff.
+func (a Application) Equal(other Application) bool {
+	return a.Guid == other.Guid && len(a.Diff(other)) == 0
+}
@@ -1,6 +1,8 @@
 package models_test
 
 import (
+	"time"
+
 	. "github.com/cloudfoundry-incubator/diego-enabler/models"
 
 	. "github.com/onsi/ginkgo"
@@ -45,7 +47,7 @@ var _ = Describe("Application", func() {
             "staging_failed_reason": null,
             "staging_failed_description": null,
             "diego": false,
-            "docker_image": null,
+            "docker_image": "cloudfoundry/lattice-app:latest",
             "package_updated_at": "2016-03-16T16:41:55Z",
             "detected_start_command": "sh boot.sh",
             "enable_ssh": true,
@@ -73,8 +75,8 @@ var _ = Describe("Application", func() {
             "production": false,
             "space_guid": "1f7ac3a5-6f4e-4d6c-8edd-ce694fc8c907",
             "stack_guid": "f3cecf19-4567-4dca-ad35-2a3af733cbde",
-            "buildpack": null,
-            "detected_buildpack": "staticfile 1.3.1",
+            "buildpack": "https://github.com/cloudfoundry/staticfile-buildpack.git#v1.3.1",
+            "detected_buildpack": "",
             "environment_json": {},
             "memory": 256,
             "instances": 10,
@@ -119,6 +121,105 @@ var _ = Describe("Application", func() {
 			Expect(applications[0].SpaceGuid).To(Equal("1f7ac3a5-6f4e-4d6c-8edd-ce694fc8c907"))
 			Expect(applications[0].Guid).To(Equal("b2ba6466-23f7-4f90-935b-4da1c87b8943"))
 			Expect(applications[0].State).To(Equal(Started))
+			Expect(applications[0].StackGuid).To(Equal("f3cecf19-4567-4dca-ad35-2a3af733cbde"))
+			Expect(applications[0].Instances).To(Equal(4))
+			Expect(applications[0].Memory).To(Equal(int64(512)))
+			Expect(*applications[0].CreatedAt).To(Equal(time.Date(2016, 3, 16, 16, 40, 43, 0, time.UTC)))
+			Expect(*applications[0].UpdatedAt).To(Equal(time.Date(2016, 3, 16, 16, 42, 1, 0, time.UTC)))
+		})
+
+		It("parses named, detected and git URL buildpacks", func() {
+			applications, err := ApplicationsParser{}.Parse([]byte(jsonBody))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applications[0].Buildpack).To(BeEmpty())
+			Expect(applications[0].DetectedBuildpack).To(Equal("staticfile 1.3.1"))
+			Expect(applications[1].Buildpack).To(Equal("https://github.com/cloudfoundry/staticfile-buildpack.git#v1.3.1"))
+		})
+
+		It("parses the docker image of docker apps", func() {
+			applications, err := ApplicationsParser{}.Parse([]byte(jsonBody))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applications[0].DockerImage).To(Equal("cloudfoundry/lattice-app:latest"))
+			Expect(applications[1].DockerImage).To(BeEmpty())
+		})
+
+		It("defaults instances and memory to 0 when they are absent", func() {
+			applications, err := ApplicationsParser{}.Parse([]byte(`{"resources": [{"entity": {"name": "old-app"}}]}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applications[0].Instances).To(BeZero())
+			Expect(applications[0].Memory).To(BeZero())
+		})
+
+		It("parses the package state", func() {
+			applications, err := ApplicationsParser{}.Parse([]byte(jsonBody))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applications[0].PackageState).To(Equal(PackageStaged))
+			Expect(*applications[0].PackageUpdatedAt).To(Equal(time.Date(2016, 3, 16, 16, 41, 55, 0, time.UTC)))
+			Expect(applications[0].Migratable()).To(BeTrue())
+		})
+
+		It("treats apps without a package state as migratable", func() {
+			applications, err := ApplicationsParser{}.Parse([]byte(`{"resources": [{"entity": {"name": "old-app", "package_updated_at": null}}, {"entity": {"package_state": "FAILED"}}]}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applications[0].PackageState).To(BeEmpty())
+			Expect(applications[0].PackageUpdatedAt).To(BeNil())
+			Expect(applications[0].Migratable()).To(BeTrue())
+			Expect(applications[1].Migratable()).To(BeFalse())
+		})
+
+		It("tolerates missing timestamps", func() {
+			applications, err := ApplicationsParser{}.Parse([]byte(`{"resources": [{"metadata": {"created_at": "2016-03-16T16:40:43Z", "updated_at": null}}, {"metadata": {}}]}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			lastChanged, ok := applications[0].LastChanged()
+			Expect(ok).To(BeTrue())
+			Expect(lastChanged).To(Equal(time.Date(2016, 3, 16, 16, 40, 43, 0, time.UTC)))
+
+			_, ok = applications[1].LastChanged()
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Diff", func() {
+		var app Application
+
+		BeforeEach(func() {
+			app = Application{
+				ApplicationEntity: ApplicationEntity{
+					Name:      "some-app",
+					Instances: 2,
+					State:     Started,
+				},
+				ApplicationMetadata: ApplicationMetadata{Guid: "some-guid"},
+			}
+		})
+
+		It("lists the fields that changed", func() {
+			migrated := app
+			migrated.Diego = true
+			migrated.Instances = 3
+
+			Expect(app.Diff(migrated)).To(Equal([]FieldChange{
+				{Field: "diego", Old: "false", New: "true"},
+				{Field: "instances", Old: "2", New: "3"},
+			}))
+			Expect(app.Equal(migrated)).To(BeFalse())
+		})
+
+		It("ignores the timestamps", func() {
+			updatedAt := time.Date(2016, 3, 16, 16, 42, 1, 0, time.UTC)
+			updated := app
+			updated.UpdatedAt = &updatedAt
+
+			Expect(app.Diff(updated)).To(BeEmpty())
+			Expect(app.Equal(updated)).To(BeTrue())
+		})
+
+		It("does not treat another app with the same fields as equal", func() {
+			other := app
+			other.Guid = "other-guid"
+
+			Expect(app.Equal(other)).To(BeFalse())
 		})
 	})
 })
@@ -0,0 +1,28 @@
+package models
+
+import "encoding/json"
+
+// Info is the part of the Cloud Controller's /v2/info that tells operators
+// what they are targeting.
+type Info struct {
+	Name       string `json:"name"`
+	Build      string `json:"build"`
+	ApiVersion string `json:"api_version"`
+
+	// DefaultToDiego is only reported by Cloud Controllers that expose
+	// their default_to_diego_backend setting, and is nil otherwise.
+	DefaultToDiego *bool `json:"default_to_diego_backend"`
+}
+
+type InfoParser struct{}
+
+func (a InfoParser) Parse(body []byte) (Info, error) {
+	var info Info
+
+	err := json.Unmarshal(body, &info)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return info, nil
+}
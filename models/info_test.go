@@ -0,0 +1,37 @@
+package models_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Info", func() {
+	Describe("Parser", func() {
+		It("parses the API version and the default runtime", func() {
+			info, err := InfoParser{}.Parse([]byte(`{
+  "name": "vcap",
+  "build": "2222",
+  "api_version": "2.54.0",
+  "default_to_diego_backend": true
+}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(info.ApiVersion).To(Equal("2.54.0"))
+			Expect(info.DefaultToDiego).NotTo(BeNil())
+			Expect(*info.DefaultToDiego).To(BeTrue())
+		})
+
+		It("leaves the default runtime unset when it is not reported", func() {
+			info, err := InfoParser{}.Parse([]byte(`{"api_version": "2.54.0"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.DefaultToDiego).To(BeNil())
+		})
+
+		It("returns an error for malformed JSON", func() {
+			_, err := InfoParser{}.Parse([]byte(`{`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,36 @@
+package models
+
+import "encoding/json"
+
+type Organizations []Organization
+
+type OrganizationEntity struct {
+	Name string `json:"name"`
+}
+
+type OrganizationMetadata struct {
+	Guid string `json:"guid"`
+}
+
+type OrganizationsResponse struct {
+	Resources Organizations `json:"resources"`
+}
+
+type Organization struct {
+	OrganizationEntity   `json:"entity"`
+	OrganizationMetadata `json:"metadata"`
+}
+
+type OrganizationsParser struct{}
+
+func (a OrganizationsParser) Parse(body []byte) (Organizations, error) {
+	var response OrganizationsResponse
+	var emptyOrganizations Organizations
+
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return emptyOrganizations, err
+	}
+
+	return response.Resources, nil
+}
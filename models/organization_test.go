@@ -0,0 +1,101 @@
+package models_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Organization", func() {
+	Describe("Parser", func() {
+		firstPage := `{
+  "total_results": 3,
+  "total_pages": 2,
+  "prev_url": null,
+  "next_url": "/v2/organizations?order-direction=asc&page=2&results-per-page=2",
+  "resources": [
+    {
+      "metadata": {
+        "guid": "a7aff246-5f5b-4cf8-87d8-f316053e4a20",
+        "url": "/v2/organizations/a7aff246-5f5b-4cf8-87d8-f316053e4a20",
+        "created_at": "2016-03-16T16:36:21Z",
+        "updated_at": null
+      },
+      "entity": {
+        "name": "org-1",
+        "billing_enabled": false,
+        "quota_definition_guid": "f9b4e4fd-9a0b-4b3c-9c0e-1e4b4f0a2d7b",
+        "status": "active",
+        "spaces_url": "/v2/organizations/a7aff246-5f5b-4cf8-87d8-f316053e4a20/spaces"
+      }
+    },
+    {
+      "metadata": {
+        "guid": "3c9e4e8b-1d2a-4d5e-9f60-7b8a9c0d1e2f",
+        "url": "/v2/organizations/3c9e4e8b-1d2a-4d5e-9f60-7b8a9c0d1e2f",
+        "created_at": "2016-03-17T09:12:03Z",
+        "updated_at": "2016-03-18T11:40:00Z"
+      },
+      "entity": {
+        "name": "org-2",
+        "billing_enabled": false,
+        "quota_definition_guid": "f9b4e4fd-9a0b-4b3c-9c0e-1e4b4f0a2d7b",
+        "status": "active",
+        "spaces_url": "/v2/organizations/3c9e4e8b-1d2a-4d5e-9f60-7b8a9c0d1e2f/spaces"
+      }
+    }
+  ]
+}`
+
+		secondPage := `{
+  "total_results": 3,
+  "total_pages": 2,
+  "prev_url": "/v2/organizations?order-direction=asc&page=1&results-per-page=2",
+  "next_url": null,
+  "resources": [
+    {
+      "metadata": {
+        "guid": "d1e2f3a4-b5c6-4d7e-8f90-a1b2c3d4e5f6",
+        "url": "/v2/organizations/d1e2f3a4-b5c6-4d7e-8f90-a1b2c3d4e5f6",
+        "created_at": "2016-03-20T14:05:44Z",
+        "updated_at": null
+      },
+      "entity": {
+        "name": "org-3",
+        "billing_enabled": true,
+        "quota_definition_guid": "f9b4e4fd-9a0b-4b3c-9c0e-1e4b4f0a2d7b",
+        "status": "suspended",
+        "spaces_url": "/v2/organizations/d1e2f3a4-b5c6-4d7e-8f90-a1b2c3d4e5f6/spaces"
+      }
+    }
+  ]
+}`
+
+		It("parses the organizations on a page", func() {
+			orgs, err := OrganizationsParser{}.Parse([]byte(firstPage))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orgs).To(HaveLen(2))
+			Expect(orgs[0].Guid).To(Equal("a7aff246-5f5b-4cf8-87d8-f316053e4a20"))
+			Expect(orgs[0].Name).To(Equal("org-1"))
+			Expect(orgs[1].Guid).To(Equal("3c9e4e8b-1d2a-4d5e-9f60-7b8a9c0d1e2f"))
+			Expect(orgs[1].Name).To(Equal("org-2"))
+		})
+
+		It("parses the last page on its own", func() {
+			orgs, err := OrganizationsParser{}.Parse([]byte(secondPage))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orgs).To(Equal(Organizations{
+				Organization{
+					OrganizationEntity:   OrganizationEntity{Name: "org-3"},
+					OrganizationMetadata: OrganizationMetadata{Guid: "d1e2f3a4-b5c6-4d7e-8f90-a1b2c3d4e5f6"},
+				},
+			}))
+		})
+
+		It("returns an error for a malformed body", func() {
+			_, err := OrganizationsParser{}.Parse([]byte(`{"resources": [`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
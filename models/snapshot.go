@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// snapshotApplication is an app as printed by the listing commands with
+// --output json.
+type snapshotApplication struct {
+	Name         string `json:"name"`
+	Guid         string `json:"guid"`
+	Space        string `json:"space"`
+	Organization string `json:"org"`
+	Diego        bool   `json:"diego"`
+	Buildpack    string `json:"buildpack"`
+	DockerImage  string `json:"docker_image"`
+}
+
+// SnapshotParser reads the JSON array printed by the listing commands with
+// --output json, which only has the fields that the listing shows.
+type SnapshotParser struct{}
+
+func (p SnapshotParser) Parse(body []byte) (Applications, error) {
+	var snapshot []snapshotApplication
+
+	err := json.Unmarshal(body, &snapshot)
+	if err != nil {
+		return Applications{}, err
+	}
+
+	apps := make(Applications, len(snapshot))
+	for i, app := range snapshot {
+		apps[i].Name = app.Name
+		apps[i].Guid = app.Guid
+		apps[i].SpaceName = app.Space
+		apps[i].OrganizationName = app.Organization
+		apps[i].Diego = app.Diego
+		apps[i].Buildpack = app.Buildpack
+		apps[i].DockerImage = app.DockerImage
+	}
+	return apps, nil
+}
+
+const (
+	AppAdded   = "added"
+	AppRemoved = "removed"
+	AppChanged = "changed"
+)
+
+// AppChange is an app that was added, removed or changed between two
+// snapshots. Fields is only set for changed apps.
+type AppChange struct {
+	Name   string        `json:"name"`
+	Guid   string        `json:"guid"`
+	Change string        `json:"change"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// CompareSnapshots matches the apps of two snapshots by guid and returns
+// the ones that differ, sorted by name.
+func CompareSnapshots(before, after Applications) []AppChange {
+	oldApps := make(map[string]Application)
+	for _, app := range before {
+		oldApps[app.Guid] = app
+	}
+
+	changes := []AppChange{}
+	for _, app := range after {
+		oldApp, found := oldApps[app.Guid]
+		delete(oldApps, app.Guid)
+
+		switch {
+		case !found:
+			changes = append(changes, AppChange{Name: app.Name, Guid: app.Guid, Change: AppAdded})
+		case !oldApp.Equal(app):
+			changes = append(changes, AppChange{Name: app.Name, Guid: app.Guid, Change: AppChanged, Fields: oldApp.Diff(app)})
+		}
+	}
+	for _, app := range oldApps {
+		changes = append(changes, AppChange{Name: app.Name, Guid: app.Guid, Change: AppRemoved})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Guid < changes[j].Guid
+	})
+	return changes
+}
@@ -0,0 +1,85 @@
+package models_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	Describe("Parser", func() {
+		It("parses the apps printed with --output json", func() {
+			apps, err := SnapshotParser{}.Parse([]byte(`[
+				{"name":"web","guid":"web-guid","space":"dev","org":"some-org","diego":true,"buildpack":"go_buildpack"},
+				{"name":"worker","guid":"worker-guid","space":"dev","org":"some-org","diego":false,"docker_image":"some/image"}
+			]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps).To(HaveLen(2))
+			Expect(apps[0].Name).To(Equal("web"))
+			Expect(apps[0].Guid).To(Equal("web-guid"))
+			Expect(apps[0].SpaceName).To(Equal("dev"))
+			Expect(apps[0].OrganizationName).To(Equal("some-org"))
+			Expect(apps[0].Diego).To(BeTrue())
+			Expect(apps[0].Buildpack).To(Equal("go_buildpack"))
+			Expect(apps[1].DockerImage).To(Equal("some/image"))
+		})
+
+		It("fails on anything but a JSON array", func() {
+			_, err := SnapshotParser{}.Parse([]byte(`{"resources":[]}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CompareSnapshots", func() {
+		app := func(name, guid string, diego bool) Application {
+			return Application{
+				ApplicationEntity:   ApplicationEntity{Name: name, Diego: diego},
+				ApplicationMetadata: ApplicationMetadata{Guid: guid},
+			}
+		}
+
+		It("reports added, removed and changed apps sorted by name", func() {
+			before := Applications{app("web", "web-guid", false), app("old", "old-guid", false), app("same", "same-guid", true)}
+			after := Applications{app("web", "web-guid", true), app("same", "same-guid", true), app("api", "api-guid", true)}
+
+			Expect(CompareSnapshots(before, after)).To(Equal([]AppChange{
+				{Name: "api", Guid: "api-guid", Change: AppAdded},
+				{Name: "old", Guid: "old-guid", Change: AppRemoved},
+				{
+					Name:   "web",
+					Guid:   "web-guid",
+					Change: AppChanged,
+					Fields: []FieldChange{{Field: "diego", Old: "false", New: "true"}},
+				},
+			}))
+		})
+
+		It("reports apps moved to another space or org", func() {
+			before := app("web", "web-guid", true)
+			before.SpaceName = "dev"
+			before.OrganizationName = "some-org"
+			after := before
+			after.SpaceName = "prod"
+			after.OrganizationName = "other-org"
+
+			Expect(CompareSnapshots(Applications{before}, Applications{after})).To(Equal([]AppChange{
+				{
+					Name:   "web",
+					Guid:   "web-guid",
+					Change: AppChanged,
+					Fields: []FieldChange{
+						{Field: "space", Old: "dev", New: "prod"},
+						{Field: "org", Old: "some-org", New: "other-org"},
+					},
+				},
+			}))
+		})
+
+		It("returns no changes for identical snapshots", func() {
+			apps := Applications{app("web", "web-guid", true)}
+
+			Expect(CompareSnapshots(apps, apps)).To(BeEmpty())
+		})
+	})
+})
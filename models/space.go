@@ -23,19 +23,6 @@ type Space struct {
 	SpaceMetadata `json:"metadata"`
 }
 
-type Organization struct {
-	OrganizationEntity   `json:"entity"`
-	OrganizationMetadata `json:"metadata"`
-}
-
-type OrganizationEntity struct {
-	Name string `json:"name"`
-}
-
-type OrganizationMetadata struct {
-	Guid string `json:"guid"`
-}
-
 type SpacesParser struct{}
 
 func (a SpacesParser) Parse(body []byte) (Spaces, error) {
@@ -49,3 +36,18 @@ func (a SpacesParser) Parse(body []byte) (Spaces, error) {
 
 	return response.Resources, nil
 }
+
+// SpaceParser parses a single space, such as the one at an app's
+// space_url, rather than a page of them.
+type SpaceParser struct{}
+
+func (a SpaceParser) Parse(body []byte) (Space, error) {
+	var space Space
+
+	err := json.Unmarshal(body, &space)
+	if err != nil {
+		return Space{}, err
+	}
+
+	return space, nil
+}
@@ -366,4 +366,31 @@ var _ = Describe("Space", func() {
 			Expect(org.Guid).To(Equal("94fe9c1a-6bda-483b-bf48-d6fa39d08cb6"))
 		})
 	})
+
+	Describe("SpaceParser", func() {
+		It("parses a single space", func() {
+			space, err := SpaceParser{}.Parse([]byte(`{
+  "metadata": {
+    "guid": "1f7ac3a5-6f4e-4d6c-8edd-ce694fc8c907",
+    "url": "/v2/spaces/1f7ac3a5-6f4e-4d6c-8edd-ce694fc8c907"
+  },
+  "entity": {
+    "name": "myspace",
+    "organization_guid": "94fe9c1a-6bda-483b-bf48-d6fa39d08cb6",
+    "organization": {
+      "metadata": {
+        "guid": "94fe9c1a-6bda-483b-bf48-d6fa39d08cb6"
+      },
+      "entity": {
+        "name": "myorg"
+      }
+    }
+  }
+}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(space.Name).To(Equal("myspace"))
+			Expect(space.Guid).To(Equal("1f7ac3a5-6f4e-4d6c-8edd-ce694fc8c907"))
+			Expect(space.Organization.Name).To(Equal("myorg"))
+		})
+	})
 })
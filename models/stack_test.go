@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	. "github.com/cloudfoundry-incubator/diego-enabler/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stack", func() {
+	Describe("Parser", func() {
+		jsonBody := `{
+  "total_results": 2,
+  "total_pages": 1,
+  "prev_url": null,
+  "next_url": null,
+  "resources": [
+    {
+      "metadata": {
+        "guid": "f3cecf19-4567-4dca-ad35-2a3af733cbde",
+        "url": "/v2/stacks/f3cecf19-4567-4dca-ad35-2a3af733cbde",
+        "created_at": "2016-03-16T16:36:20Z",
+        "updated_at": null
+      },
+      "entity": {
+        "name": "cflinuxfs2",
+        "description": "Cloud Foundry Linux-based filesystem"
+      }
+    },
+    {
+      "metadata": {
+        "guid": "0ad6a4f8-8b93-4aa0-8d4a-4e3e5b3f9b1c",
+        "url": "/v2/stacks/0ad6a4f8-8b93-4aa0-8d4a-4e3e5b3f9b1c",
+        "created_at": "2016-03-16T16:36:20Z",
+        "updated_at": null
+      },
+      "entity": {
+        "name": "windows2012R2",
+        "description": "Microsoft Windows / .Net 64 bit"
+      }
+    }
+  ]
+}`
+
+		It("parses", func() {
+			stacks, err := StacksParser{}.Parse([]byte(jsonBody))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stacks).To(HaveLen(2))
+			Expect(stacks[0].Guid).To(Equal("f3cecf19-4567-4dca-ad35-2a3af733cbde"))
+			Expect(stacks[0].Name).To(Equal("cflinuxfs2"))
+			Expect(stacks[1].Name).To(Equal("windows2012R2"))
+		})
+	})
+})
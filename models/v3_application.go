@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type v3Application struct {
+	Guid      string     `json:"guid"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	Lifecycle struct {
+		Type string `json:"type"`
+		Data struct {
+			Buildpacks []string `json:"buildpacks"`
+			Stack      string   `json:"stack"`
+		} `json:"data"`
+	} `json:"lifecycle"`
+	Relationships struct {
+		Space struct {
+			Data struct {
+				Guid string `json:"guid"`
+			} `json:"data"`
+		} `json:"space"`
+	} `json:"relationships"`
+}
+
+type v3ApplicationsResponse struct {
+	Resources []v3Application `json:"resources"`
+}
+
+// V3ApplicationsParser parses a page of apps from /v3/apps into the same
+// Applications as the v2 parser. The v3 API has no diego flag, because the
+// foundations that serve it run every app on Diego, and it keeps instances
+// and memory on the app's processes, so those are left at zero.
+type V3ApplicationsParser struct{}
+
+func (a V3ApplicationsParser) Parse(body []byte) (Applications, error) {
+	var response v3ApplicationsResponse
+	var emptyApplications Applications
+
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return emptyApplications, err
+	}
+
+	applications := make(Applications, 0, len(response.Resources))
+	for _, resource := range response.Resources {
+		app := Application{
+			ApplicationEntity: ApplicationEntity{
+				Name:      resource.Name,
+				Diego:     true,
+				State:     resource.State,
+				SpaceGuid: resource.Relationships.Space.Data.Guid,
+				StackName: resource.Lifecycle.Data.Stack,
+			},
+			ApplicationMetadata: ApplicationMetadata{
+				Guid:      resource.Guid,
+				CreatedAt: resource.CreatedAt,
+				UpdatedAt: resource.UpdatedAt,
+			},
+		}
+		if resource.Lifecycle.Type == "buildpack" && len(resource.Lifecycle.Data.Buildpacks) > 0 {
+			app.Buildpack = resource.Lifecycle.Data.Buildpacks[0]
+		}
+		applications = append(applications, app)
+	}
+
+	return applications, nil
+}
@@ -0,0 +1,82 @@
+package models_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("V3ApplicationsParser", func() {
+	jsonBody := `{
+   "pagination": {
+      "total_results": 2,
+      "total_pages": 1,
+      "first": {"href": "https://api.example.com/v3/apps?page=1&per_page=50"},
+      "last": {"href": "https://api.example.com/v3/apps?page=1&per_page=50"},
+      "next": null,
+      "previous": null
+   },
+   "resources": [
+      {
+         "guid": "1cb006ee-fb05-47e1-b541-c34179ddc446",
+         "name": "my_app",
+         "state": "STARTED",
+         "created_at": "2016-03-17T21:41:30Z",
+         "updated_at": "2016-03-18T11:32:30Z",
+         "lifecycle": {
+            "type": "buildpack",
+            "data": {
+               "buildpacks": ["java_buildpack"],
+               "stack": "cflinuxfs2"
+            }
+         },
+         "relationships": {
+            "space": {
+               "data": {"guid": "2f35885d-0c9d-4423-83ad-fd05066f8576"}
+            }
+         }
+      },
+      {
+         "guid": "02b4ec9b-94c7-4468-9c23-4e906191a0f8",
+         "name": "my_docker_app",
+         "state": "STOPPED",
+         "created_at": "2016-03-18T21:41:30Z",
+         "updated_at": null,
+         "lifecycle": {"type": "docker", "data": {}},
+         "relationships": {
+            "space": {
+               "data": {"guid": "2f35885d-0c9d-4423-83ad-fd05066f8576"}
+            }
+         }
+      }
+   ]
+}`
+
+	It("maps each v3 app onto an Application", func() {
+		apps, err := V3ApplicationsParser{}.Parse([]byte(jsonBody))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps).To(HaveLen(2))
+
+		updatedAt := time.Date(2016, 3, 18, 11, 32, 30, 0, time.UTC)
+		Expect(apps[0].Guid).To(Equal("1cb006ee-fb05-47e1-b541-c34179ddc446"))
+		Expect(apps[0].Name).To(Equal("my_app"))
+		Expect(apps[0].State).To(Equal(Started))
+		Expect(apps[0].Diego).To(BeTrue())
+		Expect(apps[0].SpaceGuid).To(Equal("2f35885d-0c9d-4423-83ad-fd05066f8576"))
+		Expect(apps[0].StackName).To(Equal("cflinuxfs2"))
+		Expect(apps[0].Buildpack).To(Equal("java_buildpack"))
+		Expect(apps[0].UpdatedAt).To(Equal(&updatedAt))
+
+		Expect(apps[1].State).To(Equal(Stopped))
+		Expect(apps[1].Buildpack).To(BeEmpty())
+		Expect(apps[1].UpdatedAt).To(BeNil())
+	})
+
+	It("returns an error for malformed JSON", func() {
+		_, err := V3ApplicationsParser{}.Parse([]byte("{"))
+		Expect(err).To(HaveOccurred())
+	})
+})
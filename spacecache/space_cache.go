@@ -0,0 +1,133 @@
+package spacecache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+// TTLVariable turns the cache on when it holds a positive duration such as
+// "10m". Cached spaces older than that are fetched again.
+const TTLVariable = "CF_DIEGO_SPACE_CACHE_TTL"
+
+type InvalidTTLError struct {
+	PassedValue string
+}
+
+func (e InvalidTTLError) Error() string {
+	return fmt.Sprintf("Invalid %s %q", TTLVariable, e.PassedValue)
+}
+
+// Cache keeps the spaces visible to a user on one API endpoint in a file
+// under the CF config directory, so they need not be fetched on every run.
+type Cache struct {
+	Path        string
+	ApiEndpoint string
+	Username    string
+	TTL         time.Duration
+	Now         func() time.Time
+}
+
+type entry struct {
+	ApiEndpoint string        `json:"api_endpoint"`
+	Username    string        `json:"username"`
+	FetchedAt   time.Time     `json:"fetched_at"`
+	Spaces      models.Spaces `json:"spaces"`
+}
+
+// New returns the cache for apiEndpoint, or nil when TTLVariable is not set,
+// which leaves caching off.
+func New(apiEndpoint, username string) (*Cache, error) {
+	value := os.Getenv(TTLVariable)
+	if value == "" {
+		return nil, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return nil, InvalidTTLError{PassedValue: value}
+	}
+
+	sum := sha1.Sum([]byte(apiEndpoint))
+	return &Cache{
+		Path:        filepath.Join(configDir(), "diego-enabler", "spaces-"+hex.EncodeToString(sum[:])+".json"),
+		ApiEndpoint: apiEndpoint,
+		Username:    username,
+		TTL:         ttl,
+		Now:         time.Now,
+	}, nil
+}
+
+// configDir is the CF CLI's config directory, under CF_HOME when it is set.
+func configDir() string {
+	home := os.Getenv("CF_HOME")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	return filepath.Join(home, ".cf")
+}
+
+// Load returns the cached spaces, unless there are none, they have expired
+// or they were cached for another user or endpoint.
+func (c *Cache) Load() (models.Spaces, bool) {
+	contents, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return nil, false
+	}
+
+	var cached entry
+	if err := json.Unmarshal(contents, &cached); err != nil {
+		return nil, false
+	}
+
+	if cached.ApiEndpoint != c.ApiEndpoint || cached.Username != c.Username {
+		return nil, false
+	}
+	if c.Now().Sub(cached.FetchedAt) > c.TTL {
+		return nil, false
+	}
+
+	return cached.Spaces, true
+}
+
+// Save replaces the cached spaces, writing them to a temporary file first
+// so that a concurrent Load never sees a partial file.
+func (c *Cache) Save(spaces models.Spaces) error {
+	contents, err := json.Marshal(entry{
+		ApiEndpoint: c.ApiEndpoint,
+		Username:    c.Username,
+		FetchedAt:   c.Now(),
+		Spaces:      spaces,
+	})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(c.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(dir, "spaces")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(contents)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), c.Path)
+}
@@ -0,0 +1,13 @@
+package spacecache_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSpaceCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SpaceCache Suite")
+}
@@ -0,0 +1,127 @@
+package spacecache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	. "github.com/cloudfoundry-incubator/diego-enabler/spacecache"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SpaceCache", func() {
+	var (
+		cfHome string
+		spaces models.Spaces
+	)
+
+	BeforeEach(func() {
+		var err error
+		cfHome, err = ioutil.TempDir("", "diego-enabler-cf-home")
+		Expect(err).NotTo(HaveOccurred())
+		os.Setenv("CF_HOME", cfHome)
+
+		spaces = models.Spaces{
+			models.Space{
+				SpaceEntity: models.SpaceEntity{
+					Name: "space-1",
+					Organization: models.Organization{
+						OrganizationEntity: models.OrganizationEntity{Name: "org-1"},
+					},
+				},
+				SpaceMetadata: models.SpaceMetadata{Guid: "space-1-guid"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CF_HOME")
+		os.Unsetenv(TTLVariable)
+		os.RemoveAll(cfHome)
+	})
+
+	Describe("New", func() {
+		It("leaves caching off when no TTL is set", func() {
+			cache, err := New("https://api.example.com", "some-user")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache).To(BeNil())
+		})
+
+		It("rejects a TTL that is not a positive duration", func() {
+			os.Setenv(TTLVariable, "forever")
+			_, err := New("https://api.example.com", "some-user")
+			Expect(err).To(Equal(InvalidTTLError{PassedValue: "forever"}))
+		})
+
+		It("keeps a file per API endpoint under the CF config directory", func() {
+			os.Setenv(TTLVariable, "10m")
+			cache, err := New("https://api.example.com", "some-user")
+			Expect(err).NotTo(HaveOccurred())
+			other, err := New("https://api.other.example.com", "some-user")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cache.TTL).To(Equal(10 * time.Minute))
+			Expect(strings.HasPrefix(cache.Path, filepath.Join(cfHome, ".cf", "diego-enabler"))).To(BeTrue())
+			Expect(cache.Path).NotTo(Equal(other.Path))
+		})
+	})
+
+	Describe("Load and Save", func() {
+		var (
+			cache *Cache
+			now   time.Time
+		)
+
+		BeforeEach(func() {
+			os.Setenv(TTLVariable, "10m")
+			var err error
+			cache, err = New("https://api.example.com", "some-user")
+			Expect(err).NotTo(HaveOccurred())
+
+			now = time.Date(2016, 3, 16, 16, 40, 0, 0, time.UTC)
+			cache.Now = func() time.Time { return now }
+		})
+
+		It("misses before anything is saved", func() {
+			_, ok := cache.Load()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the saved spaces", func() {
+			Expect(cache.Save(spaces)).To(Succeed())
+
+			loaded, ok := cache.Load()
+			Expect(ok).To(BeTrue())
+			Expect(loaded).To(Equal(spaces))
+		})
+
+		It("misses once the spaces are older than the TTL", func() {
+			Expect(cache.Save(spaces)).To(Succeed())
+			now = now.Add(11 * time.Minute)
+
+			_, ok := cache.Load()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("misses for another user", func() {
+			Expect(cache.Save(spaces)).To(Succeed())
+			cache.Username = "other-user"
+
+			_, ok := cache.Load()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("misses when the file is corrupt", func() {
+			Expect(os.MkdirAll(filepath.Dir(cache.Path), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(cache.Path, []byte("{"), 0600)).To(Succeed())
+
+			_, ok := cache.Load()
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,16 @@
+package thingdoer
+
+import (
+	"context"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+func (c AppsGetter) AllApps(ctx context.Context, appsParser ApplicationsParser, paginatedRequester PaginatedRequester) (models.Applications, error) {
+	return c.getApps(ctx, api.Filters{}, appsParser, paginatedRequester)
+}
+
+func (c AppsGetter) StreamAllApps(ctx context.Context, appsParser ApplicationsParser, paginatedRequester PaginatedRequester, handle AppsHandler) error {
+	return c.streamApps(ctx, api.Filters{}, appsParser, paginatedRequester, handle)
+}
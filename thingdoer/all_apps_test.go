@@ -0,0 +1,80 @@
+package thingdoer_test
+
+import (
+	"context"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer/thingdoerfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AllApps", func() {
+	var (
+		fakePaginatedRequester *thingdoerfakes.FakePaginatedRequester
+		fakeApplicationsParser *thingdoerfakes.FakeApplicationsParser
+		apps                   models.Applications
+
+		command thingdoer.AppsGetter
+		err     error
+	)
+
+	BeforeEach(func() {
+		fakePaginatedRequester = new(thingdoerfakes.FakePaginatedRequester)
+		fakeApplicationsParser = new(thingdoerfakes.FakeApplicationsParser)
+		command = thingdoer.AppsGetter{}
+	})
+
+	JustBeforeEach(func() {
+		apps, err = command.AllApps(context.Background(), fakeApplicationsParser, fakePaginatedRequester)
+	})
+
+	It("should create a request without a diego filter", func() {
+		Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
+		_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+		Expect(filters).To(Equal(api.Filters{}))
+	})
+
+	Context("when an organization name is specified", func() {
+		BeforeEach(func() {
+			command.OrganizationGuid = "some-organization-guid"
+		})
+
+		It("should create a request with only the organization guid set", func() {
+			expectedFilters := api.Filters{
+				api.EqualFilter{
+					Name:  "organization_guid",
+					Value: "some-organization-guid",
+				},
+			}
+
+			_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+			Expect(filters).To(Equal(expectedFilters))
+		})
+	})
+
+	Context("When the paginated requester succeeds", func() {
+		BeforeEach(func() {
+			fakePaginatedRequester.DoReturns([][]byte{[]byte("some-json")}, nil)
+			fakeApplicationsParser.ParseReturns(models.Applications{
+				models.Application{
+					ApplicationEntity: models.ApplicationEntity{
+						Diego: true,
+					},
+				},
+				models.Application{
+					ApplicationEntity: models.ApplicationEntity{
+						Diego: false,
+					},
+				},
+			}, nil)
+		})
+
+		It("returns applications on both runtimes", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps).To(HaveLen(2))
+		})
+	})
+})
@@ -1,8 +1,13 @@
 package thingdoer
 
-import "github.com/cloudfoundry-incubator/diego-enabler/api"
+import (
+	"context"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+)
 
 //go:generate counterfeiter . PaginatedRequester
 type PaginatedRequester interface {
-	Do(filter api.Filter, params map[string]interface{}) ([][]byte, error)
+	Do(ctx context.Context, filter api.Filter, params map[string]interface{}) ([][]byte, error)
+	DoEach(ctx context.Context, filter api.Filter, params map[string]interface{}, handle api.PageFunc) error
 }
@@ -1,55 +1,15 @@
 package thingdoer
 
 import (
-	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"context"
+
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
 )
 
-func (c AppsGetter) DeaApps(appsParser ApplicationsParser, paginatedRequester PaginatedRequester) (models.Applications, error) {
-	var noApps models.Applications
-
-	filter := api.Filters{
-		api.EqualFilter{
-			Name:  "diego",
-			Value: false,
-		},
-	}
-
-	if c.OrganizationGuid != "" {
-		filter = append(
-			filter,
-			api.EqualFilter{
-				Name:  "organization_guid",
-				Value: c.OrganizationGuid,
-			},
-		)
-	} else if c.SpaceGuid != "" {
-		filter = append(
-			filter,
-			api.EqualFilter{
-				Name:  "space_guid",
-				Value: c.SpaceGuid,
-			},
-		)
-	}
-
-	params := map[string]interface{}{}
-
-	responseBodies, err := paginatedRequester.Do(filter, params)
-	if err != nil {
-		return noApps, err
-	}
-
-	var applications models.Applications
-
-	for _, nextBody := range responseBodies {
-		apps, err := appsParser.Parse(nextBody)
-		if err != nil {
-			return noApps, err
-		}
-
-		applications = append(applications, apps...)
-	}
+func (c AppsGetter) DeaApps(ctx context.Context, appsParser ApplicationsParser, paginatedRequester PaginatedRequester) (models.Applications, error) {
+	return c.getApps(ctx, runtimeFilter(false), appsParser, paginatedRequester)
+}
 
-	return applications, nil
+func (c AppsGetter) StreamDeaApps(ctx context.Context, appsParser ApplicationsParser, paginatedRequester PaginatedRequester, handle AppsHandler) error {
+	return c.streamApps(ctx, runtimeFilter(false), appsParser, paginatedRequester, handle)
 }
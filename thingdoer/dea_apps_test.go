@@ -1,6 +1,7 @@
 package thingdoer_test
 
 import (
+	"context"
 	"errors"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
@@ -28,7 +29,7 @@ var _ = Describe("DeaApps", func() {
 	})
 
 	JustBeforeEach(func() {
-		apps, err = command.DeaApps(fakeApplicationsParser, fakePaginatedRequester)
+		apps, err = command.DeaApps(context.Background(), fakeApplicationsParser, fakePaginatedRequester)
 	})
 
 	It("should create a request with diego filter set to false", func() {
@@ -40,7 +41,7 @@ var _ = Describe("DeaApps", func() {
 		}
 
 		Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-		filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+		_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
 		Expect(filters).To(Equal(expectedFilters))
 	})
 
@@ -62,7 +63,7 @@ var _ = Describe("DeaApps", func() {
 			}
 
 			Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-			filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+			_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
 			Expect(filters).To(Equal(expectedFilters))
 		})
 	})
@@ -85,7 +86,7 @@ var _ = Describe("DeaApps", func() {
 			}
 
 			Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-			filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+			_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
 			Expect(filters).To(Equal(expectedFilters))
 		})
 	})
@@ -131,10 +132,10 @@ var _ = Describe("DeaApps", func() {
 		Context("when the parsing succeeds", func() {
 			var parsedApps models.Applications = models.Applications{
 				models.Application{
-					models.ApplicationEntity{
+					ApplicationEntity: models.ApplicationEntity{
 						Diego: false,
 					},
-					models.ApplicationMetadata{
+					ApplicationMetadata: models.ApplicationMetadata{
 						Guid: "some-guid",
 					},
 				},
@@ -148,18 +149,18 @@ var _ = Describe("DeaApps", func() {
 			It("returns a list of diego applications", func() {
 				expectedApps := models.Applications{
 					models.Application{
-						models.ApplicationEntity{
+						ApplicationEntity: models.ApplicationEntity{
 							Diego: false,
 						},
-						models.ApplicationMetadata{
+						ApplicationMetadata: models.ApplicationMetadata{
 							Guid: "some-guid",
 						},
 					},
 					models.Application{
-						models.ApplicationEntity{
+						ApplicationEntity: models.ApplicationEntity{
 							Diego: false,
 						},
-						models.ApplicationMetadata{
+						ApplicationMetadata: models.ApplicationMetadata{
 							Guid: "some-guid",
 						},
 					},
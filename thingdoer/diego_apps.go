@@ -1,56 +1,71 @@
 package thingdoer
 
 import (
+	"context"
+
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
 )
 
-type AppsGetterFunc func(ApplicationsParser, PaginatedRequester) (models.Applications, error)
+type AppsGetterFunc func(context.Context, ApplicationsParser, PaginatedRequester) (models.Applications, error)
+
+// AppsHandler is called with the apps of each page as it arrives.
+type AppsHandler func(models.Applications) error
+
+// AppsStreamerFunc is AppsGetterFunc for callers that handle the apps a
+// page at a time rather than all at once.
+type AppsStreamerFunc func(context.Context, ApplicationsParser, PaginatedRequester, AppsHandler) error
 
 //go:generate counterfeiter . ApplicationsParser
 type ApplicationsParser interface {
 	Parse([]byte) (models.Applications, error)
 }
 
+// AppsGetter gets the apps in the space with SpaceGuid when it is set, or
+// else in the org with OrganizationGuid, which may also be set to the org
+// of that space.
 type AppsGetter struct {
 	OrganizationGuid string
 	SpaceGuid        string
 }
 
 func (c AppsGetter) DiegoApps(
+	ctx context.Context,
 	appsParser ApplicationsParser,
 	paginatedRequester PaginatedRequester,
 ) (models.Applications, error) {
-	var noApps models.Applications
+	return c.getApps(ctx, runtimeFilter(true), appsParser, paginatedRequester)
+}
+
+func (c AppsGetter) StreamDiegoApps(
+	ctx context.Context,
+	appsParser ApplicationsParser,
+	paginatedRequester PaginatedRequester,
+	handle AppsHandler,
+) error {
+	return c.streamApps(ctx, runtimeFilter(true), appsParser, paginatedRequester, handle)
+}
 
-	filter := api.Filters{
+func runtimeFilter(diego bool) api.Filters {
+	return api.Filters{
 		api.EqualFilter{
 			Name:  "diego",
-			Value: true,
+			Value: diego,
 		},
 	}
+}
 
-	if c.OrganizationGuid != "" {
-		filter = append(
-			filter,
-			api.EqualFilter{
-				Name:  "organization_guid",
-				Value: c.OrganizationGuid,
-			},
-		)
-	} else if c.SpaceGuid != "" {
-		filter = append(
-			filter,
-			api.EqualFilter{
-				Name:  "space_guid",
-				Value: c.SpaceGuid,
-			},
-		)
-	}
+func (c AppsGetter) getApps(
+	ctx context.Context,
+	filter api.Filters,
+	appsParser ApplicationsParser,
+	paginatedRequester PaginatedRequester,
+) (models.Applications, error) {
+	var noApps models.Applications
 
 	params := map[string]interface{}{}
 
-	responseBodies, err := paginatedRequester.Do(filter, params)
+	responseBodies, err := paginatedRequester.Do(ctx, c.appsFilter(filter), params)
 	if err != nil {
 		return noApps, err
 	}
@@ -68,3 +83,47 @@ func (c AppsGetter) DiegoApps(
 
 	return applications, nil
 }
+
+// streamApps parses each page of apps and hands it to handle before the
+// next page is fetched.
+func (c AppsGetter) streamApps(
+	ctx context.Context,
+	filter api.Filters,
+	appsParser ApplicationsParser,
+	paginatedRequester PaginatedRequester,
+	handle AppsHandler,
+) error {
+	params := map[string]interface{}{}
+
+	return paginatedRequester.DoEach(ctx, c.appsFilter(filter), params, func(body []byte) error {
+		apps, err := appsParser.Parse(body)
+		if err != nil {
+			return err
+		}
+		return handle(apps)
+	})
+}
+
+// appsFilter narrows filter down to the space or, failing that, the org,
+// if one is set.
+func (c AppsGetter) appsFilter(filter api.Filters) api.Filters {
+	if c.SpaceGuid != "" {
+		filter = append(
+			filter,
+			api.EqualFilter{
+				Name:  "space_guid",
+				Value: c.SpaceGuid,
+			},
+		)
+	} else if c.OrganizationGuid != "" {
+		filter = append(
+			filter,
+			api.EqualFilter{
+				Name:  "organization_guid",
+				Value: c.OrganizationGuid,
+			},
+		)
+	}
+
+	return filter
+}
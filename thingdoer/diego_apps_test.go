@@ -1,6 +1,7 @@
 package thingdoer_test
 
 import (
+	"context"
 	"errors"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
@@ -28,7 +29,7 @@ var _ = Describe("DiegoApps", func() {
 	})
 
 	JustBeforeEach(func() {
-		apps, err = command.DiegoApps(fakeApplicationsParser, fakePaginatedRequester)
+		apps, err = command.DiegoApps(context.Background(), fakeApplicationsParser, fakePaginatedRequester)
 	})
 
 	It("should create a request with diego filter set to true", func() {
@@ -40,7 +41,7 @@ var _ = Describe("DiegoApps", func() {
 		}
 
 		Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-		filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+		_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
 		Expect(filters).To(Equal(expectedFilters))
 	})
 
@@ -62,7 +63,7 @@ var _ = Describe("DiegoApps", func() {
 			}
 
 			Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-			filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+			_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
 			Expect(filters).To(Equal(expectedFilters))
 		})
 	})
@@ -85,7 +86,31 @@ var _ = Describe("DiegoApps", func() {
 			}
 
 			Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-			filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+			_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
+			Expect(filters).To(Equal(expectedFilters))
+		})
+	})
+
+	Context("when both the space and its org are specified", func() {
+		BeforeEach(func() {
+			command.OrganizationGuid = "some-organization-guid"
+			command.SpaceGuid = "some-space-guid"
+		})
+
+		It("should create a request with only the space guid set", func() {
+			expectedFilters := api.Filters{
+				api.EqualFilter{
+					Name:  "diego",
+					Value: true,
+				},
+				api.EqualFilter{
+					Name:  "space_guid",
+					Value: "some-space-guid",
+				},
+			}
+
+			Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
+			_, filters, _ := fakePaginatedRequester.DoArgsForCall(0)
 			Expect(filters).To(Equal(expectedFilters))
 		})
 	})
@@ -131,10 +156,10 @@ var _ = Describe("DiegoApps", func() {
 		Context("when the parsing succeeds", func() {
 			var parsedApps models.Applications = models.Applications{
 				models.Application{
-					models.ApplicationEntity{
+					ApplicationEntity: models.ApplicationEntity{
 						Diego: true,
 					},
-					models.ApplicationMetadata{
+					ApplicationMetadata: models.ApplicationMetadata{
 						Guid: "some-guid",
 					},
 				},
@@ -148,18 +173,18 @@ var _ = Describe("DiegoApps", func() {
 			It("returns a list of diego applications", func() {
 				expectedApps := models.Applications{
 					models.Application{
-						models.ApplicationEntity{
+						ApplicationEntity: models.ApplicationEntity{
 							Diego: true,
 						},
-						models.ApplicationMetadata{
+						ApplicationMetadata: models.ApplicationMetadata{
 							Guid: "some-guid",
 						},
 					},
 					models.Application{
-						models.ApplicationEntity{
+						ApplicationEntity: models.ApplicationEntity{
 							Diego: true,
 						},
-						models.ApplicationMetadata{
+						ApplicationMetadata: models.ApplicationMetadata{
 							Guid: "some-guid",
 						},
 					},
@@ -171,3 +196,71 @@ var _ = Describe("DiegoApps", func() {
 		})
 	})
 })
+
+var _ = Describe("StreamDiegoApps", func() {
+	var (
+		fakePaginatedRequester *thingdoerfakes.FakePaginatedRequester
+		fakeApplicationsParser *thingdoerfakes.FakeApplicationsParser
+		handled                []models.Applications
+
+		command thingdoer.AppsGetter
+		err     error
+	)
+
+	BeforeEach(func() {
+		fakePaginatedRequester = new(thingdoerfakes.FakePaginatedRequester)
+		fakeApplicationsParser = new(thingdoerfakes.FakeApplicationsParser)
+		command = thingdoer.AppsGetter{SpaceGuid: "some-space-guid"}
+		handled = nil
+
+		fakePaginatedRequester.DoEachStub = func(_ context.Context, _ api.Filter, _ map[string]interface{}, handle api.PageFunc) error {
+			for _, body := range []string{"some-json", "some-other-json"} {
+				if err := handle([]byte(body)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		fakeApplicationsParser.ParseStub = func(body []byte) (models.Applications, error) {
+			return models.Applications{
+				models.Application{ApplicationMetadata: models.ApplicationMetadata{Guid: string(body)}},
+			}, nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = command.StreamDiegoApps(context.Background(), fakeApplicationsParser, fakePaginatedRequester, func(apps models.Applications) error {
+			handled = append(handled, apps)
+			return nil
+		})
+	})
+
+	It("hands over the apps of each page with the same filters as DiegoApps", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handled).To(Equal([]models.Applications{
+			{models.Application{ApplicationMetadata: models.ApplicationMetadata{Guid: "some-json"}}},
+			{models.Application{ApplicationMetadata: models.ApplicationMetadata{Guid: "some-other-json"}}},
+		}))
+
+		_, filters, _, _ := fakePaginatedRequester.DoEachArgsForCall(0)
+		Expect(filters).To(Equal(api.Filters{
+			api.EqualFilter{Name: "diego", Value: true},
+			api.EqualFilter{Name: "space_guid", Value: "some-space-guid"},
+		}))
+	})
+
+	Context("when the parsing fails", func() {
+		var parseError error
+
+		BeforeEach(func() {
+			parseError = errors.New("parsing json failed")
+			fakeApplicationsParser.ParseStub = nil
+			fakeApplicationsParser.ParseReturns(nil, parseError)
+		})
+
+		It("stops at the first page", func() {
+			Expect(err).To(Equal(parseError))
+			Expect(handled).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,38 @@
+package thingdoer
+
+import (
+	"context"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+//go:generate counterfeiter . OrganizationsParser
+type OrganizationsParser interface {
+	Parse([]byte) (models.Organizations, error)
+}
+
+func Organizations(ctx context.Context, organizationsParser OrganizationsParser, paginatedRequester PaginatedRequester) (models.Organizations, error) {
+	var noOrganizations models.Organizations
+
+	filter := api.Filters{}
+	params := map[string]interface{}{}
+
+	responseBodies, err := paginatedRequester.Do(ctx, filter, params)
+	if err != nil {
+		return noOrganizations, err
+	}
+
+	var organizations models.Organizations
+
+	for _, nextBody := range responseBodies {
+		parsedOrganizations, err := organizationsParser.Parse(nextBody)
+		if err != nil {
+			return noOrganizations, err
+		}
+
+		organizations = append(organizations, parsedOrganizations...)
+	}
+
+	return organizations, nil
+}
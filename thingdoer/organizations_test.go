@@ -0,0 +1,94 @@
+package thingdoer_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer/thingdoerfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Organizations", func() {
+	var (
+		fakePaginatedRequester  *thingdoerfakes.FakePaginatedRequester
+		fakeOrganizationsParser *thingdoerfakes.FakeOrganizationsParser
+		organizations           models.Organizations
+		err                     error
+	)
+
+	BeforeEach(func() {
+		fakePaginatedRequester = new(thingdoerfakes.FakePaginatedRequester)
+		fakeOrganizationsParser = new(thingdoerfakes.FakeOrganizationsParser)
+	})
+
+	JustBeforeEach(func() {
+		organizations, err = thingdoer.Organizations(context.Background(), fakeOrganizationsParser, fakePaginatedRequester)
+	})
+
+	It("should request all organizations", func() {
+		Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
+	})
+
+	Context("when the paginated requester fails", func() {
+		var requestError error
+
+		BeforeEach(func() {
+			requestError = errors.New("making API requests failed")
+			fakePaginatedRequester.DoReturns([][]byte{}, requestError)
+		})
+
+		It("returns the requester error", func() {
+			Expect(organizations).To(BeEmpty())
+			Expect(err).To(Equal(requestError))
+		})
+	})
+
+	Context("When the paginated requester succeeds", func() {
+		BeforeEach(func() {
+			responseBodies := [][]byte{
+				[]byte("some-json"),
+				[]byte("some-other-json"),
+			}
+			fakePaginatedRequester.DoReturns(responseBodies, nil)
+		})
+
+		Context("when the parsing fails", func() {
+			var parseError error
+
+			BeforeEach(func() {
+				parseError = errors.New("parsing json failed")
+				fakeOrganizationsParser.ParseReturns(models.Organizations{}, parseError)
+			})
+
+			It("returns the parse error", func() {
+				Expect(organizations).To(BeEmpty())
+				Expect(err).To(Equal(parseError))
+			})
+		})
+
+		Context("when the parsing succeeds", func() {
+			var parsedOrganizations = models.Organizations{
+				models.Organization{
+					OrganizationEntity: models.OrganizationEntity{
+						Name: "org-foo",
+					},
+					OrganizationMetadata: models.OrganizationMetadata{
+						Guid: "some-guid",
+					},
+				},
+			}
+
+			BeforeEach(func() {
+				fakeOrganizationsParser.ParseReturns(parsedOrganizations, nil)
+			})
+
+			It("returns the organizations from every page", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(organizations).To(Equal(append(parsedOrganizations, parsedOrganizations...)))
+			})
+		})
+	})
+})
@@ -1,6 +1,8 @@
 package thingdoer
 
 import (
+	"context"
+
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
 )
@@ -10,16 +12,25 @@ type SpacesParser interface {
 	Parse([]byte) (models.Spaces, error)
 }
 
-func Spaces(spacesParser SpacesParser, paginatedRequester PaginatedRequester) (models.Spaces, error) {
-	var noSpaces models.Spaces
+func Spaces(ctx context.Context, spacesParser SpacesParser, paginatedRequester PaginatedRequester) (models.Spaces, error) {
+	return SpacesWhere(ctx, spacesParser, paginatedRequester, api.Filters{})
+}
 
-	filter := api.Filters{}
+// DeveloperSpaces returns the spaces in which the user with that guid is a
+// SpaceDeveloper, and so can change the apps.
+func DeveloperSpaces(ctx context.Context, spacesParser SpacesParser, paginatedRequester PaginatedRequester, userGuid string) (models.Spaces, error) {
+	return SpacesWhere(ctx, spacesParser, paginatedRequester, api.EqualFilter{Name: "developer_guid", Value: userGuid})
+}
+
+// SpacesWhere is Spaces narrowed down by filter.
+func SpacesWhere(ctx context.Context, spacesParser SpacesParser, paginatedRequester PaginatedRequester, filter api.Filter) (models.Spaces, error) {
+	var noSpaces models.Spaces
 
 	params := map[string]interface{}{
 		"inline-relations-depth": 1,
 	}
 
-	responseBodies, err := paginatedRequester.Do(filter, params)
+	responseBodies, err := paginatedRequester.Do(ctx, filter, params)
 	if err != nil {
 		return noSpaces, err
 	}
@@ -37,3 +48,17 @@ func Spaces(spacesParser SpacesParser, paginatedRequester PaginatedRequester) (m
 
 	return spaces, nil
 }
+
+// VisibleSpaces is Spaces that reports listed false, rather than failing,
+// when the token is not allowed to list spaces, such as one scoped to a
+// single space.
+func VisibleSpaces(ctx context.Context, spacesParser SpacesParser, paginatedRequester PaginatedRequester, filter api.Filter) (spaces models.Spaces, listed bool, err error) {
+	spaces, err = SpacesWhere(ctx, spacesParser, paginatedRequester, filter)
+	if _, ok := err.(api.ForbiddenError); ok {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return spaces, true, nil
+}
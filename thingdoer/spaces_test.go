@@ -1,8 +1,10 @@
 package thingdoer_test
 
 import (
+	"context"
 	"errors"
 
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
 	"github.com/cloudfoundry-incubator/diego-enabler/models"
 	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
 	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer/thingdoerfakes"
@@ -24,7 +26,7 @@ var _ = Describe("Spaces", func() {
 	})
 
 	JustBeforeEach(func() {
-		spaces, err = thingdoer.Spaces(fakeSpacesParser, fakePaginatedRequester)
+		spaces, err = thingdoer.Spaces(context.Background(), fakeSpacesParser, fakePaginatedRequester)
 	})
 
 	It("should create a request inline-relations-depth of 1", func() {
@@ -33,7 +35,7 @@ var _ = Describe("Spaces", func() {
 		}
 
 		Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
-		_, params := fakePaginatedRequester.DoArgsForCall(0)
+		_, _, params := fakePaginatedRequester.DoArgsForCall(0)
 		Expect(params).To(Equal(expectedParams))
 	})
 
@@ -78,10 +80,10 @@ var _ = Describe("Spaces", func() {
 		Context("when the parsing succeeds", func() {
 			var parsedApps models.Spaces = models.Spaces{
 				models.Space{
-					models.SpaceEntity{
+					SpaceEntity: models.SpaceEntity{
 						Name: "space-foo",
 					},
-					models.SpaceMetadata{
+					SpaceMetadata: models.SpaceMetadata{
 						Guid: "some-guid",
 					},
 				},
@@ -95,18 +97,18 @@ var _ = Describe("Spaces", func() {
 			It("returns a list of diego applications", func() {
 				expectedApps := models.Spaces{
 					models.Space{
-						models.SpaceEntity{
+						SpaceEntity: models.SpaceEntity{
 							Name: "space-foo",
 						},
-						models.SpaceMetadata{
+						SpaceMetadata: models.SpaceMetadata{
 							Guid: "some-guid",
 						},
 					},
 					models.Space{
-						models.SpaceEntity{
+						SpaceEntity: models.SpaceEntity{
 							Name: "space-foo",
 						},
-						models.SpaceMetadata{
+						SpaceMetadata: models.SpaceMetadata{
 							Guid: "some-guid",
 						},
 					},
@@ -118,3 +120,55 @@ var _ = Describe("Spaces", func() {
 		})
 	})
 })
+
+var _ = Describe("DeveloperSpaces", func() {
+	It("only requests the spaces of that developer", func() {
+		fakePaginatedRequester := new(thingdoerfakes.FakePaginatedRequester)
+		fakePaginatedRequester.DoReturns([][]byte{[]byte("some-json")}, nil)
+		fakeSpacesParser := new(thingdoerfakes.FakeSpacesParser)
+
+		_, err := thingdoer.DeveloperSpaces(context.Background(), fakeSpacesParser, fakePaginatedRequester, "some-user-guid")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, filter, _ := fakePaginatedRequester.DoArgsForCall(0)
+		Expect(filter).To(Equal(api.EqualFilter{Name: "developer_guid", Value: "some-user-guid"}))
+	})
+})
+
+var _ = Describe("VisibleSpaces", func() {
+	var (
+		fakePaginatedRequester *thingdoerfakes.FakePaginatedRequester
+		fakeSpacesParser       *thingdoerfakes.FakeSpacesParser
+	)
+
+	BeforeEach(func() {
+		fakePaginatedRequester = new(thingdoerfakes.FakePaginatedRequester)
+		fakeSpacesParser = new(thingdoerfakes.FakeSpacesParser)
+	})
+
+	It("returns the spaces when they can be listed", func() {
+		fakePaginatedRequester.DoReturns([][]byte{[]byte("some-json")}, nil)
+		fakeSpacesParser.ParseReturns(models.Spaces{{SpaceMetadata: models.SpaceMetadata{Guid: "some-space-guid"}}}, nil)
+
+		spaces, listed, err := thingdoer.VisibleSpaces(context.Background(), fakeSpacesParser, fakePaginatedRequester, api.Filters{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listed).To(BeTrue())
+		Expect(spaces).To(Equal(models.Spaces{{SpaceMetadata: models.SpaceMetadata{Guid: "some-space-guid"}}}))
+	})
+
+	It("reports the spaces as not listed when the token is not allowed to list them", func() {
+		fakePaginatedRequester.DoReturns(nil, api.ForbiddenError{URL: "/v2/spaces"})
+
+		spaces, listed, err := thingdoer.VisibleSpaces(context.Background(), fakeSpacesParser, fakePaginatedRequester, api.Filters{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listed).To(BeFalse())
+		Expect(spaces).To(BeEmpty())
+	})
+
+	It("returns other errors", func() {
+		fakePaginatedRequester.DoReturns(nil, errors.New("disaster"))
+
+		_, _, err := thingdoer.VisibleSpaces(context.Background(), fakeSpacesParser, fakePaginatedRequester, api.Filters{})
+		Expect(err).To(MatchError("disaster"))
+	})
+})
@@ -0,0 +1,38 @@
+package thingdoer
+
+import (
+	"context"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/api"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+)
+
+//go:generate counterfeiter . StacksParser
+type StacksParser interface {
+	Parse([]byte) (models.Stacks, error)
+}
+
+func Stacks(ctx context.Context, stacksParser StacksParser, paginatedRequester PaginatedRequester) (models.Stacks, error) {
+	var noStacks models.Stacks
+
+	filter := api.Filters{}
+	params := map[string]interface{}{}
+
+	responseBodies, err := paginatedRequester.Do(ctx, filter, params)
+	if err != nil {
+		return noStacks, err
+	}
+
+	var stacks models.Stacks
+
+	for _, nextBody := range responseBodies {
+		parsedStacks, err := stacksParser.Parse(nextBody)
+		if err != nil {
+			return noStacks, err
+		}
+
+		stacks = append(stacks, parsedStacks...)
+	}
+
+	return stacks, nil
+}
@@ -0,0 +1,94 @@
+package thingdoer_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer"
+	"github.com/cloudfoundry-incubator/diego-enabler/thingdoer/thingdoerfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stacks", func() {
+	var (
+		fakePaginatedRequester *thingdoerfakes.FakePaginatedRequester
+		fakeStacksParser       *thingdoerfakes.FakeStacksParser
+		stacks                 models.Stacks
+		err                    error
+	)
+
+	BeforeEach(func() {
+		fakePaginatedRequester = new(thingdoerfakes.FakePaginatedRequester)
+		fakeStacksParser = new(thingdoerfakes.FakeStacksParser)
+	})
+
+	JustBeforeEach(func() {
+		stacks, err = thingdoer.Stacks(context.Background(), fakeStacksParser, fakePaginatedRequester)
+	})
+
+	It("should request all stacks", func() {
+		Expect(fakePaginatedRequester.DoCallCount()).To(Equal(1))
+	})
+
+	Context("when the paginated requester fails", func() {
+		var requestError error
+
+		BeforeEach(func() {
+			requestError = errors.New("making API requests failed")
+			fakePaginatedRequester.DoReturns([][]byte{}, requestError)
+		})
+
+		It("returns the requester error", func() {
+			Expect(stacks).To(BeEmpty())
+			Expect(err).To(Equal(requestError))
+		})
+	})
+
+	Context("When the paginated requester succeeds", func() {
+		BeforeEach(func() {
+			responseBodies := [][]byte{
+				[]byte("some-json"),
+				[]byte("some-other-json"),
+			}
+			fakePaginatedRequester.DoReturns(responseBodies, nil)
+		})
+
+		Context("when the parsing fails", func() {
+			var parseError error
+
+			BeforeEach(func() {
+				parseError = errors.New("parsing json failed")
+				fakeStacksParser.ParseReturns(models.Stacks{}, parseError)
+			})
+
+			It("returns the parse error", func() {
+				Expect(stacks).To(BeEmpty())
+				Expect(err).To(Equal(parseError))
+			})
+		})
+
+		Context("when the parsing succeeds", func() {
+			var parsedStacks = models.Stacks{
+				models.Stack{
+					StackEntity: models.StackEntity{
+						Name: "cflinuxfs2",
+					},
+					StackMetadata: models.StackMetadata{
+						Guid: "some-guid",
+					},
+				},
+			}
+
+			BeforeEach(func() {
+				fakeStacksParser.ParseReturns(parsedStacks, nil)
+			})
+
+			It("returns the stacks from every page", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stacks).To(Equal(append(parsedStacks, parsedStacks...)))
+			})
+		})
+	})
+})
@@ -2,6 +2,7 @@
 package thingdoerfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/cloudfoundry-incubator/diego-enabler/api"
@@ -9,9 +10,10 @@ import (
 )
 
 type FakePaginatedRequester struct {
-	DoStub        func(filter api.Filter, params map[string]interface{}) ([][]byte, error)
+	DoStub        func(ctx context.Context, filter api.Filter, params map[string]interface{}) ([][]byte, error)
 	doMutex       sync.RWMutex
 	doArgsForCall []struct {
+		ctx    context.Context
 		filter api.Filter
 		params map[string]interface{}
 	}
@@ -19,17 +21,29 @@ type FakePaginatedRequester struct {
 		result1 [][]byte
 		result2 error
 	}
+	DoEachStub        func(ctx context.Context, filter api.Filter, params map[string]interface{}, handle api.PageFunc) error
+	doEachMutex       sync.RWMutex
+	doEachArgsForCall []struct {
+		ctx    context.Context
+		filter api.Filter
+		params map[string]interface{}
+		handle api.PageFunc
+	}
+	doEachReturns struct {
+		result1 error
+	}
 }
 
-func (fake *FakePaginatedRequester) Do(filter api.Filter, params map[string]interface{}) ([][]byte, error) {
+func (fake *FakePaginatedRequester) Do(ctx context.Context, filter api.Filter, params map[string]interface{}) ([][]byte, error) {
 	fake.doMutex.Lock()
 	fake.doArgsForCall = append(fake.doArgsForCall, struct {
+		ctx    context.Context
 		filter api.Filter
 		params map[string]interface{}
-	}{filter, params})
+	}{ctx, filter, params})
 	fake.doMutex.Unlock()
 	if fake.DoStub != nil {
-		return fake.DoStub(filter, params)
+		return fake.DoStub(ctx, filter, params)
 	} else {
 		return fake.doReturns.result1, fake.doReturns.result2
 	}
@@ -41,10 +55,10 @@ func (fake *FakePaginatedRequester) DoCallCount() int {
 	return len(fake.doArgsForCall)
 }
 
-func (fake *FakePaginatedRequester) DoArgsForCall(i int) (api.Filter, map[string]interface{}) {
+func (fake *FakePaginatedRequester) DoArgsForCall(i int) (context.Context, api.Filter, map[string]interface{}) {
 	fake.doMutex.RLock()
 	defer fake.doMutex.RUnlock()
-	return fake.doArgsForCall[i].filter, fake.doArgsForCall[i].params
+	return fake.doArgsForCall[i].ctx, fake.doArgsForCall[i].filter, fake.doArgsForCall[i].params
 }
 
 func (fake *FakePaginatedRequester) DoReturns(result1 [][]byte, result2 error) {
@@ -55,4 +69,39 @@ func (fake *FakePaginatedRequester) DoReturns(result1 [][]byte, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakePaginatedRequester) DoEach(ctx context.Context, filter api.Filter, params map[string]interface{}, handle api.PageFunc) error {
+	fake.doEachMutex.Lock()
+	fake.doEachArgsForCall = append(fake.doEachArgsForCall, struct {
+		ctx    context.Context
+		filter api.Filter
+		params map[string]interface{}
+		handle api.PageFunc
+	}{ctx, filter, params, handle})
+	fake.doEachMutex.Unlock()
+	if fake.DoEachStub != nil {
+		return fake.DoEachStub(ctx, filter, params, handle)
+	} else {
+		return fake.doEachReturns.result1
+	}
+}
+
+func (fake *FakePaginatedRequester) DoEachCallCount() int {
+	fake.doEachMutex.RLock()
+	defer fake.doEachMutex.RUnlock()
+	return len(fake.doEachArgsForCall)
+}
+
+func (fake *FakePaginatedRequester) DoEachArgsForCall(i int) (context.Context, api.Filter, map[string]interface{}, api.PageFunc) {
+	fake.doEachMutex.RLock()
+	defer fake.doEachMutex.RUnlock()
+	return fake.doEachArgsForCall[i].ctx, fake.doEachArgsForCall[i].filter, fake.doEachArgsForCall[i].params, fake.doEachArgsForCall[i].handle
+}
+
+func (fake *FakePaginatedRequester) DoEachReturns(result1 error) {
+	fake.DoEachStub = nil
+	fake.doEachReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ thingdoer.PaginatedRequester = new(FakePaginatedRequester)
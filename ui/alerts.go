@@ -1,13 +1,52 @@
 package ui
 
-import "github.com/fatih/color"
+import (
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+)
 
 func SayOK() {
+	SayOKTo(color.Output)
+}
+
+func SayOKTo(w io.Writer) {
+	if Quiet {
+		return
+	}
 	c := color.New(color.FgGreen).Add(color.Bold)
-	c.Println("OK\n")
+	fmt.Fprintf(w, "%s\n\n", c.SprintFunc()("OK"))
+}
+
+// failedToLog is set by UseOutputFormat for JSON output.
+var failedToLog bool
+
+// UseOutputFormat is called with the output format of a command before it
+// prints anything. For json and ndjson, SayFailed then writes FAILED to
+// LogOutput, so that stdout only holds the JSON.
+func UseOutputFormat(format string) {
+	failedToLog = format == JSONOutput || format == NDJSONOutput
 }
 
 func SayFailed() {
+	if failedToLog {
+		SayFailedTo(LogOutput)
+		return
+	}
+	SayFailedTo(color.Output)
+}
+
+func SayFailedTo(w io.Writer) {
+	if Quiet {
+		return
+	}
 	c := color.New(color.FgRed).Add(color.Bold)
-	c.Println("FAILED")
+	fmt.Fprintln(w, c.SprintFunc()("FAILED"))
+}
+
+// WarnSpacesNotListed explains why apps are shown with the guid of their
+// space, for a token that is not allowed to list spaces.
+func WarnSpacesNotListed() {
+	Warn("Your token is not allowed to list spaces, as with a space-scoped token, so apps are shown with the guid of their space instead of its name and org\n")
 }
@@ -0,0 +1,21 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+// SayApiInfo prints what the targeted Cloud Controller reports about
+// itself to LogOutput, so that it can be asked for alongside any command.
+func SayApiInfo(endpoint string, info models.Info) {
+	diegoDefault := "unknown, not reported by the Cloud Controller"
+	if info.DefaultToDiego != nil {
+		diegoDefault = fmt.Sprint(*info.DefaultToDiego)
+	}
+
+	fmt.Fprintf(LogOutput, "API endpoint:   %s\n", terminal.EntityNameColor(endpoint))
+	fmt.Fprintf(LogOutput, "API version:    %s\n", terminal.EntityNameColor(info.ApiVersion))
+	fmt.Fprintf(LogOutput, "Diego default:  %s\n\n", diegoDefault)
+}
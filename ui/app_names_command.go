@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+// NotFound is shown in place of the name of an app guid that no app has.
+const NotFound = "not found"
+
+type AppNamesCommand struct {
+	Username string
+	Output   string
+	UI       terminal.UI
+}
+
+// AppName is the name and location that an app guid resolved to. Found is
+// false, and the other fields empty, for guids that no visible app has.
+type AppName struct {
+	Guid         string `json:"guid"`
+	Found        bool   `json:"found"`
+	Name         string `json:"name,omitempty"`
+	Space        string `json:"space,omitempty"`
+	Organization string `json:"org,omitempty"`
+}
+
+func (c *AppNamesCommand) BeforeAll(count int) {
+	fmt.Fprintf(
+		c.infoWriter(),
+		"Looking up the names of %d app guids as %s...\n",
+		count,
+		terminal.EntityNameColor(c.Username),
+	)
+}
+
+func (c *AppNamesCommand) AfterAll(names []AppName) error {
+	SayOKTo(c.infoWriter())
+
+	if c.Output == JSONOutput {
+		return json.NewEncoder(os.Stdout).Encode(names)
+	}
+
+	t := terminal.NewTable(c.UI, []string{"guid", "name", "space", "org"})
+	for _, name := range names {
+		if name.Found {
+			t.Add(name.Guid, name.Name, name.Space, name.Organization)
+		} else {
+			t.Add(name.Guid, NotFound, "", "")
+		}
+	}
+	t.Print()
+	return nil
+}
+
+// infoWriter keeps progress messages off stdout when the mapping is JSON.
+func (c *AppNamesCommand) infoWriter() io.Writer {
+	if Quiet {
+		return ioutil.Discard
+	}
+	if c.Output == JSONOutput {
+		return os.Stderr
+	}
+	return os.Stdout
+}
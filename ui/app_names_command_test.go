@@ -0,0 +1,74 @@
+package ui_test
+
+import (
+	"os"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("AppNamesCommand", func() {
+	var (
+		command AppNamesCommand
+		names   []AppName
+
+		buf    *gbytes.Buffer
+		stdout *os.File
+		err    error
+	)
+
+	BeforeEach(func() {
+		command = AppNamesCommand{
+			Username: "some-user",
+			UI:       terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), trace.NewLogger(false, "", "")),
+		}
+
+		names = []AppName{
+			{Guid: "web-guid", Found: true, Name: "web", Space: "dev", Organization: "some-org"},
+			{Guid: "missing-guid"},
+		}
+
+		buf = gbytes.NewBuffer()
+		stdout = captureStdout(buf)
+	})
+
+	AfterEach(func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	})
+
+	Describe("AfterAll", func() {
+		JustBeforeEach(func() {
+			err = command.AfterAll(names)
+			os.Stdout.Close()
+		})
+
+		It("prints a row per guid, with not found for unknown guids", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(buf.Closed).Should(BeTrue())
+			Expect(string(buf.Contents())).To(MatchRegexp("guid +name +space +org"))
+			Expect(string(buf.Contents())).To(MatchRegexp("web-guid +web +dev +some-org"))
+			Expect(string(buf.Contents())).To(MatchRegexp("missing-guid +not found"))
+		})
+
+		Context("when the output is json", func() {
+			BeforeEach(func() {
+				command.Output = JSONOutput
+			})
+
+			It("prints the mapping as a JSON array", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(buf.Contents()).To(MatchJSON(`[
+					{"guid": "web-guid", "found": true, "name": "web", "space": "dev", "org": "some-org"},
+					{"guid": "missing-guid", "found": false}
+				]`))
+			})
+		})
+	})
+})
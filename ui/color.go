@@ -0,0 +1,24 @@
+package ui
+
+import (
+	"os"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/fatih/color"
+)
+
+func init() {
+	InitColorSupport()
+}
+
+// InitColorSupport turns colored output off when NO_COLOR is set. Both
+// color packages already leave it off when stdout is not a terminal.
+func InitColorSupport() {
+	if os.Getenv("NO_COLOR") == "" {
+		return
+	}
+
+	color.NoColor = true
+	terminal.UserAskedForColors = "false"
+	terminal.InitColorSupport()
+}
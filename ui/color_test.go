@@ -0,0 +1,63 @@
+package ui_test
+
+import (
+	"os"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/fatih/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("InitColorSupport", func() {
+	var (
+		noColor            bool
+		userAskedForColors string
+	)
+
+	BeforeEach(func() {
+		noColor = color.NoColor
+		userAskedForColors = terminal.UserAskedForColors
+
+		color.NoColor = false
+		terminal.UserAskedForColors = "true"
+		terminal.InitColorSupport()
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("NO_COLOR")
+		color.NoColor = noColor
+		terminal.UserAskedForColors = userAskedForColors
+		terminal.InitColorSupport()
+	})
+
+	Context("when NO_COLOR is set", func() {
+		BeforeEach(func() {
+			os.Setenv("NO_COLOR", "1")
+			InitColorSupport()
+		})
+
+		It("prints OK without escape sequences", func() {
+			buf := gbytes.NewBuffer()
+			SayOKTo(buf)
+			Expect(string(buf.Contents())).To(Equal("OK\n\n"))
+		})
+
+		It("prints entity names without escape sequences", func() {
+			Expect(terminal.EntityNameColor("some-app")).To(Equal("some-app"))
+		})
+	})
+
+	Context("when NO_COLOR is not set", func() {
+		BeforeEach(func() {
+			InitColorSupport()
+		})
+
+		It("leaves colors on", func() {
+			Expect(terminal.EntityNameColor("some-app")).NotTo(Equal("some-app"))
+		})
+	})
+})
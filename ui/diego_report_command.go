@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+type DiegoReportCommand struct {
+	Username string
+	Output   string
+	UI       terminal.UI
+}
+
+// OrgReport counts the apps in one organization by runtime.
+type OrgReport struct {
+	Organization    string  `json:"org"`
+	Total           int     `json:"total"`
+	Diego           int     `json:"diego"`
+	DEA             int     `json:"dea"`
+	PercentMigrated float64 `json:"percent_migrated"`
+}
+
+func (r *OrgReport) add(app ApplicationPrinter) {
+	r.Total++
+	if app.Diego() {
+		r.Diego++
+	} else {
+		r.DEA++
+	}
+	r.PercentMigrated = percentOf(r.Diego, r.Total)
+}
+
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// OrgReports groups apps by organization, sorted by organization name.
+func OrgReports(apps []ApplicationPrinter) []OrgReport {
+	reports := make(map[string]*OrgReport)
+	for _, app := range apps {
+		org := app.Organization()
+		if reports[org] == nil {
+			reports[org] = &OrgReport{Organization: org}
+		}
+		reports[org].add(app)
+	}
+
+	sorted := make([]OrgReport, 0, len(reports))
+	for _, report := range reports {
+		sorted = append(sorted, *report)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Organization < sorted[j].Organization
+	})
+	return sorted
+}
+
+func (c *DiegoReportCommand) BeforeAll() {
+	fmt.Fprintf(
+		c.infoWriter(),
+		"Getting the Diego migration report for all orgs as %s...\n",
+		terminal.EntityNameColor(c.Username),
+	)
+}
+
+func (c *DiegoReportCommand) AfterAll(apps []ApplicationPrinter) error {
+	SayOKTo(c.infoWriter())
+
+	reports := OrgReports(apps)
+	if c.Output == JSONOutput {
+		return json.NewEncoder(os.Stdout).Encode(reports)
+	}
+
+	t := terminal.NewTable(c.UI, []string{"org", "apps", "diego", "dea", "migrated"})
+	diego := 0
+	for _, report := range reports {
+		t.Add(
+			report.Organization,
+			strconv.Itoa(report.Total),
+			strconv.Itoa(report.Diego),
+			strconv.Itoa(report.DEA),
+			fmt.Sprintf("%.1f%%", report.PercentMigrated),
+		)
+		diego += report.Diego
+	}
+	t.Print()
+
+	fmt.Fprintf(
+		c.infoWriter(),
+		"\n%d of %d apps (%.1f%%) are on the Diego runtime.\n",
+		diego,
+		len(apps),
+		percentOf(diego, len(apps)),
+	)
+	return nil
+}
+
+// PrintChanges prints the apps that differ between the snapshots at
+// oldPath and newPath.
+func (c *DiegoReportCommand) PrintChanges(oldPath, newPath string, changes []models.AppChange) error {
+	fmt.Fprintf(
+		c.infoWriter(),
+		"Comparing %s with %s...\n",
+		terminal.EntityNameColor(oldPath),
+		terminal.EntityNameColor(newPath),
+	)
+	SayOKTo(c.infoWriter())
+
+	if c.Output == JSONOutput {
+		return json.NewEncoder(os.Stdout).Encode(changes)
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No apps changed.")
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Change]++
+		fmt.Printf("%s: %s (%s)\n", change.Change, change.Name, change.Guid)
+		for _, field := range change.Fields {
+			fmt.Printf("   %s: %q -> %q\n", field.Field, field.Old, field.New)
+		}
+	}
+
+	fmt.Printf(
+		"\n%d added, %d removed, %d changed\n",
+		counts[models.AppAdded],
+		counts[models.AppRemoved],
+		counts[models.AppChanged],
+	)
+	return nil
+}
+
+// infoWriter keeps progress messages off stdout when the report is JSON.
+func (c *DiegoReportCommand) infoWriter() io.Writer {
+	if Quiet {
+		return ioutil.Discard
+	}
+	if c.Output == JSONOutput {
+		return os.Stderr
+	}
+	return os.Stdout
+}
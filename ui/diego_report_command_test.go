@@ -0,0 +1,149 @@
+package ui_test
+
+import (
+	"os"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("DiegoReportCommand", func() {
+	var (
+		command DiegoReportCommand
+		apps    []ApplicationPrinter
+
+		buf    *gbytes.Buffer
+		stdout *os.File
+		err    error
+	)
+
+	appInOrg := func(org string, diego bool) ApplicationPrinter {
+		return &displayhelpers.AppPrinter{
+			App: models.Application{
+				ApplicationEntity: models.ApplicationEntity{
+					Diego:     diego,
+					SpaceGuid: org + "-space-guid",
+				},
+			},
+			Spaces: map[string]models.Space{
+				org + "-space-guid": models.Space{
+					SpaceEntity: models.SpaceEntity{
+						Organization: models.Organization{
+							OrganizationEntity: models.OrganizationEntity{Name: org},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		command = DiegoReportCommand{
+			Username: "some-user",
+			UI:       terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), trace.NewLogger(false, "", "")),
+		}
+
+		apps = []ApplicationPrinter{
+			appInOrg("org-b", true),
+			appInOrg("org-a", true),
+			appInOrg("org-a", false),
+			appInOrg("org-a", false),
+			appInOrg("org-a", true),
+		}
+
+		buf = gbytes.NewBuffer()
+		stdout = captureStdout(buf)
+	})
+
+	AfterEach(func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	})
+
+	Describe("OrgReports", func() {
+		It("counts the apps of each organization by runtime", func() {
+			Expect(OrgReports(apps)).To(Equal([]OrgReport{
+				{Organization: "org-a", Total: 4, Diego: 2, DEA: 2, PercentMigrated: 50},
+				{Organization: "org-b", Total: 1, Diego: 1, DEA: 0, PercentMigrated: 100},
+			}))
+		})
+	})
+
+	Describe("AfterAll", func() {
+		JustBeforeEach(func() {
+			err = command.AfterAll(apps)
+			os.Stdout.Close()
+		})
+
+		It("prints a row per organization and the overall progress", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(buf.Closed).Should(BeTrue())
+			Expect(string(buf.Contents())).To(MatchRegexp("org +apps +diego +dea +migrated"))
+			Expect(string(buf.Contents())).To(MatchRegexp("org-a +4 +2 +2 +50.0%"))
+			Expect(string(buf.Contents())).To(MatchRegexp("org-b +1 +1 +0 +100.0%"))
+			Expect(string(buf.Contents())).To(ContainSubstring("3 of 5 apps (60.0%) are on the Diego runtime."))
+		})
+
+		Context("when the output is json", func() {
+			BeforeEach(func() {
+				command.Output = JSONOutput
+			})
+
+			It("prints the organizations as a JSON array", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(buf.Contents()).To(MatchJSON(`[
+					{"org": "org-a", "total": 4, "diego": 2, "dea": 2, "percent_migrated": 50},
+					{"org": "org-b", "total": 1, "diego": 1, "dea": 0, "percent_migrated": 100}
+				]`))
+			})
+		})
+	})
+
+	Describe("PrintChanges", func() {
+		changes := []models.AppChange{
+			{Name: "api", Guid: "api-guid", Change: models.AppAdded},
+			{
+				Name:   "web",
+				Guid:   "web-guid",
+				Change: models.AppChanged,
+				Fields: []models.FieldChange{{Field: "diego", Old: "false", New: "true"}},
+			},
+		}
+
+		JustBeforeEach(func() {
+			err = command.PrintChanges("before.json", "after.json", changes)
+			os.Stdout.Close()
+		})
+
+		It("prints each app that changed, with its fields, and a tally", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(buf.Closed).Should(BeTrue())
+			Expect(string(buf.Contents())).To(ContainSubstring("added: api (api-guid)\n"))
+			Expect(string(buf.Contents())).To(ContainSubstring("changed: web (web-guid)\n   diego: \"false\" -> \"true\"\n"))
+			Expect(string(buf.Contents())).To(ContainSubstring("1 added, 0 removed, 1 changed"))
+		})
+
+		Context("when the output is json", func() {
+			BeforeEach(func() {
+				command.Output = JSONOutput
+			})
+
+			It("prints the changes as a JSON array", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(buf.Contents()).To(MatchJSON(`[
+					{"name": "api", "guid": "api-guid", "change": "added"},
+					{"name": "web", "guid": "web-guid", "change": "changed", "fields": [{"field": "diego", "old": "false", "new": "true"}]}
+				]`))
+			})
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+// DoctorCommand prints the checklist of diego-enabler-doctor, one line per
+// check.
+type DoctorCommand struct{}
+
+func (c *DoctorCommand) BeforeAll() {
+	Info("Checking that the plugin can talk to the Cloud Controller...\n\n")
+}
+
+func (c *DoctorCommand) PassedEach(check string, detail string) {
+	fmt.Printf("%s %s: %s\n", terminal.SuccessColor("[PASS]"), check, detail)
+}
+
+func (c *DoctorCommand) FailedEach(check string, err error, hint string) {
+	fmt.Printf("%s %s: %s\n", terminal.FailureColor("[FAIL]"), check, err.Error())
+	fmt.Printf("       %s\n", hint)
+}
+
+func (c *DoctorCommand) SkippedEach(check string, reason string) {
+	fmt.Printf("%s %s: %s\n", terminal.AdvisoryColor("[SKIP]"), check, reason)
+}
+
+func (c *DoctorCommand) AfterAll(failed int) {
+	fmt.Println()
+	if failed == 0 {
+		fmt.Println("Every check passed")
+		return
+	}
+	fmt.Printf("%d checks failed; follow the hints above and run diego-enabler-doctor again\n", failed)
+}
@@ -0,0 +1,12 @@
+package ui
+
+import "fmt"
+
+const DryRunBanner = "DRY RUN — no changes made"
+
+// SayDryRun closes the output of a dry run. It is a result rather than
+// progress, so it is printed even when Quiet is set.
+func SayDryRun() {
+	fmt.Println()
+	fmt.Println(DryRunBanner)
+}
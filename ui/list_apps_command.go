@@ -1,58 +1,483 @@
 package ui
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
 )
 
+const (
+	TableOutput  = "table"
+	JSONOutput   = "json"
+	CSVOutput    = "csv"
+	NDJSONOutput = "ndjson"
+)
+
+const (
+	SortByName  = "name"
+	SortBySpace = "space"
+	SortByOrg   = "org"
+)
+
+const GroupByOrg = "org"
+
 type ListAppsCommand struct {
 	Username     string
 	Runtime      Runtime
 	Organization string
 	Space        string
-	UI           terminal.UI
+	Output       string
+	Count        bool
+
+	// Guids prints only the guid of each app, one per line, for scripts.
+	Guids bool
+	UI    terminal.UI
+
+	SortField      string
+	SortDescending bool
+
+	// Columns picks the table columns to show, from TableColumns.
+	Columns []string
+
+	// Width, when greater than zero, is the width the table must fit in;
+	// long names, spaces and orgs are truncated to make it fit.
+	Width int
+
+	// GroupBy, when set to GroupByOrg, prints a table per org.
+	GroupBy string
+
+	// Out, when set, receives the listing instead of stdout. Use SetOut so
+	// that the table goes there too.
+	Out io.Writer
+}
+
+// SetOut sends the listing to w, without colors, while the informational
+// lines still go to the terminal.
+func (c *ListAppsCommand) SetOut(w io.Writer) {
+	c.Out = w
+	c.UI = terminal.NewUI(os.Stdin, plainPrinter{Writer: w}, trace.NewLogger(false, "", ""))
+}
+
+func (c *ListAppsCommand) out() io.Writer {
+	if c.Out == nil {
+		return os.Stdout
+	}
+	return c.Out
+}
+
+// allRuntimes reports whether the listing covers apps on every runtime, in
+// which case each app's runtime is shown alongside it.
+func (c *ListAppsCommand) allRuntimes() bool {
+	return c.Runtime == AllRuntimes
+}
+
+type applicationJSON struct {
+	Name         string `json:"name"`
+	Guid         string `json:"guid"`
+	Space        string `json:"space"`
+	Organization string `json:"org"`
+	Diego        bool   `json:"diego"`
+	Buildpack    string `json:"buildpack,omitempty"`
+	DockerImage  string `json:"docker_image,omitempty"`
+}
+
+func (c *ListAppsCommand) runtimePhrase() string {
+	if c.allRuntimes() {
+		return "on all runtimes"
+	}
+	return fmt.Sprintf("on the %s runtime", terminal.EntityNameColor(c.Runtime.String()))
 }
 
 func (c *ListAppsCommand) BeforeAll() {
+	w := c.infoWriter()
+	runtime := c.runtimePhrase()
+
 	switch {
 	case c.Space != "" && c.Organization != "":
-		fmt.Printf(
-			"Getting apps on the %s runtime in org %s / %s as %s...\n",
-			terminal.EntityNameColor(c.Runtime.String()),
+		fmt.Fprintf(
+			w,
+			"Getting apps %s in org %s / %s as %s...\n",
+			runtime,
 			terminal.EntityNameColor(c.Organization),
 			terminal.EntityNameColor(c.Space),
 			terminal.EntityNameColor(c.Username),
 		)
 	case c.Organization != "":
-		fmt.Printf(
-			"Getting apps on the %s runtime in org %s as %s...\n",
-			terminal.EntityNameColor(c.Runtime.String()),
+		fmt.Fprintf(
+			w,
+			"Getting apps %s in org %s as %s...\n",
+			runtime,
 			terminal.EntityNameColor(c.Organization),
 			terminal.EntityNameColor(c.Username),
 		)
 	default:
-		fmt.Printf(
-			"Getting apps on the %s runtime as %s...\n",
-			terminal.EntityNameColor(c.Runtime.String()),
+		fmt.Fprintf(
+			w,
+			"Getting apps %s as %s...\n",
+			runtime,
 			terminal.EntityNameColor(c.Username),
 		)
 	}
 }
 
-func (c *ListAppsCommand) AfterAll(apps []ApplicationPrinter) {
-	SayOK()
+func (c *ListAppsCommand) AfterWriteFile(path string) {
+	if Quiet {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the app list to %s\n", path)
+}
+
+func (c *ListAppsCommand) AfterAll(apps []ApplicationPrinter) error {
+	SayOKTo(c.infoWriter())
+
+	if c.Count {
+		fmt.Fprintln(c.out(), len(apps))
+		return nil
+	}
+
+	if c.Guids {
+		for _, app := range apps {
+			fmt.Fprintln(c.out(), app.Guid())
+		}
+		return nil
+	}
+
+	c.sortApps(apps)
+	c.warnDockerAppsOnDEA(apps)
+
+	switch c.Output {
+	case JSONOutput:
+		return c.printJSON(apps)
+	case NDJSONOutput:
+		return c.printNDJSON(apps)
+	case CSVOutput:
+		return c.printCSV(apps)
+	default:
+		c.printTable(apps)
+		return nil
+	}
+}
+
+// Streams reports whether each app is to be printed with PrintStreamed as
+// soon as it is fetched, rather than all of them with AfterAll. Only ndjson
+// output can be streamed, and only when the apps need not be sorted or
+// counted first.
+func (c *ListAppsCommand) Streams() bool {
+	return c.Output == NDJSONOutput && c.SortField == "" && !c.Count && !c.Guids
+}
+
+// PrintStreamed prints a single app of a streamed listing.
+func (c *ListAppsCommand) PrintStreamed(app ApplicationPrinter) error {
+	c.warnDockerAppsOnDEA([]ApplicationPrinter{app})
+	return json.NewEncoder(c.out()).Encode(newApplicationJSON(app))
+}
+
+// AfterStream ends a streamed listing once every app has been printed.
+func (c *ListAppsCommand) AfterStream() {
+	SayOKTo(c.infoWriter())
+}
+
+// TableColumns lists the columns that can be shown in the app table, in
+// the order they are shown by default.
+var TableColumns = []string{
+	"name",
+	"guid",
+	"space",
+	"org",
+	"state",
+	"instances",
+	"memory",
+	"stack",
+	"runtime",
+	"buildpack",
+	"docker_image",
+}
+
+var tableCells = map[string]func(ApplicationPrinter) string{
+	"name":         ApplicationPrinter.Name,
+	"guid":         ApplicationPrinter.Guid,
+	"space":        ApplicationPrinter.Space,
+	"org":          ApplicationPrinter.Organization,
+	"state":        ApplicationPrinter.State,
+	"instances":    func(app ApplicationPrinter) string { return strconv.Itoa(app.Instances()) },
+	"memory":       func(app ApplicationPrinter) string { return fmt.Sprintf("%dM", app.Memory()) },
+	"stack":        ApplicationPrinter.Stack,
+	"runtime":      func(app ApplicationPrinter) string { return runtimeOf(app).String() },
+	"buildpack":    ApplicationPrinter.Buildpack,
+	"docker_image": ApplicationPrinter.DockerImage,
+}
+
+// tableColumns returns the requested columns, or by default every column
+// except guid and buildpack, which are only shown on request, runtime,
+// which is only shown when apps on all runtimes are listed, and
+// docker_image, which is only shown when one of the apps has a docker
+// image.
+func (c *ListAppsCommand) tableColumns(apps []ApplicationPrinter) []string {
+	if len(c.Columns) > 0 {
+		return c.Columns
+	}
+
+	var columns []string
+	for _, column := range TableColumns {
+		if column == "guid" || column == "buildpack" || (column == "runtime" && !c.allRuntimes()) {
+			continue
+		}
+		if column == "docker_image" && !anyDockerImage(apps) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+func anyDockerImage(apps []ApplicationPrinter) bool {
+	for _, app := range apps {
+		if app.DockerImage() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// warnDockerAppsOnDEA points out docker apps that are on the DEA runtime,
+// which cannot run docker images, so something has gone wrong with them.
+func (c *ListAppsCommand) warnDockerAppsOnDEA(apps []ApplicationPrinter) {
+	for _, app := range apps {
+		if app.DockerImage() != "" && !app.Diego() {
+			Warn(
+				"App %s runs docker image %s but is on DEA, which does not support docker\n",
+				terminal.EntityNameColor(app.Name()),
+				terminal.EntityNameColor(app.DockerImage()),
+			)
+		}
+	}
+}
+
+func (c *ListAppsCommand) printTable(apps []ApplicationPrinter) {
+	if c.GroupBy == GroupByOrg {
+		c.printGroupedTables(apps)
+	} else {
+		c.printAppsTable(apps)
+	}
+
+	noun := "apps"
+	if len(apps) == 1 {
+		noun = "app"
+	}
+	fmt.Fprintf(c.infoWriter(), "\nShowing %d %s %s.\n", len(apps), noun, c.runtimePhrase())
+}
+
+// printGroupedTables prints a table per org, in order of org name, keeping
+// the apps of each org in the order they were sorted in.
+func (c *ListAppsCommand) printGroupedTables(apps []ApplicationPrinter) {
+	groups := make(map[string][]ApplicationPrinter)
+	var orgs []string
+	for _, app := range apps {
+		org := app.Organization()
+		if _, ok := groups[org]; !ok {
+			orgs = append(orgs, org)
+		}
+		groups[org] = append(groups[org], app)
+	}
+	sort.Strings(orgs)
 
-	headers := []string{
-		"name",
-		"space",
-		"org",
+	for i, org := range orgs {
+		if i > 0 {
+			c.UI.Say("")
+		}
+		c.UI.Say("Apps in org %s:", terminal.EntityNameColor(org))
+		c.printAppsTable(groups[org])
 	}
-	t := terminal.NewTable(c.UI, headers)
+}
+
+func (c *ListAppsCommand) printAppsTable(apps []ApplicationPrinter) {
+	columns := c.tableColumns(apps)
+	t := terminal.NewTable(c.UI, columns)
 
+	rows := make([][]string, 0, len(apps))
 	for _, app := range apps {
-		t.Add(app.Name(), app.Space(), app.Organization())
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, tableCells[column](app))
+		}
+		rows = append(rows, row)
+	}
+
+	if c.Width > 0 {
+		fitToWidth(columns, rows, c.Width)
+	}
+
+	for _, row := range rows {
+		t.Add(row...)
 	}
 
 	t.Print()
 }
+
+// truncatableColumns are the columns whose cells can be shortened to fit
+// the table in the terminal.
+var truncatableColumns = map[string]bool{
+	"name":  true,
+	"space": true,
+	"org":   true,
+}
+
+const (
+	columnSeparatorWidth = 3
+	minTruncatedWidth    = 8
+	ellipsis             = "..."
+)
+
+// fitToWidth narrows the widest truncatable column, one character at a
+// time, until the table fits in width or every truncatable column is down
+// to minTruncatedWidth, then truncates the cells that no longer fit.
+func fitToWidth(columns []string, rows [][]string, width int) {
+	widths := make([]int, len(columns))
+	total := 0
+	for i, column := range columns {
+		widths[i] = utf8.RuneCountInString(column)
+		for _, row := range rows {
+			if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+		total += widths[i] + columnSeparatorWidth
+	}
+
+	for total > width {
+		widest := -1
+		for i, column := range columns {
+			if truncatableColumns[column] && widths[i] > minTruncatedWidth && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if truncatableColumns[columns[i]] {
+				row[i] = truncate(cell, widths[i])
+			}
+		}
+	}
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-len(ellipsis)]) + ellipsis
+}
+
+func newApplicationJSON(app ApplicationPrinter) applicationJSON {
+	return applicationJSON{
+		Name:         app.Name(),
+		Guid:         app.Guid(),
+		Space:        app.Space(),
+		Organization: app.Organization(),
+		Diego:        app.Diego(),
+		Buildpack:    app.Buildpack(),
+		DockerImage:  app.DockerImage(),
+	}
+}
+
+func (c *ListAppsCommand) printJSON(apps []ApplicationPrinter) error {
+	output := make([]applicationJSON, 0, len(apps))
+	for _, app := range apps {
+		output = append(output, newApplicationJSON(app))
+	}
+
+	return json.NewEncoder(c.out()).Encode(output)
+}
+
+// printNDJSON prints each app as a JSON object on a line of its own, so
+// that the listing can be read an app at a time.
+func (c *ListAppsCommand) printNDJSON(apps []ApplicationPrinter) error {
+	encoder := json.NewEncoder(c.out())
+	for _, app := range apps {
+		if err := encoder.Encode(newApplicationJSON(app)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ListAppsCommand) printCSV(apps []ApplicationPrinter) error {
+	w := csv.NewWriter(c.out())
+
+	err := w.Write([]string{"name", "space", "org", "guid"})
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		err = w.Write([]string{app.Name(), app.Space(), app.Organization(), app.Guid()})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (c *ListAppsCommand) sortApps(apps []ApplicationPrinter) {
+	var key func(ApplicationPrinter) string
+	switch c.SortField {
+	case SortByName:
+		key = ApplicationPrinter.Name
+	case SortBySpace:
+		key = ApplicationPrinter.Space
+	case SortByOrg:
+		key = ApplicationPrinter.Organization
+	default:
+		return
+	}
+
+	var sorter sort.Interface = appsByKey{apps: apps, key: key}
+	if c.SortDescending {
+		sorter = sort.Reverse(sorter)
+	}
+	sort.Stable(sorter)
+}
+
+type appsByKey struct {
+	apps []ApplicationPrinter
+	key  func(ApplicationPrinter) string
+}
+
+func (a appsByKey) Len() int           { return len(a.apps) }
+func (a appsByKey) Swap(i, j int)      { a.apps[i], a.apps[j] = a.apps[j], a.apps[i] }
+func (a appsByKey) Less(i, j int) bool { return a.key(a.apps[i]) < a.key(a.apps[j]) }
+
+// infoWriter keeps progress messages off stdout when the listing itself is
+// meant to be consumed by another program.
+func (c *ListAppsCommand) infoWriter() io.Writer {
+	if c.Count || c.Guids || Quiet {
+		return ioutil.Discard
+	}
+
+	switch c.Output {
+	case JSONOutput, NDJSONOutput:
+		return os.Stderr
+	case CSVOutput:
+		return ioutil.Discard
+	default:
+		return os.Stdout
+	}
+}
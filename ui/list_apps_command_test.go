@@ -0,0 +1,575 @@
+package ui_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/diego-enabler/commands/displayhelpers"
+	"github.com/cloudfoundry-incubator/diego-enabler/models"
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ListAppsCommand", func() {
+	var (
+		command ListAppsCommand
+		apps    []ApplicationPrinter
+
+		buf    *gbytes.Buffer
+		stdout *os.File
+		err    error
+	)
+
+	BeforeEach(func() {
+		command = ListAppsCommand{
+			Username: "some-user",
+			Runtime:  Diego,
+		}
+
+		apps = []ApplicationPrinter{
+			&displayhelpers.AppPrinter{
+				App: models.Application{
+					ApplicationEntity: models.ApplicationEntity{
+						Name:      "some-app",
+						Diego:     true,
+						State:     models.Started,
+						Instances: 2,
+						Memory:    512,
+						StackGuid: "some-stack-guid",
+						SpaceGuid: "some-space-guid",
+					},
+					ApplicationMetadata: models.ApplicationMetadata{
+						Guid: "some-app-guid",
+					},
+				},
+				Spaces: map[string]models.Space{
+					"some-space-guid": models.Space{
+						SpaceEntity: models.SpaceEntity{
+							Name: "some-space",
+							Organization: models.Organization{
+								OrganizationEntity: models.OrganizationEntity{
+									Name: "some-org",
+								},
+							},
+						},
+					},
+				},
+				Stacks: map[string]models.Stack{
+					"some-stack-guid": models.Stack{
+						StackEntity: models.StackEntity{
+							Name: "cflinuxfs2",
+						},
+					},
+				},
+			},
+		}
+
+		buf = gbytes.NewBuffer()
+		stdout = captureStdout(buf)
+	})
+
+	AfterEach(func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	})
+
+	Describe("AfterAll", func() {
+		JustBeforeEach(func() {
+			err = command.AfterAll(apps)
+			os.Stdout.Close()
+		})
+
+		Context("when the output is a table", func() {
+			BeforeEach(func() {
+				command.UI = terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), trace.NewLogger(false, "", ""))
+			})
+
+			It("prints the state and footprint of each app", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).To(MatchRegexp("name.*space.*org.*state.*instances.*memory.*stack"))
+				Expect(string(buf.Contents())).To(MatchRegexp("some-app.*some-space.*some-org.*started.*2.*512M.*cflinuxfs2"))
+				Expect(string(buf.Contents())).NotTo(MatchRegexp("stack +runtime"))
+			})
+
+			It("prints how many apps were shown", func() {
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).To(HaveSuffix("\nShowing 1 app on the Diego runtime.\n"))
+			})
+
+			Context("when --quiet is given", func() {
+				BeforeEach(func() {
+					Quiet = true
+				})
+
+				AfterEach(func() {
+					Quiet = false
+				})
+
+				It("does not print the count", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(ContainSubstring("some-app"))
+					Expect(string(buf.Contents())).NotTo(ContainSubstring("Showing"))
+				})
+			})
+
+			Context("when the app's space is not visible to the user", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.SpaceGuid = "hidden-space-guid"
+				})
+
+				It("shows its space and org as inaccessible", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("some-app +\\(inaccessible\\) +\\(inaccessible\\)"))
+					Expect(string(buf.Contents())).NotTo(ContainSubstring("hidden-space-guid"))
+				})
+			})
+
+			Context("when columns are picked", func() {
+				BeforeEach(func() {
+					command.Columns = []string{"org", "name"}
+				})
+
+				It("prints only those columns, in that order", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("org +name"))
+					Expect(string(buf.Contents())).To(MatchRegexp("some-org +some-app"))
+					Expect(string(buf.Contents())).NotTo(ContainSubstring("state"))
+				})
+			})
+
+			Context("when an app name is wider than the terminal allows", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.Name = "a-very-long-application-name-indeed"
+					command.Columns = []string{"name", "space", "org"}
+				})
+
+				It("leaves the names alone when the width is unknown", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(ContainSubstring("a-very-long-application-name-indeed"))
+				})
+
+				Context("when the terminal width is known", func() {
+					BeforeEach(func() {
+						command.Width = 40
+					})
+
+					It("truncates the longest names to fit", func() {
+						Eventually(buf.Closed).Should(BeTrue())
+						Expect(string(buf.Contents())).To(MatchRegexp("a-very-lon\\.\\.\\. +some-space +some-org"))
+						Expect(string(buf.Contents())).NotTo(ContainSubstring("application-name"))
+					})
+				})
+			})
+
+			Context("when the guid column is picked", func() {
+				BeforeEach(func() {
+					command.Columns = []string{"name", "guid"}
+				})
+
+				It("prints the guid of each app", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("name +guid"))
+					Expect(string(buf.Contents())).To(MatchRegexp("some-app +some-app-guid"))
+				})
+			})
+
+			Context("when the buildpack column is picked", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.DetectedBuildpack = "staticfile 1.3.1"
+					command.Columns = []string{"name", "buildpack"}
+				})
+
+				It("prints the buildpack each app was staged with", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("some-app +staticfile 1.3.1"))
+				})
+			})
+
+			Context("when an app has a docker image", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.DockerImage = "cloudfoundry/lattice-app"
+				})
+
+				It("adds a docker_image column", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("stack +docker_image"))
+					Expect(string(buf.Contents())).To(MatchRegexp("some-app.*cloudfoundry/lattice-app"))
+				})
+
+				It("does not warn about apps on Diego", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).NotTo(ContainSubstring("Warning"))
+				})
+			})
+
+			Context("when a docker app is on DEA", func() {
+				var logBuf *gbytes.Buffer
+
+				BeforeEach(func() {
+					command.Runtime = AllRuntimes
+					dockerApp := appPrinterNamed("docker-app")
+					dockerApp.(*displayhelpers.AppPrinter).App.DockerImage = "cloudfoundry/lattice-app"
+					apps = append(apps, dockerApp)
+
+					logBuf = gbytes.NewBuffer()
+					LogOutput = logBuf
+				})
+
+				AfterEach(func() {
+					LogOutput = os.Stderr
+				})
+
+				It("logs a warning that DEA cannot run it", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(logBuf.Contents())).To(Equal("Warning: App docker-app runs docker image cloudfoundry/lattice-app but is on DEA, which does not support docker\n"))
+					Expect(string(buf.Contents())).NotTo(ContainSubstring("Warning"))
+				})
+			})
+
+			It("leaves out the docker_image column when no app has a docker image", func() {
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).NotTo(ContainSubstring("docker_image"))
+			})
+
+			Context("when the apps are grouped by org", func() {
+				BeforeEach(func() {
+					command.GroupBy = GroupByOrg
+					apps = append(apps, appPrinterInOrg("other-app", "another-org"))
+				})
+
+				It("prints a table per org, in order of org name", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("(?s)Apps in org another-org:\nname.*other-app.*\n\nApps in org some-org:\nname.*some-app"))
+					Expect(string(buf.Contents())).To(HaveSuffix("\nShowing 2 apps on the Diego runtime.\n"))
+				})
+			})
+
+			Context("when apps on all runtimes are listed", func() {
+				BeforeEach(func() {
+					command.Runtime = AllRuntimes
+					apps = append(apps, appPrinterNamed("dea-app"))
+				})
+
+				It("prints the runtime of each app", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("stack.*runtime"))
+					Expect(string(buf.Contents())).To(MatchRegexp("some-app.*Diego"))
+					Expect(string(buf.Contents())).To(MatchRegexp("dea-app.*DEA"))
+				})
+			})
+		})
+
+		Context("when the output is json", func() {
+			BeforeEach(func() {
+				command.Output = JSONOutput
+			})
+
+			Context("when an app asked for a buildpack", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.Buildpack = "https://github.com/cloudfoundry/go-buildpack.git"
+				})
+
+				It("includes it in the JSON", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(ContainSubstring(`"buildpack":"https://github.com/cloudfoundry/go-buildpack.git"`))
+				})
+			})
+
+			Context("when an app has a docker image", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.DockerImage = "cloudfoundry/lattice-app"
+				})
+
+				It("includes it in the JSON", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(ContainSubstring(`"docker_image":"cloudfoundry/lattice-app"`))
+				})
+			})
+
+			It("prints the apps as a JSON array", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(buf.Contents()).To(MatchJSON(`[{
+					"name": "some-app",
+					"guid": "some-app-guid",
+					"space": "some-space",
+					"org": "some-org",
+					"diego": true
+				}]`))
+			})
+
+			Context("when there are no apps", func() {
+				BeforeEach(func() {
+					apps = nil
+				})
+
+				It("prints an empty JSON array", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(buf.Contents()).To(MatchJSON(`[]`))
+				})
+			})
+		})
+
+		Context("when the output is ndjson", func() {
+			BeforeEach(func() {
+				command.Output = NDJSONOutput
+				apps = append(apps, &displayhelpers.AppPrinter{
+					App: models.Application{
+						ApplicationEntity:   models.ApplicationEntity{Name: "other-app", SpaceGuid: "some-space-guid"},
+						ApplicationMetadata: models.ApplicationMetadata{Guid: "other-app-guid"},
+					},
+					Spaces: apps[0].(*displayhelpers.AppPrinter).Spaces,
+				})
+			})
+
+			It("prints each app as a JSON object on its own line", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				lines := strings.Split(strings.TrimSpace(string(buf.Contents())), "\n")
+				Expect(lines).To(HaveLen(2))
+				Expect(lines[0]).To(MatchJSON(`{
+					"name": "some-app",
+					"guid": "some-app-guid",
+					"space": "some-space",
+					"org": "some-org",
+					"diego": true
+				}`))
+				Expect(lines[1]).To(MatchJSON(`{
+					"name": "other-app",
+					"guid": "other-app-guid",
+					"space": "some-space",
+					"org": "some-org",
+					"diego": false
+				}`))
+			})
+
+			Context("when there are no apps", func() {
+				BeforeEach(func() {
+					apps = nil
+				})
+
+				It("prints nothing", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(buf.Contents()).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when the listing is sent elsewhere", func() {
+			var out *bytes.Buffer
+
+			BeforeEach(func() {
+				out = new(bytes.Buffer)
+				command.SetOut(out)
+			})
+
+			It("writes the table there and the informational lines to stdout", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(out.String()).To(MatchRegexp("^name.*space.*org"))
+				Expect(out.String()).To(ContainSubstring("some-app"))
+				Expect(out.String()).NotTo(ContainSubstring("Showing"))
+				Expect(out.String()).NotTo(ContainSubstring("\x1b["))
+
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).NotTo(ContainSubstring("some-app"))
+				Expect(string(buf.Contents())).To(ContainSubstring("Showing 1 app"))
+			})
+
+			Context("when the output is json", func() {
+				BeforeEach(func() {
+					command.Output = JSONOutput
+				})
+
+				It("writes the JSON there", func() {
+					Expect(out.Bytes()).To(MatchJSON(`[{
+						"name": "some-app",
+						"guid": "some-app-guid",
+						"space": "some-space",
+						"org": "some-org",
+						"diego": true
+					}]`))
+				})
+			})
+		})
+
+		Context("when only the count is requested", func() {
+			BeforeEach(func() {
+				command.Count = true
+				command.Output = JSONOutput
+				apps = append(apps, appPrinterNamed("other-app"))
+			})
+
+			It("prints only the number of apps", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).To(Equal("2\n"))
+			})
+		})
+
+		Context("when only the guids are requested", func() {
+			BeforeEach(func() {
+				command.Guids = true
+				command.UI = terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), trace.NewLogger(false, "", ""))
+				other := appPrinterNamed("other-app")
+				other.(*displayhelpers.AppPrinter).App.Guid = "other-app-guid"
+				apps = append(apps, other)
+			})
+
+			It("prints one guid per line and nothing else", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).To(Equal("some-app-guid\nother-app-guid\n"))
+			})
+
+			Context("when there are no apps", func() {
+				BeforeEach(func() {
+					apps = nil
+				})
+
+				It("prints nothing", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(buf.Contents()).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when a sort field is given", func() {
+			BeforeEach(func() {
+				command.Output = CSVOutput
+				command.SortField = SortByName
+
+				apps = append(apps,
+					appPrinterNamed("b-app"),
+					appPrinterNamed("a-app"),
+				)
+			})
+
+			It("sorts the apps in ascending order", func() {
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).To(MatchRegexp("(?s)a-app.*b-app.*some-app"))
+			})
+
+			Context("when descending order is requested", func() {
+				BeforeEach(func() {
+					command.SortDescending = true
+				})
+
+				It("sorts the apps in descending order", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(MatchRegexp("(?s)some-app.*b-app.*a-app"))
+				})
+			})
+		})
+
+		Context("when the output is csv", func() {
+			BeforeEach(func() {
+				command.Output = CSVOutput
+			})
+
+			It("prints a header row followed by one row per app", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(buf.Closed).Should(BeTrue())
+				Expect(string(buf.Contents())).To(Equal("name,space,org,guid\nsome-app,some-space,some-org,some-app-guid\n"))
+			})
+
+			Context("when a field contains a comma", func() {
+				BeforeEach(func() {
+					apps[0].(*displayhelpers.AppPrinter).App.Name = "some,app"
+				})
+
+				It("quotes the field", func() {
+					Eventually(buf.Closed).Should(BeTrue())
+					Expect(string(buf.Contents())).To(ContainSubstring(`"some,app",some-space`))
+				})
+			})
+		})
+	})
+
+	Describe("PrintStreamed", func() {
+		BeforeEach(func() {
+			command.Output = NDJSONOutput
+		})
+
+		It("streams ndjson output unless the apps are sorted", func() {
+			Expect(command.Streams()).To(BeTrue())
+
+			command.SortField = SortByName
+			Expect(command.Streams()).To(BeFalse())
+		})
+
+		It("prints the app as a JSON object on a line of its own", func() {
+			Expect(command.PrintStreamed(apps[0])).To(Succeed())
+			os.Stdout.Close()
+
+			Eventually(buf.Closed).Should(BeTrue())
+			Expect(string(buf.Contents())).To(HaveSuffix("\n"))
+			Expect(buf.Contents()).To(MatchJSON(`{
+				"name": "some-app",
+				"guid": "some-app-guid",
+				"space": "some-space",
+				"org": "some-org",
+				"diego": true
+			}`))
+		})
+	})
+})
+
+func appPrinterNamed(name string) ApplicationPrinter {
+	return &displayhelpers.AppPrinter{
+		App: models.Application{
+			ApplicationEntity: models.ApplicationEntity{
+				Name: name,
+			},
+		},
+	}
+}
+
+func appPrinterInOrg(name string, org string) ApplicationPrinter {
+	return &displayhelpers.AppPrinter{
+		App: models.Application{
+			ApplicationEntity: models.ApplicationEntity{
+				Name:      name,
+				SpaceGuid: name + "-space-guid",
+			},
+		},
+		Spaces: map[string]models.Space{
+			name + "-space-guid": models.Space{
+				SpaceEntity: models.SpaceEntity{
+					Name: name + "-space",
+					Organization: models.Organization{
+						OrganizationEntity: models.OrganizationEntity{
+							Name: org,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func captureStdout(buf *gbytes.Buffer) *os.File {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	Expect(err).NotTo(HaveOccurred())
+	os.Stdout = w
+	go func() {
+		if _, err := io.Copy(buf, r); err != nil {
+			fmt.Fprintf(GinkgoWriter, "Failed to capture stdout: %s\n", err)
+		}
+		buf.Close()
+		r.Close()
+	}()
+	return stdout
+}
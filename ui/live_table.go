@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+// Statuses shown in a LiveTable as each app is switched.
+const (
+	LivePending     = "pending"
+	LiveDone        = "done"
+	LiveSkipped     = "skipped"
+	LiveFailed      = "failed"
+	LiveTimedOut    = "timed out"
+	LiveNotStarted  = "not started"
+	LiveWouldSwitch = "would switch"
+)
+
+// LiveTable is a table of apps and their status that is redrawn in place,
+// by moving the cursor back over it, each time a status changes. UI and
+// Writer must print to the same terminal.
+type LiveTable struct {
+	UI     terminal.UI
+	Writer io.Writer
+
+	// Width and Height are the size of the terminal, or 0 when unknown.
+	// Rows wider than Width wrap onto several lines, and a table taller
+	// than Height cannot be drawn over, so each status change is then
+	// printed on a line of its own instead.
+	Width  int
+	Height int
+
+	mu         sync.Mutex
+	names      []string
+	statuses   []string
+	footer     string
+	lines      int
+	overflowed bool
+}
+
+// NewLiveTable draws a table with every app pending, on a terminal of the
+// given size.
+func NewLiveTable(ui terminal.UI, w io.Writer, width int, height int, names []string) *LiveTable {
+	t := &LiveTable{
+		UI:       ui,
+		Writer:   w,
+		Width:    width,
+		Height:   height,
+		names:    names,
+		statuses: make([]string, len(names)),
+	}
+	for i := range t.statuses {
+		t.statuses[i] = LivePending
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fits() {
+		t.draw()
+	}
+	return t
+}
+
+// Set changes the status of the app at index and redraws the table.
+func (t *LiveTable) Set(index int, status string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statuses[index] = status
+	if t.fits() {
+		t.draw()
+		return
+	}
+	t.UI.Say("%s: %s", terminal.EntityNameColor(t.names[index]), liveStatusColor(status))
+}
+
+// SetFooter shows message on its own line below the table, since a line
+// printed any other way would be drawn over.
+func (t *LiveTable) SetFooter(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.footer = message
+	if t.fits() {
+		t.draw()
+		return
+	}
+	t.UI.Say(message)
+}
+
+// fits reports whether the table can be drawn over. Once it is too tall
+// for the terminal it never is again, since the lines that scrolled out of
+// view cannot be reached with the cursor.
+func (t *LiveTable) fits() bool {
+	if t.Height > 0 && t.height() > t.Height {
+		t.overflowed = true
+	}
+	return !t.overflowed
+}
+
+func (t *LiveTable) draw() {
+	if t.lines > 0 {
+		fmt.Fprintf(t.Writer, "\033[%dA\033[J", t.lines)
+	}
+
+	table := terminal.NewTable(t.UI, []string{"name", "status"})
+	for i, name := range t.names {
+		table.Add(name, liveStatusColor(t.statuses[i]))
+	}
+	table.Print()
+
+	if t.footer != "" {
+		t.UI.Say(t.footer)
+	}
+	t.lines = t.height()
+}
+
+// height is the number of terminal lines the table takes up, counting
+// the rows that wrap. Like terminal.Table, it pads the name column to the
+// longest name and ends each column with three spaces.
+func (t *LiveTable) height() int {
+	nameWidth := utf8.RuneCountInString("name")
+	for _, name := range t.names {
+		if width := utf8.RuneCountInString(name); width > nameWidth {
+			nameWidth = width
+		}
+	}
+
+	lines := t.wrappedLines(nameWidth + 3 + len("status") + 3)
+	for _, status := range t.statuses {
+		lines += t.wrappedLines(nameWidth + 3 + len(status) + 3)
+	}
+	if t.footer != "" {
+		lines += t.wrappedLines(utf8.RuneCountInString(t.footer))
+	}
+	return lines
+}
+
+// wrappedLines is the number of lines a line of width characters takes up
+// on the terminal.
+func (t *LiveTable) wrappedLines(width int) int {
+	if t.Width <= 0 || width <= t.Width {
+		return 1
+	}
+	return (width + t.Width - 1) / t.Width
+}
+
+func liveStatusColor(status string) string {
+	switch status {
+	case LiveDone:
+		return terminal.SuccessColor(status)
+	case LiveFailed, LiveTimedOut:
+		return terminal.FailureColor(status)
+	}
+	return status
+}
@@ -0,0 +1,52 @@
+package ui_test
+
+import (
+	"os"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+	"github.com/cloudfoundry/cli/cf/terminal"
+	"github.com/cloudfoundry/cli/cf/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("LiveTable", func() {
+	var (
+		buf     *gbytes.Buffer
+		cursor  *gbytes.Buffer
+		stdout  *os.File
+		tableUI terminal.UI
+	)
+
+	BeforeEach(func() {
+		buf = gbytes.NewBuffer()
+		cursor = gbytes.NewBuffer()
+		stdout = captureStdout(buf)
+		tableUI = terminal.NewUI(os.Stdin, terminal.NewTeePrinter(), trace.NewLogger(false, "", ""))
+	})
+
+	AfterEach(func() {
+		os.Stdout.Close()
+		os.Stdout = stdout
+	})
+
+	It("moves back over every line of the rows that wrap", func() {
+		table := NewLiveTable(tableUI, cursor, 20, 24, []string{"some-app-with-a-long-name"})
+		table.Set(0, LiveDone)
+
+		Expect(string(cursor.Contents())).To(Equal("\033[4A\033[J"))
+	})
+
+	It("prints a line for each change when the table is taller than the terminal", func() {
+		table := NewLiveTable(tableUI, cursor, 80, 3, []string{"first-app", "second-app", "third-app"})
+		table.Set(1, LiveDone)
+		os.Stdout.Close()
+
+		Eventually(buf.Closed).Should(BeTrue())
+		Expect(string(buf.Contents())).NotTo(ContainSubstring("pending"))
+		Expect(terminal.Decolorize(string(buf.Contents()))).To(Equal("second-app: done\n"))
+		Expect(cursor.Contents()).To(BeEmpty())
+	})
+})
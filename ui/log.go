@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type LogLevel int
+
+const (
+	LogError LogLevel = iota
+	LogWarn
+	LogInfo
+)
+
+var logLevelNames = map[string]LogLevel{
+	"error": LogError,
+	"warn":  LogWarn,
+	"info":  LogInfo,
+}
+
+// Level is the least severe kind of message that is logged.
+var Level = LogInfo
+
+// LogOutput is where Info, Warn and Error messages go, so they stay apart from
+// the results a command prints to stdout.
+var LogOutput io.Writer = os.Stderr
+
+type InvalidLogLevelError struct {
+	PassedValue string
+}
+
+func (e InvalidLogLevelError) Error() string {
+	return fmt.Sprintf("Invalid log level %q, expected error, warn or info", e.PassedValue)
+}
+
+// SetLogLevel sets Level from its name. A level below info also sets Quiet,
+// as the OK banners and progress messages are informational too.
+func SetLogLevel(name string) error {
+	level, ok := logLevelNames[name]
+	if !ok {
+		return InvalidLogLevelError{PassedValue: name}
+	}
+
+	Level = level
+	if level < LogInfo {
+		BeQuiet()
+	}
+	return nil
+}
+
+// Info logs a progress message unless Quiet is set or Level is below info.
+func Info(format string, a ...interface{}) {
+	if Quiet || Level < LogInfo {
+		return
+	}
+	fmt.Fprintf(LogOutput, format, a...)
+}
+
+// Warn logs a message about something that may need attention, unless
+// Level is error. Quiet does not silence warnings.
+func Warn(format string, a ...interface{}) {
+	if Level < LogWarn {
+		return
+	}
+	fmt.Fprintf(LogOutput, "Warning: "+format, a...)
+}
+
+// Error logs why a command failed. Neither Level nor Quiet filters it out,
+// so that a failure is always explained.
+func Error(format string, a ...interface{}) {
+	fmt.Fprintf(LogOutput, "Error: "+format, a...)
+}
@@ -0,0 +1,73 @@
+package ui_test
+
+import (
+	"os"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Log", func() {
+	var buf *gbytes.Buffer
+
+	BeforeEach(func() {
+		buf = gbytes.NewBuffer()
+		LogOutput = buf
+	})
+
+	AfterEach(func() {
+		LogOutput = os.Stderr
+		Level = LogInfo
+		Quiet = false
+	})
+
+	It("logs info and warnings at the info level", func() {
+		Info("Setting %s\n", "some-app")
+		Warn("App %s is odd\n", "some-app")
+
+		Expect(string(buf.Contents())).To(Equal("Setting some-app\nWarning: App some-app is odd\n"))
+	})
+
+	Context("when the level is warn", func() {
+		BeforeEach(func() {
+			Expect(SetLogLevel("warn")).To(Succeed())
+		})
+
+		It("only logs warnings", func() {
+			Info("Setting %s\n", "some-app")
+			Warn("App %s is odd\n", "some-app")
+
+			Expect(string(buf.Contents())).To(Equal("Warning: App some-app is odd\n"))
+		})
+
+		It("is quiet", func() {
+			Expect(Quiet).To(BeTrue())
+		})
+	})
+
+	Context("when the level is error", func() {
+		It("logs neither info nor warnings", func() {
+			Expect(SetLogLevel("error")).To(Succeed())
+
+			Info("Setting %s\n", "some-app")
+			Warn("App %s is odd\n", "some-app")
+
+			Expect(buf.Contents()).To(BeEmpty())
+		})
+	})
+
+	It("always logs errors", func() {
+		Expect(SetLogLevel("error")).To(Succeed())
+
+		Error("App %s not found\n", "some-app")
+
+		Expect(string(buf.Contents())).To(Equal("Error: App some-app not found\n"))
+	})
+
+	It("rejects an unknown level", func() {
+		Expect(SetLogLevel("debug")).To(MatchError(`Invalid log level "debug", expected error, warn or info`))
+	})
+})
@@ -16,7 +16,7 @@ type MigrateAppsCommand struct {
 func (c *MigrateAppsCommand) BeforeAll() {
 	switch {
 	case c.Organization != "" && c.Space != "":
-		fmt.Printf(
+		Say(
 			"Migrating apps to %s in org %s / %s as %s...\n",
 			terminal.EntityNameColor(c.Runtime.String()),
 			terminal.EntityNameColor(c.Organization),
@@ -24,14 +24,14 @@ func (c *MigrateAppsCommand) BeforeAll() {
 			terminal.EntityNameColor(c.Username),
 		)
 	case c.Organization != "":
-		fmt.Printf(
+		Say(
 			"Migrating apps to %s in org %s as %s...\n",
 			terminal.EntityNameColor(c.Runtime.String()),
 			terminal.EntityNameColor(c.Organization),
 			terminal.EntityNameColor(c.Username),
 		)
 	default:
-		fmt.Printf(
+		Say(
 			"Migrating apps to %s as %s...\n",
 			terminal.EntityNameColor(c.Runtime.String()),
 			terminal.EntityNameColor(c.Username),
@@ -40,9 +40,8 @@ func (c *MigrateAppsCommand) BeforeAll() {
 }
 
 func (c *MigrateAppsCommand) BeforeEach(app ApplicationPrinter) {
-	fmt.Println()
-	fmt.Printf(
-		"Migrating app %s in org %s / space %s to %s as %s...\n",
+	Say(
+		"\nMigrating app %s in org %s / space %s to %s as %s...\n",
 		terminal.EntityNameColor(app.Name()),
 		terminal.EntityNameColor(app.Organization()),
 		terminal.EntityNameColor(app.Space()),
@@ -52,9 +51,8 @@ func (c *MigrateAppsCommand) BeforeEach(app ApplicationPrinter) {
 }
 
 func (c *MigrateAppsCommand) CompletedEach(app ApplicationPrinter) {
-	fmt.Println()
-	fmt.Printf(
-		"Completed migrating app %s in org %s / space %s to %s as %s\n",
+	Say(
+		"\nCompleted migrating app %s in org %s / space %s to %s as %s\n",
 		terminal.EntityNameColor(app.Name()),
 		terminal.EntityNameColor(app.Organization()),
 		terminal.EntityNameColor(app.Space()),
@@ -64,7 +62,7 @@ func (c *MigrateAppsCommand) CompletedEach(app ApplicationPrinter) {
 }
 
 func (c *MigrateAppsCommand) DuringEach(app ApplicationPrinter) {
-	fmt.Print(".")
+	Say(".")
 }
 
 func (c *MigrateAppsCommand) AfterAll(attempts, warnings int, errors int) {
@@ -73,6 +71,17 @@ func (c *MigrateAppsCommand) AfterAll(attempts, warnings int, errors int) {
 	fmt.Printf("Migration to %s completed: %d apps, %d errors, %d warnings\n", terminal.EntityNameColor(c.Runtime.String()), successes, errors, warnings)
 }
 
+func (c *MigrateAppsCommand) Interrupted() {
+	fmt.Println()
+	fmt.Println("Interrupted: waiting for the apps being migrated to finish. Press Ctrl-C again to quit.")
+}
+
+func (c *MigrateAppsCommand) AfterInterrupt(attempts, warnings, errors, notStarted int) {
+	successes := attempts - warnings - errors
+	fmt.Println()
+	fmt.Printf("Migration to %s interrupted: %d apps, %d errors, %d warnings, %d not started\n", terminal.EntityNameColor(c.Runtime.String()), successes, errors, warnings, notStarted)
+}
+
 func (c *MigrateAppsCommand) UserWarning(app ApplicationPrinter) {
 	fmt.Printf(
 		"WARNING: No authorization to migrate app %s to %s in space %s / org %s as %s\n",
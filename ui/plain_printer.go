@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+// plainPrinter is a terminal.Printer that writes to Writer with the colors
+// stripped, for output that is not going to a terminal.
+type plainPrinter struct {
+	Writer io.Writer
+}
+
+func (p plainPrinter) Print(a ...interface{}) (int, error) {
+	return io.WriteString(p.Writer, terminal.Decolorize(fmt.Sprint(a...)))
+}
+
+func (p plainPrinter) Printf(format string, a ...interface{}) (int, error) {
+	return io.WriteString(p.Writer, terminal.Decolorize(fmt.Sprintf(format, a...)))
+}
+
+func (p plainPrinter) Println(a ...interface{}) (int, error) {
+	return io.WriteString(p.Writer, terminal.Decolorize(fmt.Sprintln(a...)))
+}
+
+func (p plainPrinter) ForcePrint(a ...interface{}) (int, error) {
+	return p.Print(a...)
+}
+
+func (p plainPrinter) ForcePrintf(format string, a ...interface{}) (int, error) {
+	return p.Printf(format, a...)
+}
+
+func (p plainPrinter) ForcePrintln(a ...interface{}) (int, error) {
+	return p.Println(a...)
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// PageProgress reports pagination progress for noun on stderr, keeping it
+// out of the table and JSON output. It returns nil, reporting nothing,
+// when Quiet is set or stderr is not a terminal.
+func PageProgress(noun string) func(page, totalPages, results int) {
+	if Quiet || !isatty.IsTerminal(os.Stderr.Fd()) {
+		return nil
+	}
+	return PageProgressTo(os.Stderr, noun)
+}
+
+func PageProgressTo(w io.Writer, noun string) func(page, totalPages, results int) {
+	return func(page, totalPages, results int) {
+		fmt.Fprintf(w, "\rFetched page %d/%d (%d %s)...", page, totalPages, results, noun)
+		if page == totalPages {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// FetchTotals announces how many noun, across how many pages, are about to
+// be fetched, when there is more than one page. It is logged as info, so
+// Quiet silences it.
+func FetchTotals(noun string) func(totalResults, totalPages int) {
+	return func(totalResults, totalPages int) {
+		if totalPages > 1 {
+			Info("About to fetch %d %s across %d pages\n", totalResults, noun, totalPages)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ui_test
+
+import (
+	"os"
+
+	. "github.com/cloudfoundry-incubator/diego-enabler/ui"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("PageProgress", func() {
+	It("rewrites the progress line until the last page", func() {
+		buf := gbytes.NewBuffer()
+		progress := PageProgressTo(buf, "apps")
+
+		progress(1, 2, 100)
+		progress(2, 2, 150)
+
+		Expect(string(buf.Contents())).To(Equal("\rFetched page 1/2 (100 apps)...\rFetched page 2/2 (150 apps)...\n"))
+	})
+})
+
+var _ = Describe("FetchTotals", func() {
+	var buf *gbytes.Buffer
+
+	BeforeEach(func() {
+		buf = gbytes.NewBuffer()
+		LogOutput = buf
+	})
+
+	AfterEach(func() {
+		LogOutput = os.Stderr
+	})
+
+	It("announces the size of a fetch with several pages", func() {
+		FetchTotals("apps")(150, 2)
+
+		Expect(string(buf.Contents())).To(Equal("About to fetch 150 apps across 2 pages\n"))
+	})
+
+	It("says nothing about a single page", func() {
+		FetchTotals("apps")(50, 1)
+
+		Expect(buf.Contents()).To(BeEmpty())
+	})
+})
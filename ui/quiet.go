@@ -0,0 +1,19 @@
+package ui
+
+import "fmt"
+
+// Quiet silences informational output, such as progress messages and the
+// OK and FAILED banners, leaving only errors, warnings and final results.
+var Quiet bool
+
+func BeQuiet() {
+	Quiet = true
+}
+
+// Say prints informational output unless Quiet is set.
+func Say(format string, a ...interface{}) {
+	if Quiet {
+		return
+	}
+	fmt.Printf(format, a...)
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+	sshterminal "golang.org/x/crypto/ssh/terminal"
+)
+
+// TerminalWidth returns the width of the terminal stdout is attached to, or
+// 0 when stdout is not a terminal, for example when it is piped.
+func TerminalWidth() int {
+	width, _ := terminalSize()
+	return width
+}
+
+// TerminalHeight is TerminalWidth for the number of lines of the terminal.
+func TerminalHeight() int {
+	_, height := terminalSize()
+	return height
+}
+
+func terminalSize() (int, int) {
+	fd := os.Stdout.Fd()
+	if !isatty.IsTerminal(fd) {
+		return 0, 0
+	}
+
+	width, height, err := sshterminal.GetSize(int(fd))
+	if err != nil {
+		return 0, 0
+	}
+	return width, height
+}
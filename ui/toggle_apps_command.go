@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+type ToggleAppsCommand struct {
+	Username     string
+	Runtime      Runtime
+	Organization string
+	Space        string
+	UI           terminal.UI
+	Interactive  bool
+
+	// liveMu guards live, which Interrupted reads from the goroutine that
+	// handles Ctrl-C.
+	liveMu sync.Mutex
+	live   *LiveTable
+}
+
+func (c *ToggleAppsCommand) BeforeAll() {
+	switch {
+	case c.Organization != "" && c.Space != "":
+		Say(
+			"Switching apps in org %s / %s to %s as %s...\n",
+			terminal.EntityNameColor(c.Organization),
+			terminal.EntityNameColor(c.Space),
+			terminal.EntityNameColor(c.Runtime.String()),
+			terminal.EntityNameColor(c.Username),
+		)
+	case c.Organization != "":
+		Say(
+			"Switching apps in org %s to %s as %s...\n",
+			terminal.EntityNameColor(c.Organization),
+			terminal.EntityNameColor(c.Runtime.String()),
+			terminal.EntityNameColor(c.Username),
+		)
+	default:
+		Say(
+			"Switching apps in space %s to %s as %s...\n",
+			terminal.EntityNameColor(c.Space),
+			terminal.EntityNameColor(c.Runtime.String()),
+			terminal.EntityNameColor(c.Username),
+		)
+	}
+}
+
+// ConfirmChanges prompts through UI rather than UI.Confirm, which needs
+// the CLI's translations loaded to read anything other than "y".
+func (c *ToggleAppsCommand) ConfirmChanges(count int) bool {
+	answer := c.UI.Ask(fmt.Sprintf("This will change %d apps. Continue? [y/N]", count))
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true
+	}
+
+	fmt.Println("Switching apps cancelled")
+	return false
+}
+
+func (c *ToggleAppsCommand) SkippedEach(app ApplicationPrinter) {
+	Say(
+		"Skipping app %s: already on %s\n",
+		terminal.EntityNameColor(app.Name()),
+		terminal.EntityNameColor(c.Runtime.String()),
+	)
+}
+
+func (c *ToggleAppsCommand) CompletedEach(app ApplicationPrinter) {
+	Say(
+		"Switched app %s to %s\n",
+		terminal.EntityNameColor(app.Name()),
+		terminal.EntityNameColor(c.Runtime.String()),
+	)
+}
+
+func (c *ToggleAppsCommand) DryRunEach(app ApplicationPrinter) {
+	fmt.Printf(
+		"Would switch app %s to %s\n",
+		terminal.EntityNameColor(app.Name()),
+		terminal.EntityNameColor(c.Runtime.String()),
+	)
+}
+
+func (c *ToggleAppsCommand) FailedEach(app ApplicationPrinter, err error) {
+	fmt.Printf(
+		"Error: Failed to switch app %s to %s: %s\n",
+		terminal.EntityNameColor(app.Name()),
+		terminal.EntityNameColor(c.Runtime.String()),
+		terminal.EntityNameColor(err.Error()),
+	)
+}
+
+func (c *ToggleAppsCommand) TimedOutEach(app ApplicationPrinter, timeout time.Duration) {
+	fmt.Printf(
+		"Error: Timed out switching app %s to %s after %s\n",
+		terminal.EntityNameColor(app.Name()),
+		terminal.EntityNameColor(c.Runtime.String()),
+		timeout,
+	)
+}
+
+func (c *ToggleAppsCommand) BeforeVerify() {
+	Say(
+		"\nVerifying switched apps are set to %s...\n",
+		terminal.EntityNameColor(c.Runtime.String()),
+	)
+}
+
+func (c *ToggleAppsCommand) UnverifiedEach(app ApplicationPrinter) {
+	fmt.Printf(
+		"Error: App %s is NOT set to %s\n",
+		terminal.EntityNameColor(app.Name()),
+		terminal.EntityNameColor(c.Runtime.String()),
+	)
+}
+
+func (c *ToggleAppsCommand) AfterAll(changed, skipped, failed, timedOut int) {
+	fmt.Println()
+	fmt.Printf(
+		"Switching apps to %s completed: %d changed, %d skipped, %d failed%s\n",
+		terminal.EntityNameColor(c.Runtime.String()),
+		changed,
+		skipped,
+		failed,
+		timedOutTally(timedOut),
+	)
+}
+
+// timedOutTally only mentions timeouts when there were any, which needs
+// --timeout-per-app.
+func timedOutTally(timedOut int) string {
+	if timedOut == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d timed out", timedOut)
+}
+
+// BeforeLive draws the table of apps that LiveEach updates, in place of a
+// line for each app.
+func (c *ToggleAppsCommand) BeforeLive(apps []ApplicationPrinter) {
+	names := make([]string, 0, len(apps))
+	for _, app := range apps {
+		names = append(names, app.Name())
+	}
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.live = NewLiveTable(c.UI, os.Stdout, TerminalWidth(), TerminalHeight(), names)
+}
+
+// LiveEach sets the status of the app at index in the table drawn by
+// BeforeLive.
+func (c *ToggleAppsCommand) LiveEach(index int, status string) {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	c.live.Set(index, status)
+}
+
+// AfterLive stops redrawing the table, so that the lines printed after it
+// are not drawn over.
+func (c *ToggleAppsCommand) AfterLive() {
+	c.liveMu.Lock()
+	c.live = nil
+	c.liveMu.Unlock()
+	fmt.Println()
+}
+
+func (c *ToggleAppsCommand) Interrupted() {
+	message := "Interrupted: waiting for the apps being switched to finish. Press Ctrl-C again to quit."
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	if c.live != nil {
+		c.live.SetFooter(message)
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(message)
+}
+
+func (c *ToggleAppsCommand) AfterInterrupt(changed, skipped, failed, timedOut, notStarted int) {
+	fmt.Println()
+	fmt.Printf(
+		"Switching apps to %s interrupted: %d changed, %d skipped, %d failed%s, %d not started\n",
+		terminal.EntityNameColor(c.Runtime.String()),
+		changed,
+		skipped,
+		failed,
+		timedOutTally(timedOut),
+		notStarted,
+	)
+}
+
+func (c *ToggleAppsCommand) AfterWriteFailures(path string, count int) {
+	Info("Wrote the guids of %d apps that were not switched to %s\n", count, path)
+}
+
+func (c *ToggleAppsCommand) AfterDryRun(changed, skipped int) {
+	fmt.Println()
+	fmt.Printf(
+		"Switching apps to %s would change %d apps, skipping %d\n",
+		terminal.EntityNameColor(c.Runtime.String()),
+		changed,
+		skipped,
+	)
+	SayDryRun()
+}
@@ -10,6 +10,9 @@ type Runtime string
 const (
 	DEA   Runtime = "DEA"
 	Diego Runtime = "Diego"
+
+	// AllRuntimes is used by commands that cover apps regardless of runtime.
+	AllRuntimes Runtime = ""
 )
 
 func (r Runtime) String() string {
@@ -43,6 +46,21 @@ func ParseRuntime(runtime string) (Runtime, error) {
 
 type ApplicationPrinter interface {
 	Name() string
+	Guid() string
+	Diego() bool
 	Organization() string
 	Space() string
+	State() string
+	Instances() int
+	Memory() int64
+	Stack() string
+	Buildpack() string
+	DockerImage() string
+}
+
+func runtimeOf(app ApplicationPrinter) Runtime {
+	if app.Diego() {
+		return Diego
+	}
+	return DEA
 }